@@ -35,6 +35,35 @@ func Connect() {
 		&models.Partner{},
 		&models.Password{},
 		&models.Pdf{},
+		&models.Animal{},
+		&models.AnimalPhoto{},
+		&models.MedicalRecord{},
+		&models.MedicalAttachment{},
+		&models.Sponsorship{},
+		&models.AnimalUpdate{},
+		&models.ExcursionSession{},
+		&models.Booking{},
+		&models.ExcursionSchedule{},
+		&models.BlackoutDate{},
+		&models.WaitlistEntry{},
+		&models.Campaign{},
+		&models.Donation{},
+		&models.WebhookEvent{},
+		&models.Receipt{},
+		&models.Expense{},
+		&models.MonthlyReport{},
+		&models.Volunteer{},
+		&models.Shift{},
+		&models.ShiftSignup{},
+		&models.RefreshToken{},
+		&models.Session{},
+		&models.LoginThrottle{},
+		&models.RecoveryCode{},
+		&models.Invitation{},
+		&models.AuditLog{},
+		&models.APIKey{},
+		&models.UserIdentity{},
+		&models.OIDCLogin{},
 		&models.Review{},
 	)
 
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"os"
+
+	"github.com/kholodihor/cows-shelter-backend/mailer"
+)
+
+// Mailer is the application-wide outgoing email service
+var Mailer mailer.Mailer = mailer.LogMailer{}
+
+// NewMailer creates a mailer based on the configuration. Without SMTP_HOST
+// messages are only written to the log.
+func NewMailer() mailer.Mailer {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return mailer.LogMailer{}
+	}
+
+	return &mailer.SMTPMailer{
+		Host:     host,
+		Port:     GetEnv("SMTP_PORT", "587"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     GetEnv("SMTP_FROM", "no-reply@cowshelter.com"),
+	}
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strings"
+
+	"github.com/kholodihor/cows-shelter-backend/notifier"
+)
+
+// Notifier is the application-wide notification service for volunteers
+var Notifier notifier.Notifier = notifier.Log{}
+
+// NewNotifier creates a notifier for the channels listed in
+// NOTIFIER_CHANNELS (comma-separated: email, webhook, log; email by
+// default). Email goes through Mailer, so it must be set up first.
+func NewNotifier() notifier.Notifier {
+	var channels notifier.Multi
+	for _, name := range strings.Split(GetEnv("NOTIFIER_CHANNELS", "email"), ",") {
+		switch name = strings.TrimSpace(name); name {
+		case "":
+		case "email":
+			channels = append(channels, notifier.Email{Mailer: Mailer})
+		case "webhook":
+			url := os.Getenv("NOTIFIER_WEBHOOK_URL")
+			if url == "" {
+				log.Printf("Warning: the webhook notifier needs NOTIFIER_WEBHOOK_URL, skipping it")
+				continue
+			}
+			channels = append(channels, &notifier.Webhook{URL: url, Secret: os.Getenv("NOTIFIER_WEBHOOK_SECRET")})
+		case "log":
+			channels = append(channels, notifier.Log{})
+		default:
+			log.Printf("Warning: unknown notifier channel %q", name)
+		}
+	}
+
+	switch len(channels) {
+	case 0:
+		return notifier.Log{}
+	case 1:
+		return channels[0]
+	}
+	return channels
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kholodihor/cows-shelter-backend/models"
+)
+
+// OIDCConfig describes the identity provider used for staff single sign-on
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string   // this API's /api/auth/oidc/callback URL
+	Scopes       []string // requested in addition to "openid"
+	RoleClaim    string   // ID token claim holding groups or roles
+	RoleMap      map[string]string
+	DefaultRole  string // role for new users with no mapped claim; empty refuses them
+}
+
+// LoadOIDCConfig reads the OIDC settings. It returns nil when single sign-on
+// is not configured (OIDC_ISSUER_URL unset).
+func LoadOIDCConfig() (*OIDCConfig, error) {
+	issuer := os.Getenv("OIDC_ISSUER_URL")
+	if issuer == "" {
+		return nil, nil
+	}
+
+	cfg := &OIDCConfig{
+		IssuerURL:    issuer,
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		Scopes:       strings.Fields(GetEnv("OIDC_SCOPES", "email profile")),
+		RoleClaim:    GetEnv("OIDC_ROLE_CLAIM", "groups"),
+		RoleMap:      map[string]string{},
+		DefaultRole:  os.Getenv("OIDC_DEFAULT_ROLE"),
+	}
+	if cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, fmt.Errorf("OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required when OIDC_ISSUER_URL is set")
+	}
+	if cfg.DefaultRole != "" && !models.ValidRole(cfg.DefaultRole) {
+		return nil, fmt.Errorf("invalid OIDC_DEFAULT_ROLE %q", cfg.DefaultRole)
+	}
+
+	// OIDC_ROLE_MAP is a comma-separated list of claim-value:role pairs
+	for _, entry := range strings.Split(os.Getenv("OIDC_ROLE_MAP"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		// Claim values may contain colons themselves, so split on the last one
+		i := strings.LastIndex(entry, ":")
+		if i <= 0 || !models.ValidRole(entry[i+1:]) {
+			return nil, fmt.Errorf("invalid OIDC_ROLE_MAP entry %q, expected claim-value:role", entry)
+		}
+		cfg.RoleMap[entry[:i]] = entry[i+1:]
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"os"
+
+	"github.com/kholodihor/cows-shelter-backend/payments"
+)
+
+// PaymentProviders are the payment providers whose webhooks are accepted,
+// keyed by the name used in the webhook URL
+var PaymentProviders = map[string]payments.Provider{}
+
+// NewPaymentProviders creates the providers that are configured. Monobank
+// needs MONOBANK_PUBLIC_KEY or MONOBANK_TOKEN, PayPal needs its client
+// credentials and PAYPAL_WEBHOOK_ID.
+func NewPaymentProviders() map[string]payments.Provider {
+	providers := map[string]payments.Provider{}
+
+	if key, token := os.Getenv("MONOBANK_PUBLIC_KEY"), os.Getenv("MONOBANK_TOKEN"); key != "" || token != "" {
+		providers["monobank"] = &payments.Monobank{
+			PublicKey: key,
+			Token:     token,
+			APIBase:   GetEnv("MONOBANK_API_BASE", payments.DefaultMonobankAPI),
+		}
+	}
+
+	if webhookID := os.Getenv("PAYPAL_WEBHOOK_ID"); webhookID != "" {
+		providers["paypal"] = &payments.PayPal{
+			ClientID:     os.Getenv("PAYPAL_CLIENT_ID"),
+			ClientSecret: os.Getenv("PAYPAL_CLIENT_SECRET"),
+			WebhookID:    webhookID,
+			APIBase:      GetEnv("PAYPAL_API_BASE", payments.DefaultPayPalAPI),
+		}
+	}
+
+	return providers
+}
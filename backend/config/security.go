@@ -0,0 +1,14 @@
+package config
+
+// RequireAdminMFA reports whether accounts with the admin role must use
+// two-factor authentication (REQUIRE_ADMIN_2FA=true)
+func RequireAdminMFA() bool {
+	return GetEnv("REQUIRE_ADMIN_2FA", "false") == "true"
+}
+
+// SelfRegistrationEnabled reports whether invited users register themselves
+// through POST /api/user (ALLOW_SELF_REGISTRATION, enabled by default). When
+// disabled, admins create accounts directly instead of sending invitations.
+func SelfRegistrationEnabled() bool {
+	return GetEnv("ALLOW_SELF_REGISTRATION", "true") == "true"
+}
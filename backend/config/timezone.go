@@ -0,0 +1,33 @@
+package config
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	// Embed the time zone database so schedules work in minimal containers
+	_ "time/tzdata"
+)
+
+const defaultShelterTimezone = "Europe/Kyiv"
+
+var (
+	shelterLocationOnce sync.Once
+	shelterLocation     *time.Location
+)
+
+// ShelterLocation returns the time zone the shelter operates in
+// (SHELTER_TIMEZONE, Europe/Kyiv by default). Recurring schedules are
+// expanded in this zone so sessions keep their local time across DST changes.
+func ShelterLocation() *time.Location {
+	shelterLocationOnce.Do(func() {
+		name := GetEnv("SHELTER_TIMEZONE", defaultShelterTimezone)
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			log.Printf("Warning: unknown SHELTER_TIMEZONE %q, using %s", name, defaultShelterTimezone)
+			loc, _ = time.LoadLocation(defaultShelterTimezone)
+		}
+		shelterLocation = loc
+	})
+	return shelterLocation
+}
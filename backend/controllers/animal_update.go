@@ -0,0 +1,222 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kholodihor/cows-shelter-backend/config"
+	"github.com/kholodihor/cows-shelter-backend/mailer"
+	"github.com/kholodihor/cows-shelter-backend/middleware"
+	"github.com/kholodihor/cows-shelter-backend/models"
+	"gorm.io/gorm"
+)
+
+// AnimalUpdateRequest represents the JSON request body for a sponsor update post.
+// On update, omitted fields are left unchanged.
+type AnimalUpdateRequest struct {
+	TitleEn   *string `json:"title_en"`
+	TitleUa   *string `json:"title_ua"`
+	BodyEn    *string `json:"body_en"`
+	BodyUa    *string `json:"body_ua"`
+	ImageData string  `json:"image_data"` // base64-encoded image (optional)
+}
+
+// findAnimalUpdate loads the update named by the :id route parameter
+func findAnimalUpdate(c *gin.Context) (*models.AnimalUpdate, bool) {
+	var update models.AnimalUpdate
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&update).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Update not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching update"})
+		return nil, false
+	}
+	return &update, true
+}
+
+// notifySponsors emails a new update to the animal's active sponsors
+func notifySponsors(c *gin.Context, animal *models.Animal, update *models.AnimalUpdate) {
+	var emails []string
+	if err := activeSponsorships(config.DB.Model(&models.Sponsorship{})).
+		Where("animal_id = ?", animal.ID).
+		Distinct().Pluck("sponsor_email", &emails).Error; err != nil {
+		log.Printf("Failed to look up sponsors of animal %d: %v", animal.ID, err)
+		return
+	}
+
+	for _, email := range emails {
+		if err := config.Mailer.Send(c.Request.Context(), mailer.Message{
+			To:      email,
+			Subject: fmt.Sprintf("News about %s: %s", animal.NameEn, update.TitleEn),
+			Body: fmt.Sprintf("%s\n\n%s\n\n"+
+				"You receive this email because you sponsor %s. "+
+				"All updates are on your sponsor page.\n", update.TitleEn, update.BodyEn, animal.NameEn),
+		}); err != nil {
+			log.Printf("Update email for %s failed: %v", email, err)
+		}
+	}
+}
+
+// GetAnimalUpdates - List the sponsor-only updates of an animal, newest first
+func GetAnimalUpdates(c *gin.Context) {
+	animal, ok := findAnimal(c)
+	if !ok {
+		return
+	}
+
+	updates := []models.AnimalUpdate{}
+	if err := config.DB.Where("animal_id = ?", animal.ID).Order("created_at DESC").Find(&updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching updates"})
+		return
+	}
+	c.JSON(http.StatusOK, &updates)
+}
+
+// CreateAnimalUpdate - Post a sponsor-only update about an animal and email its sponsors
+func CreateAnimalUpdate(c *gin.Context) {
+	animal, ok := findAnimal(c)
+	if !ok {
+		return
+	}
+
+	var req AnimalUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+	if req.TitleEn == nil || *req.TitleEn == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title_en is required"})
+		return
+	}
+
+	update := models.AnimalUpdate{
+		AnimalID: animal.ID,
+		TitleEn:  *req.TitleEn,
+		AuthorID: c.MustGet("user").(models.User).ID,
+	}
+	if req.TitleUa != nil {
+		update.TitleUa = *req.TitleUa
+	}
+	if req.BodyEn != nil {
+		update.BodyEn = *req.BodyEn
+	}
+	if req.BodyUa != nil {
+		update.BodyUa = *req.BodyUa
+	}
+
+	if req.ImageData != "" {
+		store := middleware.GetStorage(c.Request.Context())
+		if store == nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Storage service not available"})
+			return
+		}
+
+		imageURL, err := store.UploadBase64(c.Request.Context(), req.ImageData, "animal-updates")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload image: " + err.Error()})
+			return
+		}
+		update.ImageUrl = imageURL
+	}
+
+	if err := config.DB.Create(&update).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create update"})
+		return
+	}
+	recordAudit(c, models.AuditCreate, "animal_update", update.ID, nil, update)
+
+	notifySponsors(c, animal, &update)
+
+	c.JSON(http.StatusCreated, update)
+}
+
+// UpdateAnimalUpdate - Edit a sponsor update; sponsors are not emailed again
+func UpdateAnimalUpdate(c *gin.Context) {
+	update, ok := findAnimalUpdate(c)
+	if !ok {
+		return
+	}
+	before := *update
+
+	var req AnimalUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+
+	if req.TitleEn != nil {
+		if *req.TitleEn == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "title_en cannot be empty"})
+			return
+		}
+		update.TitleEn = *req.TitleEn
+	}
+	if req.TitleUa != nil {
+		update.TitleUa = *req.TitleUa
+	}
+	if req.BodyEn != nil {
+		update.BodyEn = *req.BodyEn
+	}
+	if req.BodyUa != nil {
+		update.BodyUa = *req.BodyUa
+	}
+
+	if req.ImageData != "" {
+		store := middleware.GetStorage(c.Request.Context())
+		if store == nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Storage service not available"})
+			return
+		}
+
+		newImageURL, err := store.UploadBase64(c.Request.Context(), req.ImageData, "animal-updates")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload new image: " + err.Error()})
+			return
+		}
+		if update.ImageUrl != "" {
+			if oldObjectName := store.ExtractObjectName(update.ImageUrl); oldObjectName != "" {
+				_ = store.DeleteFile(c.Request.Context(), oldObjectName)
+			}
+		}
+		update.ImageUrl = newImageURL
+	}
+
+	if err := config.DB.Save(update).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update post"})
+		return
+	}
+	recordAudit(c, models.AuditUpdate, "animal_update", update.ID, before, *update)
+
+	c.JSON(http.StatusOK, update)
+}
+
+// DeleteAnimalUpdate - Delete a sponsor update and its image
+func DeleteAnimalUpdate(c *gin.Context) {
+	update, ok := findAnimalUpdate(c)
+	if !ok {
+		return
+	}
+
+	if update.ImageUrl != "" {
+		store := middleware.GetStorage(c.Request.Context())
+		if store == nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Storage service not available"})
+			return
+		}
+		if objectName := store.ExtractObjectName(update.ImageUrl); objectName != "" {
+			_ = store.DeleteFile(c.Request.Context(), objectName)
+		}
+	}
+
+	if err := config.DB.Delete(update).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete update"})
+		return
+	}
+	recordAudit(c, models.AuditDelete, "animal_update", update.ID, *update, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Update deleted successfully"})
+}
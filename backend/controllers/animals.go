@@ -0,0 +1,547 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kholodihor/cows-shelter-backend/config"
+	"github.com/kholodihor/cows-shelter-backend/middleware"
+	"github.com/kholodihor/cows-shelter-backend/models"
+	"gorm.io/gorm"
+)
+
+// CreateAnimalRequest represents the JSON request body for creating an animal
+type CreateAnimalRequest struct {
+	NameEn      string `json:"name_en" binding:"required"`
+	NameUa      string `json:"name_ua"`
+	StoryEn     string `json:"story_en"`
+	StoryUa     string `json:"story_ua"`
+	Breed       string `json:"breed"`
+	BirthDate   string `json:"birth_date"`   // YYYY-MM-DD
+	ArrivalDate string `json:"arrival_date"` // YYYY-MM-DD
+	Status      string `json:"status"`
+	MonthlyCost int64  `json:"monthly_cost"` // minor currency units
+	ImageData   string `json:"image_data"`   // base64-encoded cover photo
+}
+
+// UpdateAnimalRequest represents the JSON request body for updating an animal.
+// Omitted fields are left unchanged; an empty date clears it.
+type UpdateAnimalRequest struct {
+	NameEn      *string `json:"name_en"`
+	NameUa      *string `json:"name_ua"`
+	StoryEn     *string `json:"story_en"`
+	StoryUa     *string `json:"story_ua"`
+	Breed       *string `json:"breed"`
+	BirthDate   *string `json:"birth_date"`
+	ArrivalDate *string `json:"arrival_date"`
+	Status      *string `json:"status"`
+	MonthlyCost *int64  `json:"monthly_cost"`
+	ImageData   string  `json:"image_data"` // base64-encoded cover photo (optional)
+}
+
+// AddAnimalPhotoRequest represents the JSON request body for adding a photo
+type AddAnimalPhotoRequest struct {
+	ImageData string `json:"image_data" binding:"required"` // base64-encoded image data
+	CaptionEn string `json:"caption_en"`
+	CaptionUa string `json:"caption_ua"`
+}
+
+// parseDate parses an optional YYYY-MM-DD date; an empty string means no date
+func parseDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", value)
+	}
+	return &date, nil
+}
+
+// orderedPhotos preloads an animal's photos in display order
+func orderedPhotos(db *gorm.DB) *gorm.DB {
+	return db.Order("position, id")
+}
+
+// findAnimal loads the animal named by the :id route parameter with its photos
+func findAnimal(c *gin.Context) (*models.Animal, bool) {
+	var animal models.Animal
+	if err := config.DB.Preload("Photos", orderedPhotos).Where("id = ?", c.Param("id")).First(&animal).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Animal not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching animal"})
+		return nil, false
+	}
+	return &animal, true
+}
+
+// GetAllAnimals - List all animals; ?status= filters by status
+func GetAllAnimals(c *gin.Context) {
+	animals := []models.Animal{}
+	query := config.DB.Order("arrival_date DESC, id DESC")
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Find(&animals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching animals"})
+		return
+	}
+	c.JSON(http.StatusOK, &animals)
+}
+
+// GetAnimals - List animals with pagination; ?status= filters by status
+func GetAnimals(c *gin.Context) {
+	var animals []models.Animal
+	var total int64
+
+	// Default values for pagination
+	limit := 10
+	page := 1
+
+	if l := c.Query("limit"); l != "" {
+		fmt.Sscanf(l, "%d", &limit)
+	}
+	if p := c.Query("page"); p != "" {
+		fmt.Sscanf(p, "%d", &page)
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+	if page < 1 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	query := config.DB.Model(&models.Animal{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching animals"})
+		return
+	}
+	if err := query.Order("arrival_date DESC, id DESC").Limit(limit).Offset(offset).Find(&animals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching animals"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       animals,
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+		"totalPages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// GetAnimalByID - Get an animal's profile with its photos
+func GetAnimalByID(c *gin.Context) {
+	animal, ok := findAnimal(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, animal)
+}
+
+// CreateAnimal handles the creation of an animal with an optional cover photo
+// @Summary Create a new animal
+// @Description Create a new animal profile with an optional base64-encoded cover photo
+// @Tags animals
+// @Accept json
+// @Produce json
+// @Param input body CreateAnimalRequest true "Animal data"
+// @Success 201 {object} models.Animal
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /animals [post]
+func CreateAnimal(c *gin.Context) {
+	var req CreateAnimalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+
+	if req.Status == "" {
+		req.Status = models.AnimalResident
+	}
+	if req.MonthlyCost < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "monthly_cost cannot be negative"})
+		return
+	}
+	if !models.ValidAnimalStatus(req.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Status must be resident, adopted or deceased"})
+		return
+	}
+	birthDate, err := parseDate(req.BirthDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "birth_date: " + err.Error()})
+		return
+	}
+	arrivalDate, err := parseDate(req.ArrivalDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "arrival_date: " + err.Error()})
+		return
+	}
+
+	animal := models.Animal{
+		NameEn:      req.NameEn,
+		NameUa:      req.NameUa,
+		StoryEn:     req.StoryEn,
+		StoryUa:     req.StoryUa,
+		Breed:       req.Breed,
+		BirthDate:   birthDate,
+		ArrivalDate: arrivalDate,
+		Status:      req.Status,
+		MonthlyCost: req.MonthlyCost,
+	}
+
+	// Handle cover photo upload if present
+	if req.ImageData != "" {
+		store := middleware.GetStorage(c.Request.Context())
+		if store == nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Storage service not available"})
+			return
+		}
+
+		imageURL, err := store.UploadBase64(c.Request.Context(), req.ImageData, "animals")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload image: " + err.Error()})
+			return
+		}
+		animal.CoverImageUrl = imageURL
+	}
+
+	if err := config.DB.Create(&animal).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create animal"})
+		return
+	}
+	recordAudit(c, models.AuditCreate, "animal", animal.ID, nil, animal)
+
+	c.JSON(http.StatusCreated, animal)
+}
+
+// UpdateAnimal handles updating an animal with an optional new cover photo
+// @Summary Update an animal
+// @Description Update an animal profile; omitted fields are left unchanged
+// @Tags animals
+// @Accept json
+// @Produce json
+// @Param id path int true "Animal ID"
+// @Param input body UpdateAnimalRequest true "Updated animal data"
+// @Success 200 {object} models.Animal
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /animals/{id} [put]
+func UpdateAnimal(c *gin.Context) {
+	animal, ok := findAnimal(c)
+	if !ok {
+		return
+	}
+	before := *animal
+
+	var req UpdateAnimalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+
+	// Update fields if provided
+	if req.NameEn != nil {
+		if *req.NameEn == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "name_en cannot be empty"})
+			return
+		}
+		animal.NameEn = *req.NameEn
+	}
+	if req.NameUa != nil {
+		animal.NameUa = *req.NameUa
+	}
+	if req.StoryEn != nil {
+		animal.StoryEn = *req.StoryEn
+	}
+	if req.StoryUa != nil {
+		animal.StoryUa = *req.StoryUa
+	}
+	if req.Breed != nil {
+		animal.Breed = *req.Breed
+	}
+	if req.Status != nil {
+		if !models.ValidAnimalStatus(*req.Status) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Status must be resident, adopted or deceased"})
+			return
+		}
+		animal.Status = *req.Status
+	}
+	if req.MonthlyCost != nil {
+		if *req.MonthlyCost < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "monthly_cost cannot be negative"})
+			return
+		}
+		animal.MonthlyCost = *req.MonthlyCost
+	}
+	if req.BirthDate != nil {
+		birthDate, err := parseDate(*req.BirthDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "birth_date: " + err.Error()})
+			return
+		}
+		animal.BirthDate = birthDate
+	}
+	if req.ArrivalDate != nil {
+		arrivalDate, err := parseDate(*req.ArrivalDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "arrival_date: " + err.Error()})
+			return
+		}
+		animal.ArrivalDate = arrivalDate
+	}
+
+	// Handle cover photo upload if new image data is provided
+	if req.ImageData != "" {
+		store := middleware.GetStorage(c.Request.Context())
+		if store == nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Storage service not available"})
+			return
+		}
+
+		newImageURL, err := store.UploadBase64(c.Request.Context(), req.ImageData, "animals")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload new image: " + err.Error()})
+			return
+		}
+
+		// Delete the old cover photo if it exists
+		if animal.CoverImageUrl != "" {
+			if oldObjectName := store.ExtractObjectName(animal.CoverImageUrl); oldObjectName != "" {
+				_ = store.DeleteFile(c.Request.Context(), oldObjectName)
+			}
+		}
+		animal.CoverImageUrl = newImageURL
+	}
+
+	// Photos are managed through their own endpoints
+	if err := config.DB.Omit("Photos").Save(animal).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update animal"})
+		return
+	}
+	recordAudit(c, models.AuditUpdate, "animal", animal.ID, before, *animal)
+
+	c.JSON(http.StatusOK, animal)
+}
+
+// DeleteAnimal handles the deletion of an animal with its cover photo and photos
+// @Summary Delete an animal
+// @Description Delete an animal profile together with its stored photos
+// @Tags animals
+// @Produce json
+// @Param id path int true "Animal ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /animals/{id} [delete]
+func DeleteAnimal(c *gin.Context) {
+	animal, ok := findAnimal(c)
+	if !ok {
+		return
+	}
+
+	store := middleware.GetStorage(c.Request.Context())
+	if store == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Storage service not available"})
+		return
+	}
+
+	errHasMedicalRecords := errors.New("animal has medical records")
+	errHasSponsorships := errors.New("animal has sponsorships")
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Medical history is never removed as a side effect
+		var medicalRecords int64
+		if err := tx.Model(&models.MedicalRecord{}).Where("animal_id = ?", animal.ID).Count(&medicalRecords).Error; err != nil {
+			return err
+		}
+		if medicalRecords > 0 {
+			return errHasMedicalRecords
+		}
+
+		// Sponsorships would keep counting towards coverage and sending updates
+		var sponsorships int64
+		if err := tx.Model(&models.Sponsorship{}).
+			Where("animal_id = ? AND status <> ?", animal.ID, models.SponsorshipCancelled).
+			Count(&sponsorships).Error; err != nil {
+			return err
+		}
+		if sponsorships > 0 {
+			return errHasSponsorships
+		}
+
+		if err := tx.Where("animal_id = ?", animal.ID).Delete(&models.AnimalPhoto{}).Error; err != nil {
+			return err
+		}
+		return tx.Omit("Photos").Delete(animal).Error
+	})
+	if errors.Is(err, errHasMedicalRecords) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Animal has medical records; delete them before the animal"})
+		return
+	}
+	if errors.Is(err, errHasSponsorships) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Animal has sponsorships; cancel them before deleting the animal"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete animal"})
+		return
+	}
+
+	// Remove the stored images once the records are gone
+	urls := []string{animal.CoverImageUrl}
+	for _, photo := range animal.Photos {
+		urls = append(urls, photo.ImageUrl)
+	}
+	for _, url := range urls {
+		if objectName := store.ExtractObjectName(url); url != "" && objectName != "" {
+			_ = store.DeleteFile(c.Request.Context(), objectName)
+		}
+	}
+	recordAudit(c, models.AuditDelete, "animal", animal.ID, *animal, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Animal deleted successfully"})
+}
+
+// AddAnimalPhoto - Upload a photo and append it to the end of an animal's photo set
+func AddAnimalPhoto(c *gin.Context) {
+	animal, ok := findAnimal(c)
+	if !ok {
+		return
+	}
+
+	var req AddAnimalPhotoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+
+	store := middleware.GetStorage(c.Request.Context())
+	if store == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Storage service not available"})
+		return
+	}
+
+	imageURL, err := store.UploadBase64(c.Request.Context(), req.ImageData, "animals")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload image: " + err.Error()})
+		return
+	}
+
+	photo := models.AnimalPhoto{
+		AnimalID:  animal.ID,
+		ImageUrl:  imageURL,
+		CaptionEn: req.CaptionEn,
+		CaptionUa: req.CaptionUa,
+		Position:  len(animal.Photos),
+	}
+	if len(animal.Photos) > 0 {
+		photo.Position = animal.Photos[len(animal.Photos)-1].Position + 1
+	}
+
+	if err := config.DB.Create(&photo).Error; err != nil {
+		_ = store.DeleteFile(c.Request.Context(), store.ExtractObjectName(imageURL))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add photo"})
+		return
+	}
+	recordAudit(c, models.AuditCreate, "animal_photo", photo.ID, nil, photo)
+
+	c.JSON(http.StatusCreated, photo)
+}
+
+// ReorderAnimalPhotos - Set the display order of an animal's photos.
+// photo_ids must list every photo of the animal exactly once.
+func ReorderAnimalPhotos(c *gin.Context) {
+	animal, ok := findAnimal(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		PhotoIDs []uint `json:"photo_ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+
+	existing := map[uint]bool{}
+	for _, photo := range animal.Photos {
+		existing[photo.ID] = true
+	}
+	if len(req.PhotoIDs) != len(existing) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "photo_ids must list every photo of the animal exactly once"})
+		return
+	}
+	for _, id := range req.PhotoIDs {
+		if !existing[id] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "photo_ids must list every photo of the animal exactly once"})
+			return
+		}
+		delete(existing, id)
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		for position, id := range req.PhotoIDs {
+			if err := tx.Model(&models.AnimalPhoto{}).Where("id = ?", id).Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder photos"})
+		return
+	}
+
+	before := *animal
+	updated, ok := findAnimal(c)
+	if !ok {
+		return
+	}
+	recordAudit(c, models.AuditUpdate, "animal", animal.ID, gin.H{"photos": before.Photos}, gin.H{"photos": updated.Photos})
+
+	c.JSON(http.StatusOK, updated.Photos)
+}
+
+// DeleteAnimalPhoto - Remove a photo from an animal's photo set
+func DeleteAnimalPhoto(c *gin.Context) {
+	var photo models.AnimalPhoto
+	if err := config.DB.Where("id = ? AND animal_id = ?", c.Param("photo_id"), c.Param("id")).First(&photo).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Photo not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching photo"})
+		return
+	}
+
+	store := middleware.GetStorage(c.Request.Context())
+	if store == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Storage service not available"})
+		return
+	}
+
+	if err := config.DB.Delete(&photo).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete photo"})
+		return
+	}
+	if objectName := store.ExtractObjectName(photo.ImageUrl); objectName != "" {
+		_ = store.DeleteFile(c.Request.Context(), objectName)
+	}
+	recordAudit(c, models.AuditDelete, "animal_photo", photo.ID, photo, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Photo deleted successfully"})
+}
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kholodihor/cows-shelter-backend/config"
+	"github.com/kholodihor/cows-shelter-backend/middleware"
+	"github.com/kholodihor/cows-shelter-backend/models"
+	"github.com/kholodihor/cows-shelter-backend/utils"
+	"gorm.io/gorm"
+)
+
+// GetAPIKeys - List API keys; ?status=active|revoked|expired filters the list
+func GetAPIKeys(c *gin.Context) {
+	var keys []models.APIKey
+	query := config.DB.Order("created_at DESC")
+
+	now := time.Now()
+	switch c.Query("status") {
+	case "active":
+		query = query.Where("revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", now)
+	case "revoked":
+		query = query.Where("revoked_at IS NOT NULL")
+	case "expired":
+		query = query.Where("revoked_at IS NULL AND expires_at <= ?", now)
+	}
+
+	if err := query.Find(&keys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching API keys"})
+		return
+	}
+	c.JSON(http.StatusOK, &keys)
+}
+
+// CreateAPIKey - Create an API key acting for the current user; the key is only returned here
+func CreateAPIKey(c *gin.Context) {
+	var requestBody struct {
+		Name      string     `json:"name" binding:"required"`
+		Scopes    []string   `json:"scopes" binding:"required,min=1"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+	for _, scope := range requestBody.Scopes {
+		if !middleware.ValidPermission(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scope " + scope + ", expected <resource>:read, :write or :*"})
+			return
+		}
+		// A key can never do more than the user creating it
+		if !middleware.RoleHasPermission(user.Role, scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You cannot grant scope " + scope})
+			return
+		}
+	}
+	if requestBody.ExpiresAt != nil && !requestBody.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+		return
+	}
+
+	key, prefix, err := utils.GenerateAPIKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating API key"})
+		return
+	}
+
+	apiKey := models.APIKey{
+		Name:      requestBody.Name,
+		Prefix:    prefix,
+		KeyHash:   utils.HashToken(key),
+		Scopes:    requestBody.Scopes,
+		UserID:    user.ID,
+		ExpiresAt: requestBody.ExpiresAt,
+	}
+	if err := config.DB.Create(&apiKey).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating API key"})
+		return
+	}
+	recordAudit(c, models.AuditCreate, "api_key", apiKey.ID, nil, apiKey)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"api_key": apiKey,
+		"key":     key,
+	})
+}
+
+// RevokeAPIKey - Revoke an API key immediately
+func RevokeAPIKey(c *gin.Context) {
+	var apiKey models.APIKey
+	if err := config.DB.Where("id = ? AND revoked_at IS NULL", c.Param("id")).First(&apiKey).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Active API key not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching API key"})
+		return
+	}
+	before := apiKey
+
+	now := time.Now()
+	apiKey.RevokedAt = &now
+	if err := config.DB.Model(&apiKey).Update("revoked_at", now).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error revoking API key"})
+		return
+	}
+	recordAudit(c, models.AuditUpdate, "api_key", apiKey.ID, before, apiKey)
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
@@ -0,0 +1,237 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kholodihor/cows-shelter-backend/config"
+	"github.com/kholodihor/cows-shelter-backend/models"
+)
+
+// auditIgnoredFields change on every save and would only add noise to diffs
+var auditIgnoredFields = map[string]bool{
+	"UpdatedAt":  true,
+	"updated_at": true,
+}
+
+// auditFieldChange is one changed field in an audit entry
+type auditFieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// auditFields flattens an entity into its JSON fields. Fields hidden from JSON,
+// such as password hashes, never reach the audit log.
+func auditFields(entity interface{}) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	if entity == nil {
+		return fields, nil
+	}
+
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// auditDiff returns the fields that differ between before and after. Either
+// side may be nil for creates and deletes.
+func auditDiff(before, after interface{}) (json.RawMessage, error) {
+	beforeFields, err := auditFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := auditFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]auditFieldChange{}
+	for key, value := range beforeFields {
+		if auditIgnoredFields[key] {
+			continue
+		}
+		if next, ok := afterFields[key]; !ok || !reflect.DeepEqual(value, next) {
+			changes[key] = auditFieldChange{Before: value, After: afterFields[key]}
+		}
+	}
+	for key, value := range afterFields {
+		if _, ok := beforeFields[key]; !ok && !auditIgnoredFields[key] {
+			changes[key] = auditFieldChange{After: value}
+		}
+	}
+
+	return json.Marshal(changes)
+}
+
+// recordAudit stores an audit entry for a change made by the authenticated
+// user. Failures are logged rather than failing the request, since the change
+// itself has already been committed.
+func recordAudit(c *gin.Context, action, entityType string, entityID uint, before, after interface{}) {
+	saveAudit(c, models.AuditLog{Action: action, EntityType: entityType, EntityID: entityID},
+		fmt.Sprint(entityID), before, after)
+}
+
+// recordSessionAudit stores an audit entry for a change to a login session,
+// whose ID is not a number
+func recordSessionAudit(c *gin.Context, action, sessionID string, before, after interface{}) {
+	saveAudit(c, models.AuditLog{Action: action, EntityType: "session", EntityKey: sessionID},
+		sessionID, before, after)
+}
+
+// saveAudit completes the entry with the actor and the changes and stores it
+func saveAudit(c *gin.Context, entry models.AuditLog, entityID string, before, after interface{}) {
+	entityType := entry.EntityType
+	changes, err := auditDiff(before, after)
+	if err != nil {
+		log.Printf("Failed to build audit diff for %s %s: %v", entityType, entityID, err)
+		return
+	}
+
+	entry.ActorEmail = c.GetString("email")
+	entry.Changes = changes
+	entry.IP = c.ClientIP()
+	if user, ok := c.Get("user"); ok {
+		if actor, ok := user.(models.User); ok {
+			entry.ActorID = &actor.ID
+		}
+	}
+	if key, ok := c.Get("api_key"); ok {
+		if apiKey, ok := key.(models.APIKey); ok {
+			entry.APIKeyID = &apiKey.ID
+		}
+	}
+
+	if err := config.DB.Create(&entry).Error; err != nil {
+		log.Printf("Failed to record audit entry for %s %s: %v", entityType, entityID, err)
+	}
+}
+
+// auditRetention returns how long audit entries are kept; zero keeps them forever
+func auditRetention() time.Duration {
+	retention, err := time.ParseDuration(config.GetEnv("AUDIT_RETENTION", "8760h"))
+	if err != nil || retention < 0 {
+		return 8760 * time.Hour
+	}
+	return retention
+}
+
+// PruneAuditLog deletes audit entries older than the configured retention
+func PruneAuditLog() (int64, error) {
+	retention := auditRetention()
+	if retention == 0 {
+		return 0, nil
+	}
+
+	result := config.DB.Where("created_at < ?", time.Now().Add(-retention)).Delete(&models.AuditLog{})
+	return result.RowsAffected, result.Error
+}
+
+// StartAuditRetention prunes the audit log now and then every
+// AUDIT_PRUNE_INTERVAL until ctx is cancelled
+func StartAuditRetention(ctx context.Context) {
+	interval := envDuration("AUDIT_PRUNE_INTERVAL", 24*time.Hour)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if deleted, err := PruneAuditLog(); err != nil {
+				log.Printf("Failed to prune audit log: %v", err)
+			} else if deleted > 0 {
+				log.Printf("Pruned %d audit log entries", deleted)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// GetAuditLog - List audit entries, newest first, with pagination.
+// Filters: ?actor=, ?action=, ?entity_type=, ?entity_id=, ?from= and ?to= (RFC 3339).
+func GetAuditLog(c *gin.Context) {
+	var entries []models.AuditLog
+	var total int64
+
+	// Default values for pagination
+	limit := 20
+	page := 1
+
+	if l := c.Query("limit"); l != "" {
+		fmt.Sscanf(l, "%d", &limit)
+	}
+	if p := c.Query("page"); p != "" {
+		fmt.Sscanf(p, "%d", &page)
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	if page < 1 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	query := config.DB.Model(&models.AuditLog{})
+	if actor := c.Query("actor"); actor != "" {
+		query = query.Where("actor_email = ?", actor)
+	}
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if entityType := c.Query("entity_type"); entityType != "" {
+		query = query.Where("entity_type = ?", entityType)
+	}
+	if entityID := c.Query("entity_id"); entityID != "" {
+		// Sessions are identified by a UUID rather than a number
+		if _, err := strconv.ParseUint(entityID, 10, 64); err == nil {
+			query = query.Where("entity_id = ?", entityID)
+		} else {
+			query = query.Where("entity_key = ?", entityID)
+		}
+	}
+	for param, condition := range map[string]string{"from": "created_at >= ?", "to": "created_at < ?"} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid %s, expected an RFC 3339 timestamp", param)})
+			return
+		}
+		query = query.Where(condition, t)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching audit log"})
+		return
+	}
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching audit log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       entries,
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+		"totalPages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
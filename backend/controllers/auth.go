@@ -0,0 +1,246 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/kholodihor/cows-shelter-backend/config"
+	"github.com/kholodihor/cows-shelter-backend/models"
+	"github.com/kholodihor/cows-shelter-backend/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// tokenPair is the pair of tokens returned on login and refresh
+type tokenPair struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+// createRefreshToken stores a new refresh token in the given family and
+// returns the plain token together with the stored record
+func createRefreshToken(tx *gorm.DB, userID uint, familyID string) (string, *models.RefreshToken, error) {
+	plain, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	record := models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: utils.HashToken(plain),
+		ExpiresAt: time.Now().Add(utils.RefreshTokenTTL()),
+	}
+	if err := tx.Create(&record).Error; err != nil {
+		return "", nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	return plain, &record, nil
+}
+
+// issueTokens starts a new session for the user on the requesting device and
+// returns a fresh access token with the first refresh token of the session
+func issueTokens(c *gin.Context, user *models.User) (*tokenPair, error) {
+	now := time.Now()
+	session := models.Session{
+		ID:         uuid.New().String(),
+		UserID:     user.ID,
+		Device:     utils.DescribeUserAgent(c.Request.UserAgent()),
+		UserAgent:  c.Request.UserAgent(),
+		IP:         c.ClientIP(),
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(utils.RefreshTokenTTL()),
+	}
+
+	var refreshToken string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&session).Error; err != nil {
+			return fmt.Errorf("failed to store session: %w", err)
+		}
+
+		var err error
+		// The session ID doubles as the refresh token family
+		refreshToken, _, err = createRefreshToken(tx, user.ID, session.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := utils.GenerateJWT(user.Email, user.Role, session.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	return &tokenPair{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// tokenResponse renders a token pair together with basic user info
+func tokenResponse(user *models.User, tokens *tokenPair) gin.H {
+	return gin.H{
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    int(utils.AccessTokenTTL().Seconds()),
+		"user": gin.H{
+			"id":    user.ID,
+			"email": user.Email,
+			"role":  user.Role,
+		},
+	}
+}
+
+// revokeRefreshFamily revokes every still-active token of a refresh family
+// and ends the session it belongs to
+func revokeRefreshFamily(tx *gorm.DB, familyID string) error {
+	now := time.Now()
+	if err := tx.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", now).Error
+}
+
+// revokeUserRefreshTokens revokes every active refresh token and session of a user
+func revokeUserRefreshTokens(tx *gorm.DB, userID uint) error {
+	now := time.Now()
+	if err := tx.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error
+}
+
+var (
+	errRefreshInvalid = errors.New("invalid refresh token")
+	errRefreshReused  = errors.New("refresh token reuse detected")
+)
+
+// RefreshToken - Exchange a refresh token for a new access and refresh token
+func RefreshToken(c *gin.Context) {
+	var requestBody struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	var user models.User
+	var tokens tokenPair
+	var reusedFamilyID string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var current models.RefreshToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", utils.HashToken(requestBody.RefreshToken)).
+			First(&current).Error; err != nil {
+			return errRefreshInvalid
+		}
+
+		// A token that was already rotated is being replayed: assume it was
+		// stolen and kill the whole chain, including the legitimate holder
+		if current.ReplacedByID != nil {
+			reusedFamilyID = current.FamilyID
+			return errRefreshReused
+		}
+
+		if current.RevokedAt != nil || time.Now().After(current.ExpiresAt) {
+			return errRefreshInvalid
+		}
+
+		if err := tx.First(&user, current.UserID).Error; err != nil || user.DeactivatedAt != nil {
+			return errRefreshInvalid
+		}
+
+		plain, next, err := createRefreshToken(tx, user.ID, current.FamilyID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := tx.Model(&current).Updates(map[string]interface{}{
+			"revoked_at":     now,
+			"replaced_by_id": next.ID,
+		}).Error; err != nil {
+			return err
+		}
+
+		// Refresh chains started before sessions were tracked get one now
+		session := models.Session{
+			ID:        current.FamilyID,
+			UserID:    user.ID,
+			Device:    utils.DescribeUserAgent(c.Request.UserAgent()),
+			UserAgent: c.Request.UserAgent(),
+		}
+		if err := tx.FirstOrCreate(&session, models.Session{ID: current.FamilyID}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&session).Updates(map[string]interface{}{
+			"last_seen_at": now,
+			"ip":           c.ClientIP(),
+			"expires_at":   next.ExpiresAt,
+		}).Error; err != nil {
+			return err
+		}
+
+		accessToken, err := utils.GenerateJWT(user.Email, user.Role, current.FamilyID)
+		if err != nil {
+			return err
+		}
+
+		tokens = tokenPair{AccessToken: accessToken, RefreshToken: plain}
+		return nil
+	})
+
+	switch {
+	case errors.Is(err, errRefreshReused):
+		// Revoke outside the rolled-back transaction so it sticks
+		if err := revokeRefreshFamily(config.DB, reusedFamilyID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error revoking session"})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token reuse detected, please log in again"})
+		return
+	case errors.Is(err, errRefreshInvalid):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error refreshing token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokenResponse(&user, &tokens))
+}
+
+// Logout - Revoke the refresh token family the given token belongs to
+func Logout(c *gin.Context) {
+	var requestBody struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	var current models.RefreshToken
+	if err := config.DB.Where("token_hash = ?", utils.HashToken(requestBody.RefreshToken)).First(&current).Error; err == nil {
+		if err := revokeRefreshFamily(config.DB, current.FamilyID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error revoking session"})
+			return
+		}
+	}
+
+	// Unknown tokens are treated as already logged out
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
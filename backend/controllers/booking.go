@@ -0,0 +1,788 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kholodihor/cows-shelter-backend/config"
+	"github.com/kholodihor/cows-shelter-backend/mailer"
+	"github.com/kholodihor/cows-shelter-backend/models"
+	"github.com/kholodihor/cows-shelter-backend/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errNotEnoughSeats is returned when a session cannot fit a booking
+var errNotEnoughSeats = errors.New("not enough seats left")
+
+// bookingConfirmTTL is how long a pending booking holds its seats. It is kept
+// short because anyone can hold seats with an unconfirmed booking.
+func bookingConfirmTTL() time.Duration {
+	return envDuration("BOOKING_CONFIRM_TTL", 20*time.Minute)
+}
+
+// bookingMaxSeats is the largest number of seats one booking may take
+func bookingMaxSeats() int {
+	return envInt("BOOKING_MAX_SEATS", 10)
+}
+
+// activeBookingStatuses are the statuses that hold seats
+var activeBookingStatuses = []string{models.BookingPending, models.BookingConfirmed}
+
+// reserveSeats takes seats from a scheduled, not yet started session. The
+// capacity check and the increment are one conditional UPDATE, so concurrent
+// bookings can never push a session past its capacity.
+func reserveSeats(tx *gorm.DB, sessionID uint, seats int) error {
+	result := tx.Model(&models.ExcursionSession{}).
+		Where("id = ? AND status = ? AND starts_at > ? AND booked_seats + ? <= capacity",
+			sessionID, models.SessionScheduled, time.Now(), seats).
+		UpdateColumn("booked_seats", gorm.Expr("booked_seats + ?", seats))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != 1 {
+		return errNotEnoughSeats
+	}
+	return nil
+}
+
+// releaseSeats gives seats back to a session
+func releaseSeats(tx *gorm.DB, sessionID uint, seats int) error {
+	return tx.Model(&models.ExcursionSession{}).
+		Where("id = ?", sessionID).
+		UpdateColumn("booked_seats", gorm.Expr("GREATEST(booked_seats - ?, 0)", seats)).Error
+}
+
+// transitionBooking moves a booking to a new status if it is still in one of
+// the given statuses. It reports whether the booking changed, so seats are
+// released only once even when two requests race.
+func transitionBooking(tx *gorm.DB, booking *models.Booking, to string, from ...string) (bool, error) {
+	now := time.Now()
+	updates := map[string]interface{}{"status": to}
+	switch to {
+	case models.BookingConfirmed:
+		updates["confirmed_at"] = now
+		updates["expires_at"] = nil
+	case models.BookingCancelled:
+		updates["cancelled_at"] = now
+	}
+
+	result := tx.Model(&models.Booking{}).Where("id = ? AND status IN ?", booking.ID, from).Updates(updates)
+	if result.Error != nil || result.RowsAffected != 1 {
+		return false, result.Error
+	}
+
+	booking.Status = to
+	switch to {
+	case models.BookingConfirmed:
+		booking.ConfirmedAt = &now
+		booking.ExpiresAt = nil
+	case models.BookingCancelled:
+		booking.CancelledAt = &now
+	}
+	return true, nil
+}
+
+// cancelBooking cancels a pending or confirmed booking and offers its seats
+// to the waitlist
+func cancelBooking(ctx context.Context, booking *models.Booking) (bool, error) {
+	var changed bool
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		changed, err = transitionBooking(tx, booking, models.BookingCancelled, activeBookingStatuses...)
+		if err != nil || !changed {
+			return err
+		}
+		return releaseSeats(tx, booking.SessionID, booking.Seats)
+	})
+	if changed && err == nil {
+		offerFreedSeats(ctx, booking.SessionID)
+	}
+	return changed, err
+}
+
+// formatSessionTime formats a session start in the shelter's time zone for visitor emails
+func formatSessionTime(t time.Time) string {
+	return t.In(config.ShelterLocation()).Format("Mon, 02 Jan 2006 15:04 MST")
+}
+
+// sessionTitle returns the excursion title of a session for emails
+func sessionTitle(session *models.ExcursionSession) string {
+	if session.Excursion != nil && session.Excursion.TitleEn != "" {
+		return session.Excursion.TitleEn
+	}
+	return "Excursion"
+}
+
+// bookingLink is the frontend page where a visitor manages a booking
+func bookingLink(token string) string {
+	return fmt.Sprintf("%s/bookings/%s", config.GetEnv("FRONTEND_URL", "http://localhost:5173"), token)
+}
+
+// sendBookingCancelledEmail tells a visitor that the shelter cancelled their booking
+func sendBookingCancelledEmail(ctx context.Context, booking *models.Booking, session *models.ExcursionSession) {
+	if err := config.Mailer.Send(ctx, mailer.Message{
+		To:      booking.Email,
+		Subject: fmt.Sprintf("Your booking for %s was cancelled", sessionTitle(session)),
+		Body: fmt.Sprintf("Hello %s,\n\nUnfortunately your booking of %d seat(s) for %s on %s had to be cancelled. "+
+			"We are sorry for the inconvenience and hope to see you at another time.\n",
+			booking.Name, booking.Seats, sessionTitle(session), formatSessionTime(session.StartsAt)),
+	}); err != nil {
+		log.Printf("Cancellation email for booking %d failed: %v", booking.ID, err)
+	}
+}
+
+// findSession loads the excursion session named by the :id route parameter
+func findSession(c *gin.Context) (*models.ExcursionSession, bool) {
+	var session models.ExcursionSession
+	if err := config.DB.Preload("Excursion").Where("id = ?", c.Param("id")).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching session"})
+		return nil, false
+	}
+	return &session, true
+}
+
+// findBookingByToken loads the booking for a booking link
+func findBookingByToken(c *gin.Context) (*models.Booking, bool) {
+	var booking models.Booking
+	if err := config.DB.Preload("Session.Excursion").
+		Where("token_hash = ?", utils.HashToken(c.Param("token"))).
+		First(&booking).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching booking"})
+		return nil, false
+	}
+	return &booking, true
+}
+
+// GetExcursionSessions - List the upcoming sessions of an excursion with the
+// seats left. Recurring schedules are expanded up to ?to= (RFC 3339), which
+// defaults to SCHEDULE_HORIZON from now.
+func GetExcursionSessions(c *gin.Context) {
+	var excursion models.Excursion
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&excursion).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Excursion not found"})
+		return
+	}
+
+	now := time.Now()
+	until := now.Add(scheduleHorizon())
+	if to := c.Query("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be an RFC 3339 timestamp"})
+			return
+		}
+		until = t
+	}
+	if err := expandSchedules(excursion.ID, until); err != nil {
+		log.Printf("Failed to expand schedules of excursion %d: %v", excursion.ID, err)
+	}
+
+	sessions := []models.ExcursionSession{}
+	if err := config.DB.
+		Where("excursion_id = ? AND status = ? AND starts_at > ? AND starts_at <= ?", excursion.ID, models.SessionScheduled, now, until).
+		Order("starts_at").Find(&sessions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching sessions"})
+		return
+	}
+	c.JSON(http.StatusOK, &sessions)
+}
+
+// CreateBooking - Reserve seats in a session. The seats are held until the
+// visitor confirms through the emailed link or the hold expires.
+func CreateBooking(c *gin.Context) {
+	var requestBody struct {
+		Name  string `json:"name" binding:"required"`
+		Email string `json:"email" binding:"required,email"`
+		Phone string `json:"phone"`
+		Seats int    `json:"seats" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+	if maxSeats := bookingMaxSeats(); requestBody.Seats > maxSeats {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A booking can have at most %d seats", maxSeats)})
+		return
+	}
+
+	wait, err := allowPublicRequest(models.ThrottleBookingIP, c.ClientIP(),
+		envInt("BOOKINGS_PER_IP", 5), publicFormWindow())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating booking"})
+		return
+	}
+	if wait > 0 {
+		respondThrottled(c, wait)
+		return
+	}
+
+	session, ok := findSession(c)
+	if !ok {
+		return
+	}
+	if session.Status != models.SessionScheduled || !session.StartsAt.After(time.Now()) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This session can no longer be booked"})
+		return
+	}
+
+	email := normalizeEmail(requestBody.Email)
+	var existing int64
+	if err := config.DB.Model(&models.Booking{}).
+		Where("session_id = ? AND email = ? AND status IN ?", session.ID, email, activeBookingStatuses).
+		Count(&existing).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking bookings"})
+		return
+	}
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "You already have a booking for this session"})
+		return
+	}
+
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating booking"})
+		return
+	}
+
+	expiresAt := time.Now().Add(bookingConfirmTTL())
+	booking := models.Booking{
+		SessionID: session.ID,
+		Name:      requestBody.Name,
+		Email:     email,
+		Phone:     requestBody.Phone,
+		Seats:     requestBody.Seats,
+		Status:    models.BookingPending,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: &expiresAt,
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := reserveSeats(tx, session.ID, booking.Seats); err != nil {
+			return err
+		}
+		return tx.Create(&booking).Error
+	})
+	if errors.Is(err, errNotEnoughSeats) {
+		config.DB.Where("id = ?", session.ID).First(session)
+		c.JSON(http.StatusConflict, gin.H{
+			"error":      "Not enough seats left; you can join the waitlist instead",
+			"seats_left": session.SeatsLeft,
+			"waitlist":   fmt.Sprintf("/api/excursion-sessions/%d/waitlist", session.ID),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating booking"})
+		return
+	}
+
+	if err := config.Mailer.Send(c.Request.Context(), mailer.Message{
+		To:      booking.Email,
+		Subject: fmt.Sprintf("Confirm your booking for %s", sessionTitle(session)),
+		Body: fmt.Sprintf("Hello %s,\n\nWe are holding %d seat(s) for you for %s on %s.\n\n"+
+			"Open the link below to confirm your booking within %s, otherwise the seats are released. "+
+			"You can also cancel your booking there.\n\n%s\n",
+			booking.Name, booking.Seats, sessionTitle(session), formatSessionTime(session.StartsAt),
+			bookingConfirmTTL(), bookingLink(token)),
+	}); err != nil {
+		// The hold expires on its own if the visitor never gets the link
+		log.Printf("Booking email for booking %d failed: %v", booking.ID, err)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "We have emailed you a link to confirm your booking",
+		"booking": booking,
+	})
+}
+
+// GetBookingByToken - Show a booking to the holder of its link
+func GetBookingByToken(c *gin.Context) {
+	booking, ok := findBookingByToken(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, booking)
+}
+
+// ConfirmBooking - Confirm a pending booking through its link
+func ConfirmBooking(c *gin.Context) {
+	booking, ok := findBookingByToken(c)
+	if !ok {
+		return
+	}
+	if booking.Status == models.BookingConfirmed {
+		c.JSON(http.StatusOK, booking)
+		return
+	}
+	if booking.Status != models.BookingPending || (booking.ExpiresAt != nil && booking.ExpiresAt.Before(time.Now())) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This booking can no longer be confirmed"})
+		return
+	}
+
+	changed, err := transitionBooking(config.DB, booking, models.BookingConfirmed, models.BookingPending)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error confirming booking"})
+		return
+	}
+	if !changed {
+		c.JSON(http.StatusConflict, gin.H{"error": "This booking can no longer be confirmed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, booking)
+}
+
+// CancelBookingByToken - Let a visitor cancel their booking before the session starts
+func CancelBookingByToken(c *gin.Context) {
+	booking, ok := findBookingByToken(c)
+	if !ok {
+		return
+	}
+	if booking.Session != nil && !booking.Session.StartsAt.After(time.Now()) {
+		c.JSON(http.StatusConflict, gin.H{"error": "The session has already started"})
+		return
+	}
+
+	changed, err := cancelBooking(c.Request.Context(), booking)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error cancelling booking"})
+		return
+	}
+	if !changed {
+		c.JSON(http.StatusConflict, gin.H{"error": "This booking is not active"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Your booking has been cancelled"})
+}
+
+// GetSessions - List excursion sessions with pagination.
+// Filters: ?excursion_id=, ?status=, ?from= and ?to= (RFC 3339).
+func GetSessions(c *gin.Context) {
+	var sessions []models.ExcursionSession
+	var total int64
+
+	// Default values for pagination
+	limit := 20
+	page := 1
+
+	if l := c.Query("limit"); l != "" {
+		fmt.Sscanf(l, "%d", &limit)
+	}
+	if p := c.Query("page"); p != "" {
+		fmt.Sscanf(p, "%d", &page)
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	if page < 1 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	if err := expandSchedules(0, time.Now().Add(scheduleHorizon())); err != nil {
+		log.Printf("Failed to expand schedules: %v", err)
+	}
+
+	query := config.DB.Model(&models.ExcursionSession{})
+	if excursionID := c.Query("excursion_id"); excursionID != "" {
+		query = query.Where("excursion_id = ?", excursionID)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	for param, condition := range map[string]string{"from": "starts_at >= ?", "to": "starts_at < ?"} {
+		if value := c.Query(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": param + " must be an RFC 3339 timestamp"})
+				return
+			}
+			query = query.Where(condition, t)
+		}
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching sessions"})
+		return
+	}
+	if err := query.Preload("Excursion").Order("starts_at").Limit(limit).Offset(offset).Find(&sessions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       sessions,
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+		"totalPages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// CreateExcursionSession - Schedule a bookable session of an excursion
+func CreateExcursionSession(c *gin.Context) {
+	var requestBody struct {
+		StartsAt time.Time `json:"starts_at" binding:"required"`
+		EndsAt   time.Time `json:"ends_at" binding:"required"`
+		Capacity int       `json:"capacity" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+	if !requestBody.EndsAt.After(requestBody.StartsAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ends_at must be after starts_at"})
+		return
+	}
+
+	var excursion models.Excursion
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&excursion).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Excursion not found"})
+		return
+	}
+
+	session := models.ExcursionSession{
+		ExcursionID: excursion.ID,
+		StartsAt:    requestBody.StartsAt,
+		EndsAt:      requestBody.EndsAt,
+		Capacity:    requestBody.Capacity,
+		Status:      models.SessionScheduled,
+		SeatsLeft:   requestBody.Capacity,
+	}
+	if err := config.DB.Create(&session).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+		return
+	}
+	recordAudit(c, models.AuditCreate, "excursion_session", session.ID, nil, session)
+
+	c.JSON(http.StatusCreated, session)
+}
+
+// UpdateExcursionSession - Move a session or change its capacity. Capacity
+// cannot drop below the seats already booked.
+func UpdateExcursionSession(c *gin.Context) {
+	var requestBody struct {
+		StartsAt *time.Time `json:"starts_at"`
+		EndsAt   *time.Time `json:"ends_at"`
+		Capacity *int       `json:"capacity"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+
+	var session, before models.ExcursionSession
+	var validationErr string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the row so bookings wait until the new capacity is in place
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", c.Param("id")).First(&session).Error; err != nil {
+			return err
+		}
+		before = session
+
+		if requestBody.StartsAt != nil {
+			session.StartsAt = *requestBody.StartsAt
+		}
+		if requestBody.EndsAt != nil {
+			session.EndsAt = *requestBody.EndsAt
+		}
+		if requestBody.Capacity != nil {
+			session.Capacity = *requestBody.Capacity
+		}
+
+		switch {
+		case !session.EndsAt.After(session.StartsAt):
+			validationErr = "ends_at must be after starts_at"
+		case session.Capacity < 1:
+			validationErr = "capacity must be at least 1"
+		case session.Capacity < session.BookedSeats:
+			validationErr = fmt.Sprintf("capacity cannot be lower than the %d seats already booked", session.BookedSeats)
+		}
+		if validationErr != "" {
+			return nil
+		}
+
+		return tx.Model(&session).Updates(map[string]interface{}{
+			"starts_at": session.StartsAt,
+			"ends_at":   session.EndsAt,
+			"capacity":  session.Capacity,
+		}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update session"})
+		return
+	}
+	if validationErr != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr})
+		return
+	}
+	config.DB.Where("id = ?", session.ID).First(&session)
+	recordAudit(c, models.AuditUpdate, "excursion_session", session.ID, before, session)
+
+	if session.Capacity > before.Capacity {
+		offerFreedSeats(c.Request.Context(), session.ID)
+		config.DB.Where("id = ?", session.ID).First(&session)
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// CancelExcursionSession - Cancel a session and all of its bookings, emailing the visitors
+func CancelExcursionSession(c *gin.Context) {
+	session, ok := findSession(c)
+	if !ok {
+		return
+	}
+	if session.Status == models.SessionCancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Session is already cancelled"})
+		return
+	}
+	before := *session
+
+	cancelled, err := cancelSession(c.Request.Context(), session)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel session"})
+		return
+	}
+	recordAudit(c, models.AuditUpdate, "excursion_session", session.ID, before, *session)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":            "Session cancelled",
+		"cancelled_bookings": cancelled,
+	})
+}
+
+// cancelSession cancels a session and all of its bookings and emails the
+// visitors. It returns the number of cancelled bookings.
+func cancelSession(ctx context.Context, session *models.ExcursionSession) (int, error) {
+	var cancelled []models.Booking
+	var waitlisted []models.WaitlistEntry
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", session.ID).First(&models.ExcursionSession{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("session_id = ? AND status IN ?", session.ID, activeBookingStatuses).Find(&cancelled).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Booking{}).
+			Where("session_id = ? AND status IN ?", session.ID, activeBookingStatuses).
+			Updates(map[string]interface{}{"status": models.BookingCancelled, "cancelled_at": time.Now()}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("session_id = ? AND status IN ?", session.ID, activeWaitlistStatuses).Find(&waitlisted).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.WaitlistEntry{}).
+			Where("session_id = ? AND status IN ?", session.ID, activeWaitlistStatuses).
+			Updates(map[string]interface{}{"status": models.WaitlistCancelled, "claim_token_hash": nil}).Error; err != nil {
+			return err
+		}
+		return tx.Model(session).Updates(map[string]interface{}{"status": models.SessionCancelled, "booked_seats": 0}).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	session.Status = models.SessionCancelled
+	session.BookedSeats = 0
+	session.SeatsLeft = 0
+
+	for i := range cancelled {
+		sendBookingCancelledEmail(ctx, &cancelled[i], session)
+	}
+	for _, entry := range waitlisted {
+		if err := config.Mailer.Send(ctx, mailer.Message{
+			To:      entry.Email,
+			Subject: fmt.Sprintf("%s on %s was cancelled", sessionTitle(session), formatSessionTime(session.StartsAt)),
+			Body: fmt.Sprintf("Hello %s,\n\nThe session of %s on %s you were waiting for has been cancelled, "+
+				"so your waitlist entry was closed. We hope to see you at another time.\n",
+				entry.Name, sessionTitle(session), formatSessionTime(session.StartsAt)),
+		}); err != nil {
+			log.Printf("Cancellation email for waitlist entry %d failed: %v", entry.ID, err)
+		}
+	}
+	return len(cancelled), nil
+}
+
+// DeleteExcursionSession - Delete a session that has no active bookings
+func DeleteExcursionSession(c *gin.Context) {
+	session, ok := findSession(c)
+	if !ok {
+		return
+	}
+
+	var active int64
+	if err := config.DB.Model(&models.Booking{}).
+		Where("session_id = ? AND status IN ?", session.ID, activeBookingStatuses).
+		Count(&active).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking bookings"})
+		return
+	}
+	if active > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Session has active bookings; cancel it instead"})
+		return
+	}
+
+	if err := config.DB.Omit("Excursion").Delete(session).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete session"})
+		return
+	}
+	recordAudit(c, models.AuditDelete, "excursion_session", session.ID, *session, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session deleted successfully"})
+}
+
+// GetSessionAttendees - List the bookings of a session. By default only
+// pending and confirmed bookings are shown; ?status=all includes the rest.
+func GetSessionAttendees(c *gin.Context) {
+	session, ok := findSession(c)
+	if !ok {
+		return
+	}
+
+	bookings := []models.Booking{}
+	query := config.DB.Where("session_id = ?", session.ID).Order("created_at")
+	switch status := c.Query("status"); status {
+	case "":
+		query = query.Where("status IN ?", activeBookingStatuses)
+	case "all":
+	default:
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Find(&bookings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching bookings"})
+		return
+	}
+
+	var confirmedSeats, pendingSeats int
+	for _, booking := range bookings {
+		switch booking.Status {
+		case models.BookingConfirmed:
+			confirmedSeats += booking.Seats
+		case models.BookingPending:
+			pendingSeats += booking.Seats
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session":         session,
+		"bookings":        bookings,
+		"confirmed_seats": confirmedSeats,
+		"pending_seats":   pendingSeats,
+	})
+}
+
+// AdminCancelBooking - Cancel a visitor's booking on their behalf and email them
+func AdminCancelBooking(c *gin.Context) {
+	var booking models.Booking
+	if err := config.DB.Preload("Session.Excursion").Where("id = ?", c.Param("id")).First(&booking).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching booking"})
+		return
+	}
+	before := booking
+
+	changed, err := cancelBooking(c.Request.Context(), &booking)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error cancelling booking"})
+		return
+	}
+	if !changed {
+		c.JSON(http.StatusConflict, gin.H{"error": "Booking is not active"})
+		return
+	}
+	recordAudit(c, models.AuditUpdate, "booking", booking.ID, before, booking)
+
+	if booking.Session != nil {
+		sendBookingCancelledEmail(c.Request.Context(), &booking, booking.Session)
+	}
+
+	c.JSON(http.StatusOK, booking)
+}
+
+// ExpirePendingBookings releases the seats of bookings that were not
+// confirmed in time and offers them to the waitlist
+func ExpirePendingBookings(ctx context.Context) (int, error) {
+	var bookings []models.Booking
+	if err := config.DB.Where("status = ? AND expires_at < ?", models.BookingPending, time.Now()).
+		Find(&bookings).Error; err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	sessions := map[uint]bool{}
+	for i := range bookings {
+		booking := &bookings[i]
+		var changed bool
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			var err error
+			changed, err = transitionBooking(tx, booking, models.BookingExpired, models.BookingPending)
+			if err != nil || !changed {
+				return err
+			}
+			return releaseSeats(tx, booking.SessionID, booking.Seats)
+		})
+		if err != nil {
+			return expired, err
+		}
+		if changed {
+			expired++
+			sessions[booking.SessionID] = true
+		}
+	}
+	for sessionID := range sessions {
+		offerFreedSeats(ctx, sessionID)
+	}
+	return expired, nil
+}
+
+// StartBookingExpiry expires unconfirmed bookings and unclaimed waitlist
+// offers now and then every BOOKING_EXPIRY_INTERVAL until ctx is cancelled
+func StartBookingExpiry(ctx context.Context) {
+	interval := envDuration("BOOKING_EXPIRY_INTERVAL", 5*time.Minute)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if expired, err := ExpirePendingBookings(ctx); err != nil {
+				log.Printf("Failed to expire bookings: %v", err)
+			} else if expired > 0 {
+				log.Printf("Expired %d unconfirmed bookings", expired)
+			}
+			if expired, err := ExpireWaitlistOffers(ctx); err != nil {
+				log.Printf("Failed to expire waitlist offers: %v", err)
+			} else if expired > 0 {
+				log.Printf("Expired %d unclaimed waitlist offers", expired)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
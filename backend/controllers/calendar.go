@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kholodihor/cows-shelter-backend/config"
+	"github.com/kholodihor/cows-shelter-backend/models"
+	"github.com/kholodihor/cows-shelter-backend/utils"
+)
+
+// calendarPast is how far back feeds keep sessions, so subscribers still see
+// recent visits and cancellations
+const calendarPast = 30 * 24 * time.Hour
+
+// calendarLang reads ?lang=, accepting ua (or uk) for Ukrainian and
+// defaulting to English
+func calendarLang(c *gin.Context) string {
+	switch strings.ToLower(c.Query("lang")) {
+	case "ua", "uk":
+		return "ua"
+	default:
+		return "en"
+	}
+}
+
+// localized picks the text in lang, falling back to the other language
+func localized(lang, en, ua string) string {
+	if lang == "ua" && ua != "" {
+		return ua
+	}
+	if en == "" {
+		return ua
+	}
+	return en
+}
+
+// calendarDomain is the host used to make event UIDs globally unique
+func calendarDomain() string {
+	if u, err := url.Parse(config.GetEnv("FRONTEND_URL", "")); err == nil && u.Hostname() != "" {
+		return u.Hostname()
+	}
+	return "cows-shelter"
+}
+
+// excursionLink is the frontend page of an excursion
+func excursionLink(id uint) string {
+	return fmt.Sprintf("%s/excursions/%d", config.GetEnv("FRONTEND_URL", "http://localhost:5173"), id)
+}
+
+// excursionEvent builds a calendar event for an excursion taking place
+// between start and end
+func excursionEvent(excursion *models.Excursion, uid string, start, end time.Time, cancelled bool, modified time.Time, lang string) utils.ICalEvent {
+	status := utils.ICalConfirmed
+	if cancelled {
+		status = utils.ICalCancelled
+	}
+	return utils.ICalEvent{
+		UID:          uid + "@" + calendarDomain(),
+		Start:        start,
+		End:          end,
+		Summary:      localized(lang, excursion.TitleEn, excursion.TitleUa),
+		Description:  localized(lang, excursion.DescriptionEn, excursion.DescriptionUa),
+		Location:     config.GetEnv("SHELTER_ADDRESS", ""),
+		URL:          excursionLink(excursion.ID),
+		Status:       status,
+		LastModified: modified,
+	}
+}
+
+// excursionEvents returns the events of the given excursions (all when
+// excursionID is 0): their sessions from calendarPast ago up to the schedule
+// horizon, cancelled ones included, plus one-off excursions with fixed times
+func excursionEvents(excursionID uint, lang string) ([]utils.ICalEvent, error) {
+	now := time.Now()
+	if err := expandSchedules(excursionID, now.Add(scheduleHorizon())); err != nil {
+		log.Printf("Failed to expand schedules for calendar: %v", err)
+	}
+	since := now.Add(-calendarPast)
+
+	var sessions []models.ExcursionSession
+	query := config.DB.Preload("Excursion").Where("starts_at >= ?", since)
+	if excursionID != 0 {
+		query = query.Where("excursion_id = ?", excursionID)
+	}
+	if err := query.Order("starts_at").Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	var excursions []models.Excursion
+	query = config.DB.Where("starts_at IS NOT NULL AND ends_at >= ?", since)
+	if excursionID != 0 {
+		query = query.Where("id = ?", excursionID)
+	}
+	if err := query.Order("starts_at").Find(&excursions).Error; err != nil {
+		return nil, err
+	}
+
+	events := make([]utils.ICalEvent, 0, len(sessions)+len(excursions))
+	for i := range excursions {
+		excursion := &excursions[i]
+		events = append(events, excursionEvent(excursion, fmt.Sprintf("excursion-%d", excursion.ID),
+			*excursion.StartsAt, *excursion.EndsAt, false, excursion.UpdatedAt, lang))
+	}
+	for i := range sessions {
+		session := &sessions[i]
+		if session.Excursion == nil {
+			continue // excursion was deleted
+		}
+		events = append(events, excursionEvent(session.Excursion, fmt.Sprintf("session-%d", session.ID),
+			session.StartsAt, session.EndsAt, session.Status == models.SessionCancelled, session.UpdatedAt, lang))
+	}
+	return events, nil
+}
+
+// writeCalendar sends a calendar document
+func writeCalendar(c *gin.Context, cal *utils.Calendar) {
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", cal.Encode())
+}
+
+// GetExcursionsCalendar - Public iCalendar feed of all excursions that
+// calendar apps can subscribe to. ?lang=en|ua selects the titles.
+func GetExcursionsCalendar(c *gin.Context) {
+	lang := calendarLang(c)
+	events, err := excursionEvents(0, lang)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching excursions"})
+		return
+	}
+	writeCalendar(c, &utils.Calendar{
+		Name:       localized(lang, "Cows Shelter excursions", "Екскурсії притулку для корів"),
+		Location:   config.ShelterLocation(),
+		RefreshTTL: time.Hour,
+		Events:     events,
+	})
+}
+
+// GetExcursionCalendar - Download the dates of one excursion as an .ics file
+func GetExcursionCalendar(c *gin.Context) {
+	var excursion models.Excursion
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&excursion).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Excursion not found"})
+		return
+	}
+	lang := calendarLang(c)
+	events, err := excursionEvents(excursion.ID, lang)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching sessions"})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="excursion-%d.ics"`, excursion.ID))
+	writeCalendar(c, &utils.Calendar{
+		Name:     localized(lang, excursion.TitleEn, excursion.TitleUa),
+		Location: config.ShelterLocation(),
+		Events:   events,
+	})
+}
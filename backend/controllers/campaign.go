@@ -0,0 +1,469 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kholodihor/cows-shelter-backend/config"
+	"github.com/kholodihor/cows-shelter-backend/models"
+	"gorm.io/gorm"
+)
+
+// CampaignProgress is the live fundraising state of a campaign
+type CampaignProgress struct {
+	Raised    int64 `json:"raised"` // completed donations credited to the campaign
+	Remaining int64 `json:"remaining"`
+	Percent   int64 `json:"percent"`
+	Donations int64 `json:"donations"`
+	// Unconverted sums completed donations in other currencies per currency
+	// until an admin enters their value in the campaign currency
+	Unconverted map[string]int64 `json:"unconverted,omitempty"`
+	DaysLeft    *int             `json:"days_left"` // nil without a deadline
+	Ended       bool             `json:"ended"`
+}
+
+// CampaignView is a campaign together with its progress
+type CampaignView struct {
+	models.Campaign
+	Progress CampaignProgress `json:"progress"`
+}
+
+// PublicDonation is how a donation is shown on the public site
+type PublicDonation struct {
+	DonorName  string    `json:"donor_name"` // empty for anonymous donations
+	Amount     int64     `json:"amount"`
+	Currency   string    `json:"currency"`
+	Message    string    `json:"message"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// normalizeCurrency upper-cases an ISO 4217 code, defaulting to fallback
+func normalizeCurrency(value, fallback string) (string, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if value == "" {
+		value = fallback
+	}
+	if !currencyCode.MatchString(value) {
+		return "", fmt.Errorf("invalid currency %q, expected an ISO 4217 code such as UAH", value)
+	}
+	return value, nil
+}
+
+// campaignProgress computes the progress of each campaign
+func campaignProgress(campaigns []models.Campaign) (map[uint]CampaignProgress, error) {
+	ids := make([]uint, len(campaigns))
+	for i, campaign := range campaigns {
+		ids[i] = campaign.ID
+	}
+
+	var totals []struct {
+		CampaignID uint
+		Raised     int64
+		Donations  int64
+	}
+	var unconverted []struct {
+		CampaignID uint
+		Currency   string
+		Amount     int64
+	}
+	if len(ids) > 0 {
+		if err := config.DB.Model(&models.Donation{}).
+			Select("campaign_id, COALESCE(SUM(campaign_amount), 0) AS raised, COUNT(*) AS donations").
+			Where("campaign_id IN ? AND status = ?", ids, models.DonationCompleted).
+			Group("campaign_id").Scan(&totals).Error; err != nil {
+			return nil, err
+		}
+		if err := config.DB.Model(&models.Donation{}).
+			Select("donations.campaign_id, donations.currency, SUM(donations.amount) AS amount").
+			Joins("JOIN campaigns ON campaigns.id = donations.campaign_id").
+			Where("donations.campaign_id IN ? AND donations.status = ? AND donations.campaign_amount = 0 AND donations.currency <> campaigns.currency",
+				ids, models.DonationCompleted).
+			Group("donations.campaign_id, donations.currency").Scan(&unconverted).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	progress := make(map[uint]CampaignProgress, len(campaigns))
+	for _, row := range totals {
+		progress[row.CampaignID] = CampaignProgress{Raised: row.Raised, Donations: row.Donations}
+	}
+	for _, row := range unconverted {
+		entry := progress[row.CampaignID]
+		if entry.Unconverted == nil {
+			entry.Unconverted = map[string]int64{}
+		}
+		entry.Unconverted[row.Currency] = row.Amount
+		progress[row.CampaignID] = entry
+	}
+
+	loc := config.ShelterLocation()
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	for _, campaign := range campaigns {
+		entry := progress[campaign.ID]
+		if entry.Raised < campaign.GoalAmount {
+			entry.Remaining = campaign.GoalAmount - entry.Raised
+		}
+		if campaign.GoalAmount > 0 {
+			entry.Percent = entry.Raised * 100 / campaign.GoalAmount
+		}
+		if campaign.Deadline != nil {
+			// Deadlines are calendar dates; the last day still counts
+			days := int(campaign.Deadline.Sub(today).Hours() / 24)
+			if days < 0 {
+				days = 0
+			}
+			entry.DaysLeft = &days
+			entry.Ended = campaign.Deadline.Before(today)
+		}
+		if campaign.Status == models.CampaignClosed {
+			entry.Ended = true
+		}
+		progress[campaign.ID] = entry
+	}
+	return progress, nil
+}
+
+// campaignViews pairs campaigns with their progress
+func campaignViews(campaigns []models.Campaign) ([]CampaignView, error) {
+	progress, err := campaignProgress(campaigns)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]CampaignView, len(campaigns))
+	for i, campaign := range campaigns {
+		views[i] = CampaignView{Campaign: campaign, Progress: progress[campaign.ID]}
+	}
+	return views, nil
+}
+
+// findCampaign loads the campaign named by the :id parameter
+func findCampaign(c *gin.Context) (*models.Campaign, bool) {
+	var campaign models.Campaign
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&campaign).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching campaign"})
+		return nil, false
+	}
+	return &campaign, true
+}
+
+// findPublicCampaign loads a campaign that is visible on the public site
+func findPublicCampaign(c *gin.Context) (*models.Campaign, bool) {
+	campaign, ok := findCampaign(c)
+	if ok && campaign.Status == models.CampaignDraft {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return nil, false
+	}
+	return campaign, ok
+}
+
+// validateCampaign checks the goal, currency and status
+func validateCampaign(campaign *models.Campaign) error {
+	if strings.TrimSpace(campaign.TitleEn) == "" && strings.TrimSpace(campaign.TitleUa) == "" {
+		return errors.New("title_en or title_ua is required")
+	}
+	if campaign.GoalAmount <= 0 {
+		return errors.New("goal_amount must be positive")
+	}
+	if !models.ValidCampaignStatus(campaign.Status) {
+		return errors.New("status must be draft, active or closed")
+	}
+	return nil
+}
+
+// GetCampaigns - List public campaigns with their progress. Active campaigns
+// are listed by default, ?status=closed lists finished ones.
+func GetCampaigns(c *gin.Context) {
+	status := c.DefaultQuery("status", models.CampaignActive)
+	if status != models.CampaignActive && status != models.CampaignClosed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be active or closed"})
+		return
+	}
+
+	var campaigns []models.Campaign
+	if err := config.DB.Where("status = ?", status).
+		Order("deadline IS NULL, deadline, created_at DESC").Find(&campaigns).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching campaigns"})
+		return
+	}
+	views, err := campaignViews(campaigns)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching donations"})
+		return
+	}
+	c.JSON(http.StatusOK, views)
+}
+
+// GetCampaignByID - Get a public campaign with its progress
+func GetCampaignByID(c *gin.Context) {
+	campaign, ok := findPublicCampaign(c)
+	if !ok {
+		return
+	}
+	views, err := campaignViews([]models.Campaign{*campaign})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching donations"})
+		return
+	}
+	c.JSON(http.StatusOK, views[0])
+}
+
+// GetCampaignDonations - List the completed donations of a public campaign,
+// newest first, hiding anonymous donors
+func GetCampaignDonations(c *gin.Context) {
+	campaign, ok := findPublicCampaign(c)
+	if !ok {
+		return
+	}
+
+	// Default values for pagination
+	limit := 20
+	page := 1
+
+	if l := c.Query("limit"); l != "" {
+		fmt.Sscanf(l, "%d", &limit)
+	}
+	if p := c.Query("page"); p != "" {
+		fmt.Sscanf(p, "%d", &page)
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	if page < 1 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	var donations []models.Donation
+	var total int64
+	query := config.DB.Model(&models.Donation{}).Where("campaign_id = ? AND status = ?", campaign.ID, models.DonationCompleted)
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching donations"})
+		return
+	}
+	if err := query.Order("received_at DESC").Limit(limit).Offset(offset).Find(&donations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching donations"})
+		return
+	}
+
+	public := make([]PublicDonation, len(donations))
+	for i, donation := range donations {
+		public[i] = PublicDonation{
+			Amount:     donation.Amount,
+			Currency:   donation.Currency,
+			Message:    donation.Message,
+			ReceivedAt: donation.ReceivedAt,
+		}
+		if !donation.Anonymous {
+			public[i].DonorName = donation.DonorName
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       public,
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+		"totalPages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// GetAdminCampaigns - List all campaigns with their progress; ?status= filters
+func GetAdminCampaigns(c *gin.Context) {
+	var campaigns []models.Campaign
+	query := config.DB.Order("created_at DESC")
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Find(&campaigns).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching campaigns"})
+		return
+	}
+	views, err := campaignViews(campaigns)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching donations"})
+		return
+	}
+	c.JSON(http.StatusOK, views)
+}
+
+// CreateCampaign - Start a fundraising campaign
+func CreateCampaign(c *gin.Context) {
+	var requestBody struct {
+		TitleEn       string `json:"title_en"`
+		TitleUa       string `json:"title_ua"`
+		DescriptionEn string `json:"description_en"`
+		DescriptionUa string `json:"description_ua"`
+		GoalAmount    int64  `json:"goal_amount" binding:"required"`
+		Currency      string `json:"currency"`
+		Deadline      string `json:"deadline"` // YYYY-MM-DD
+		Status        string `json:"status"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+
+	currency, err := normalizeCurrency(requestBody.Currency, sponsorshipCurrency())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	deadline, err := parseDate(requestBody.Deadline)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "deadline: " + err.Error()})
+		return
+	}
+	if requestBody.Status == "" {
+		requestBody.Status = models.CampaignActive
+	}
+
+	campaign := models.Campaign{
+		TitleEn:       strings.TrimSpace(requestBody.TitleEn),
+		TitleUa:       strings.TrimSpace(requestBody.TitleUa),
+		DescriptionEn: requestBody.DescriptionEn,
+		DescriptionUa: requestBody.DescriptionUa,
+		GoalAmount:    requestBody.GoalAmount,
+		Currency:      currency,
+		Deadline:      deadline,
+		Status:        requestBody.Status,
+	}
+	if err := validateCampaign(&campaign); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := config.DB.Create(&campaign).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create campaign"})
+		return
+	}
+	recordAudit(c, models.AuditCreate, "campaign", campaign.ID, nil, campaign)
+
+	c.JSON(http.StatusCreated, CampaignView{Campaign: campaign})
+}
+
+// UpdateCampaign - Change a campaign; omitted fields are left unchanged. The
+// currency is fixed once donations are credited to the campaign.
+func UpdateCampaign(c *gin.Context) {
+	campaign, ok := findCampaign(c)
+	if !ok {
+		return
+	}
+	before := *campaign
+
+	var requestBody struct {
+		TitleEn       *string `json:"title_en"`
+		TitleUa       *string `json:"title_ua"`
+		DescriptionEn *string `json:"description_en"`
+		DescriptionUa *string `json:"description_ua"`
+		GoalAmount    *int64  `json:"goal_amount"`
+		Currency      *string `json:"currency"`
+		Deadline      *string `json:"deadline"` // YYYY-MM-DD, "" removes it
+		Status        *string `json:"status"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+
+	if requestBody.TitleEn != nil {
+		campaign.TitleEn = strings.TrimSpace(*requestBody.TitleEn)
+	}
+	if requestBody.TitleUa != nil {
+		campaign.TitleUa = strings.TrimSpace(*requestBody.TitleUa)
+	}
+	if requestBody.DescriptionEn != nil {
+		campaign.DescriptionEn = *requestBody.DescriptionEn
+	}
+	if requestBody.DescriptionUa != nil {
+		campaign.DescriptionUa = *requestBody.DescriptionUa
+	}
+	if requestBody.GoalAmount != nil {
+		campaign.GoalAmount = *requestBody.GoalAmount
+	}
+	if requestBody.Currency != nil {
+		currency, err := normalizeCurrency(*requestBody.Currency, campaign.Currency)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if currency != campaign.Currency {
+			var credited int64
+			if err := config.DB.Model(&models.Donation{}).Where("campaign_id = ?", campaign.ID).Count(&credited).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking donations"})
+				return
+			}
+			if credited > 0 {
+				c.JSON(http.StatusConflict, gin.H{"error": "The currency of a campaign with donations cannot be changed"})
+				return
+			}
+		}
+		campaign.Currency = currency
+	}
+	if requestBody.Deadline != nil {
+		deadline, err := parseDate(*requestBody.Deadline)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "deadline: " + err.Error()})
+			return
+		}
+		campaign.Deadline = deadline
+	}
+	if requestBody.Status != nil {
+		campaign.Status = *requestBody.Status
+	}
+	if err := validateCampaign(campaign); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := config.DB.Save(campaign).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update campaign"})
+		return
+	}
+	recordAudit(c, models.AuditUpdate, "campaign", campaign.ID, before, *campaign)
+
+	views, err := campaignViews([]models.Campaign{*campaign})
+	if err != nil {
+		c.JSON(http.StatusOK, CampaignView{Campaign: *campaign})
+		return
+	}
+	c.JSON(http.StatusOK, views[0])
+}
+
+// DeleteCampaign - Delete a campaign without donations; campaigns that
+// received money should be closed instead so their totals stay reportable
+func DeleteCampaign(c *gin.Context) {
+	campaign, ok := findCampaign(c)
+	if !ok {
+		return
+	}
+
+	var donations int64
+	if err := config.DB.Model(&models.Donation{}).Where("campaign_id = ?", campaign.ID).Count(&donations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking donations"})
+		return
+	}
+	if donations > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Campaign has donations; close it instead"})
+		return
+	}
+
+	if err := config.DB.Delete(campaign).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete campaign"})
+		return
+	}
+	recordAudit(c, models.AuditDelete, "campaign", campaign.ID, *campaign, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Campaign deleted successfully"})
+}
@@ -58,6 +58,7 @@ func CreateContact(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating contact"})
 		return
 	}
+	recordAudit(c, models.AuditCreate, "contact", contact.ID, nil, contact)
 
 	c.JSON(http.StatusCreated, contact)
 }
@@ -72,6 +73,7 @@ func UpdateContact(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
 		return
 	}
+	before := contact
 
 	// Use a map to handle partial updates
 	var updateData map[string]interface{}
@@ -108,6 +110,7 @@ func UpdateContact(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating contact"})
 		return
 	}
+	recordAudit(c, models.AuditUpdate, "contact", contact.ID, before, contact)
 
 	c.JSON(http.StatusOK, &contact)
 }
@@ -124,6 +127,10 @@ func DeleteContact(c *gin.Context) {
 	}
 
 	// Delete the contact
-	config.DB.Delete(&contact)
+	if err := config.DB.Delete(&contact).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting contact"})
+		return
+	}
+	recordAudit(c, models.AuditDelete, "contact", contact.ID, contact, nil)
 	c.JSON(http.StatusOK, gin.H{"message": "Contact deleted successfully"})
 }
@@ -0,0 +1,506 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kholodihor/cows-shelter-backend/config"
+	"github.com/kholodihor/cows-shelter-backend/models"
+	"gorm.io/gorm"
+)
+
+// DonationTotal is one row of a donation report
+type DonationTotal struct {
+	Currency string `json:"currency"`
+	Total    int64  `json:"total"`
+	Count    int64  `json:"count"`
+}
+
+// findDonation loads the donation named by the :id parameter
+func findDonation(c *gin.Context) (*models.Donation, bool) {
+	var donation models.Donation
+	if err := config.DB.Preload("Campaign").Where("id = ?", c.Param("id")).First(&donation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Donation not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching donation"})
+		return nil, false
+	}
+	return &donation, true
+}
+
+// loadDonationCampaign returns the campaign with the given ID, or nil for 0
+func loadDonationCampaign(id uint) (*models.Campaign, error) {
+	if id == 0 {
+		return nil, nil
+	}
+	var campaign models.Campaign
+	if err := config.DB.Where("id = ?", id).First(&campaign).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("campaign not found")
+		}
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+// applyDonationCampaign assigns a donation to a campaign (nil for none) and
+// works out the amount credited to it. Donations in the campaign currency are
+// credited in full; others need campaignAmount, the converted value, or stay
+// uncredited until it is entered.
+func applyDonationCampaign(donation *models.Donation, campaign *models.Campaign, campaignAmount *int64) error {
+	donation.Campaign = campaign
+	if campaign == nil {
+		donation.CampaignID = nil
+		donation.CampaignAmount = 0
+		return nil
+	}
+	donation.CampaignID = &campaign.ID
+	if donation.Currency == campaign.Currency {
+		donation.CampaignAmount = donation.Amount
+		return nil
+	}
+	if campaignAmount != nil {
+		if *campaignAmount < 0 {
+			return errors.New("campaign_amount cannot be negative")
+		}
+		donation.CampaignAmount = *campaignAmount
+	}
+	return nil
+}
+
+// validateDonation checks amount, source and status
+func validateDonation(donation *models.Donation) error {
+	if donation.Amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+	if !models.ValidDonationSource(donation.Source) {
+		return errors.New("source must be privatbank, monobank, paypal, western_union, swift, cash or other")
+	}
+	if !models.ValidDonationStatus(donation.Status) {
+		return errors.New("status must be pending, completed, failed or refunded")
+	}
+	return nil
+}
+
+// externalIDTaken reports whether another donation from the same source has
+// the payment reference, so provider payments are only recorded once
+func externalIDTaken(donation *models.Donation) (bool, error) {
+	if donation.ExternalID == nil {
+		return false, nil
+	}
+	var count int64
+	err := config.DB.Model(&models.Donation{}).
+		Where("source = ? AND external_id = ? AND id <> ?", donation.Source, *donation.ExternalID, donation.ID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// optionalString trims a string and turns an empty one into nil
+func optionalString(value *string) *string {
+	if value == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*value)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
+
+// reportRange reads ?from= and ?to= (YYYY-MM-DD, inclusive, in the shelter
+// time zone), defaulting to the current year so far
+func reportRange(c *gin.Context) (from, to time.Time, err error) {
+	loc := config.ShelterLocation()
+	now := time.Now().In(loc)
+	from = time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, loc)
+	to = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc).AddDate(0, 0, 1)
+
+	if value := c.Query("from"); value != "" {
+		if from, err = time.ParseInLocation("2006-01-02", value, loc); err != nil {
+			return from, to, fmt.Errorf("invalid from date %q, expected YYYY-MM-DD", value)
+		}
+	}
+	if value := c.Query("to"); value != "" {
+		if to, err = time.ParseInLocation("2006-01-02", value, loc); err != nil {
+			return from, to, fmt.Errorf("invalid to date %q, expected YYYY-MM-DD", value)
+		}
+		to = to.AddDate(0, 0, 1)
+	}
+	if !to.After(from) {
+		return from, to, errors.New("to must not be before from")
+	}
+	return from, to, nil
+}
+
+// GetDonations - List donation records with pagination. Filters: ?campaign_id=
+// ("none" for unassigned), ?source=, ?status=, ?currency=, ?email=, ?from=, ?to=
+func GetDonations(c *gin.Context) {
+	var donations []models.Donation
+	var total int64
+
+	// Default values for pagination
+	limit := 20
+	page := 1
+
+	if l := c.Query("limit"); l != "" {
+		fmt.Sscanf(l, "%d", &limit)
+	}
+	if p := c.Query("page"); p != "" {
+		fmt.Sscanf(p, "%d", &page)
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	if page < 1 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	query := config.DB.Model(&models.Donation{})
+	if campaignID := c.Query("campaign_id"); campaignID == "none" {
+		query = query.Where("campaign_id IS NULL")
+	} else if campaignID != "" {
+		query = query.Where("campaign_id = ?", campaignID)
+	}
+	if source := c.Query("source"); source != "" {
+		query = query.Where("source = ?", source)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if currency := c.Query("currency"); currency != "" {
+		query = query.Where("currency = ?", strings.ToUpper(currency))
+	}
+	if email := c.Query("email"); email != "" {
+		query = query.Where("donor_email = ?", normalizeEmail(email))
+	}
+	if c.Query("from") != "" || c.Query("to") != "" {
+		from, to, err := reportRange(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		query = query.Where("received_at >= ? AND received_at < ?", from, to)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching donations"})
+		return
+	}
+	if err := query.Preload("Campaign").Order("received_at DESC").Limit(limit).Offset(offset).Find(&donations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching donations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       donations,
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+		"totalPages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// CreateDonation - Record a donation received through any source, such as a
+// bank transfer seen on the statement
+func CreateDonation(c *gin.Context) {
+	var requestBody struct {
+		CampaignID     uint       `json:"campaign_id"`
+		Source         string     `json:"source" binding:"required"`
+		ExternalID     *string    `json:"external_id"`
+		Amount         int64      `json:"amount" binding:"required"`
+		Currency       string     `json:"currency"`
+		CampaignAmount *int64     `json:"campaign_amount"`
+		Status         string     `json:"status"`
+		DonorName      string     `json:"donor_name"`
+		DonorEmail     string     `json:"donor_email" binding:"omitempty,email"`
+		Anonymous      bool       `json:"anonymous"`
+		Message        string     `json:"message"`
+		Note           string     `json:"note"`
+		ReceivedAt     *time.Time `json:"received_at"` // RFC 3339, defaults to now
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+
+	campaign, err := loadDonationCampaign(requestBody.CampaignID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	fallback := sponsorshipCurrency()
+	if campaign != nil {
+		fallback = campaign.Currency
+	}
+	currency, err := normalizeCurrency(requestBody.Currency, fallback)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if requestBody.Status == "" {
+		requestBody.Status = models.DonationCompleted
+	}
+	receivedAt := time.Now()
+	if requestBody.ReceivedAt != nil {
+		receivedAt = *requestBody.ReceivedAt
+	}
+
+	donation := models.Donation{
+		Source:     requestBody.Source,
+		ExternalID: optionalString(requestBody.ExternalID),
+		Amount:     requestBody.Amount,
+		Currency:   currency,
+		Status:     requestBody.Status,
+		DonorName:  strings.TrimSpace(requestBody.DonorName),
+		DonorEmail: normalizeEmail(requestBody.DonorEmail),
+		Anonymous:  requestBody.Anonymous,
+		Message:    requestBody.Message,
+		Note:       requestBody.Note,
+		ReceivedAt: receivedAt,
+	}
+	if user, ok := c.Get("user"); ok {
+		if actor, ok := user.(models.User); ok {
+			donation.CreatedByID = &actor.ID
+		}
+	}
+	if err := validateDonation(&donation); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := applyDonationCampaign(&donation, campaign, requestBody.CampaignAmount); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	taken, err := externalIDTaken(&donation)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking donations"})
+		return
+	}
+	if taken {
+		c.JSON(http.StatusConflict, gin.H{"error": "A donation with this external_id is already recorded for " + donation.Source})
+		return
+	}
+
+	if err := config.DB.Omit("Campaign").Create(&donation).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record donation"})
+		return
+	}
+	recordAudit(c, models.AuditCreate, "donation", donation.ID, nil, donation)
+
+	c.JSON(http.StatusCreated, donation)
+}
+
+// UpdateDonation - Change a donation record; omitted fields are left
+// unchanged and campaign_id 0 unassigns it from its campaign
+func UpdateDonation(c *gin.Context) {
+	donation, ok := findDonation(c)
+	if !ok {
+		return
+	}
+	before := *donation
+
+	var requestBody struct {
+		CampaignID     *uint      `json:"campaign_id"`
+		Source         *string    `json:"source"`
+		ExternalID     *string    `json:"external_id"`
+		Amount         *int64     `json:"amount"`
+		Currency       *string    `json:"currency"`
+		CampaignAmount *int64     `json:"campaign_amount"`
+		Status         *string    `json:"status"`
+		DonorName      *string    `json:"donor_name"`
+		DonorEmail     *string    `json:"donor_email" binding:"omitempty,email"`
+		Anonymous      *bool      `json:"anonymous"`
+		Message        *string    `json:"message"`
+		Note           *string    `json:"note"`
+		ReceivedAt     *time.Time `json:"received_at"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+
+	campaign := donation.Campaign
+	if requestBody.CampaignID != nil {
+		loaded, err := loadDonationCampaign(*requestBody.CampaignID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		campaign = loaded
+	}
+	if requestBody.Source != nil {
+		donation.Source = *requestBody.Source
+	}
+	if requestBody.ExternalID != nil {
+		donation.ExternalID = optionalString(requestBody.ExternalID)
+	}
+	if requestBody.Amount != nil {
+		donation.Amount = *requestBody.Amount
+	}
+	if requestBody.Currency != nil {
+		currency, err := normalizeCurrency(*requestBody.Currency, donation.Currency)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		donation.Currency = currency
+	}
+	if requestBody.Status != nil {
+		donation.Status = *requestBody.Status
+	}
+	if requestBody.DonorName != nil {
+		donation.DonorName = strings.TrimSpace(*requestBody.DonorName)
+	}
+	if requestBody.DonorEmail != nil {
+		donation.DonorEmail = normalizeEmail(*requestBody.DonorEmail)
+	}
+	if requestBody.Anonymous != nil {
+		donation.Anonymous = *requestBody.Anonymous
+	}
+	if requestBody.Message != nil {
+		donation.Message = *requestBody.Message
+	}
+	if requestBody.Note != nil {
+		donation.Note = *requestBody.Note
+	}
+	if requestBody.ReceivedAt != nil {
+		donation.ReceivedAt = *requestBody.ReceivedAt
+	}
+	if err := validateDonation(donation); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	sameCampaign := campaign != nil && before.CampaignID != nil && *before.CampaignID == campaign.ID
+	if !sameCampaign || donation.Currency != before.Currency || donation.Amount != before.Amount {
+		// A different campaign or currency invalidates the converted amount
+		donation.CampaignAmount = 0
+	}
+	if err := applyDonationCampaign(donation, campaign, requestBody.CampaignAmount); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	taken, err := externalIDTaken(donation)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking donations"})
+		return
+	}
+	if taken {
+		c.JSON(http.StatusConflict, gin.H{"error": "A donation with this external_id is already recorded for " + donation.Source})
+		return
+	}
+
+	if err := config.DB.Omit("Campaign").Save(donation).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update donation"})
+		return
+	}
+	recordAudit(c, models.AuditUpdate, "donation", donation.ID, before, *donation)
+
+	c.JSON(http.StatusOK, donation)
+}
+
+// DeleteDonation - Delete a donation recorded by mistake. Refunds should set
+// the status to refunded instead so they stay in the reports. Receipts of
+// the donation must be voided first.
+func DeleteDonation(c *gin.Context) {
+	donation, ok := findDonation(c)
+	if !ok {
+		return
+	}
+	var receipts int64
+	if err := config.DB.Model(&models.Receipt{}).Where("donation_id = ? AND status = ?", donation.ID, models.ReceiptIssued).Count(&receipts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking receipts"})
+		return
+	}
+	if receipts > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "The donation has a receipt; void it before deleting the donation"})
+		return
+	}
+	if err := config.DB.Delete(donation).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete donation"})
+		return
+	}
+	recordAudit(c, models.AuditDelete, "donation", donation.ID, *donation, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Donation deleted successfully"})
+}
+
+// GetDonationReport - Summarize donations received between ?from= and ?to=
+// (YYYY-MM-DD, current year by default), optionally for one ?campaign_id=.
+// Totals are per currency, since amounts in different currencies cannot be added.
+func GetDonationReport(c *gin.Context) {
+	from, to, err := reportRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	scope := func(status string) *gorm.DB {
+		query := config.DB.Model(&models.Donation{}).
+			Where("donations.status = ? AND donations.received_at >= ? AND donations.received_at < ?", status, from, to)
+		if campaignID := c.Query("campaign_id"); campaignID != "" {
+			query = query.Where("donations.campaign_id = ?", campaignID)
+		}
+		return query
+	}
+
+	var totals, refunded []DonationTotal
+	var bySource []struct {
+		Source string `json:"source"`
+		DonationTotal
+	}
+	var byMonth []struct {
+		Month string `json:"month"` // YYYY-MM in the shelter time zone
+		DonationTotal
+	}
+	var byCampaign []struct {
+		CampaignID uint   `json:"campaign_id"`
+		TitleEn    string `json:"title_en"`
+		TitleUa    string `json:"title_ua"`
+		Currency   string `json:"currency"`
+		Total      int64  `json:"total"` // credited amount in the campaign currency
+		Count      int64  `json:"count"`
+	}
+
+	queries := []*gorm.DB{
+		scope(models.DonationCompleted).Select("currency, SUM(amount) AS total, COUNT(*) AS count").
+			Group("currency").Order("currency").Scan(&totals),
+		scope(models.DonationRefunded).Select("currency, SUM(amount) AS total, COUNT(*) AS count").
+			Group("currency").Order("currency").Scan(&refunded),
+		scope(models.DonationCompleted).Select("source, currency, SUM(amount) AS total, COUNT(*) AS count").
+			Group("source, currency").Order("source, currency").Scan(&bySource),
+		scope(models.DonationCompleted).Select("to_char(received_at AT TIME ZONE ?, 'YYYY-MM') AS month, currency, SUM(amount) AS total, COUNT(*) AS count",
+			config.ShelterLocation().String()).
+			Group("month, currency").Order("month, currency").Scan(&byMonth),
+		scope(models.DonationCompleted).
+			Select("donations.campaign_id, campaigns.title_en, campaigns.title_ua, campaigns.currency, " +
+				"SUM(donations.campaign_amount) AS total, COUNT(*) AS count").
+			Joins("JOIN campaigns ON campaigns.id = donations.campaign_id").
+			Group("donations.campaign_id, campaigns.title_en, campaigns.title_ua, campaigns.currency").
+			Order("donations.campaign_id").Scan(&byCampaign),
+	}
+	for _, query := range queries {
+		if query.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error building donation report"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":        from.Format("2006-01-02"),
+		"to":          to.AddDate(0, 0, -1).Format("2006-01-02"),
+		"totals":      totals,
+		"refunded":    refunded,
+		"by_source":   bySource,
+		"by_month":    byMonth,
+		"by_campaign": byCampaign,
+	})
+}
@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kholodihor/cows-shelter-backend/config"
+	"github.com/kholodihor/cows-shelter-backend/middleware"
+	"github.com/kholodihor/cows-shelter-backend/models"
+	"github.com/kholodihor/cows-shelter-backend/payments"
+	"github.com/kholodihor/cows-shelter-backend/utils"
+)
+
+// Payment QR standards
+const (
+	qrStandardNBU = "nbu" // Ukrainian hryvnia transfers
+	qrStandardEPC = "epc" // SEPA euro transfers
+)
+
+// qrCachedSizes are the image sizes of donation QR codes kept in storage
+var qrCachedSizes = map[int]bool{256: true, 512: true, 1024: true}
+
+// donationQRCacheable reports whether a QR code is one of the standard
+// variants kept in storage: the default purpose, with or without a campaign,
+// no amount or one of DONATION_QR_AMOUNTS and one of qrCachedSizes. The
+// endpoint is public, so other requests are rendered on the fly instead of
+// filling the bucket with one object per amount.
+func donationQRCacheable(size int, amount int64, customPurpose bool) bool {
+	if customPurpose || !qrCachedSizes[size] {
+		return false
+	}
+	if amount == 0 {
+		return true
+	}
+	for _, preset := range strings.Split(config.GetEnv("DONATION_QR_AMOUNTS", "100,250,500,1000"), ",") {
+		if minor, err := payments.ParseAmount(preset, "UAH"); err == nil && minor == amount {
+			return true
+		}
+	}
+	return false
+}
+
+// donationTransfer returns the shelter's bank details for a QR standard
+func donationTransfer(standard string) payments.BankTransfer {
+	name := config.GetEnv("DONATION_RECIPIENT_NAME", "")
+	if standard == qrStandardEPC {
+		return payments.BankTransfer{
+			Name:    config.GetEnv("DONATION_EUR_RECIPIENT_NAME", name),
+			IBAN:    config.GetEnv("DONATION_EUR_IBAN", ""),
+			BIC:     config.GetEnv("DONATION_EUR_BIC", ""),
+			Purpose: config.GetEnv("DONATION_EUR_PURPOSE", "Donation"),
+		}
+	}
+	return payments.BankTransfer{
+		Name:          name,
+		IBAN:          config.GetEnv("DONATION_IBAN", ""),
+		RecipientCode: config.GetEnv("DONATION_RECIPIENT_CODE", ""),
+		Purpose:       config.GetEnv("DONATION_PURPOSE", "Благодійний внесок"),
+	}
+}
+
+// GetDonationQR - Generate a bank transfer QR code for donations.
+// ?standard=nbu (hryvnia, default) or epc (SEPA euro), ?format=png or svg,
+// ?size= in pixels (128-1024), ?amount= as a decimal, ?purpose= to replace
+// the default purpose and ?campaign_id= to name a campaign in it. Standard
+// variants are cached in storage under the hash of their content and the
+// response redirects to the cached image; others are served directly.
+// ?output=json returns the URL of the cached image, if any, and the payload.
+func GetDonationQR(c *gin.Context) {
+	standard := c.DefaultQuery("standard", qrStandardNBU)
+	if standard != qrStandardNBU && standard != qrStandardEPC {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "standard must be nbu or epc"})
+		return
+	}
+	format := c.DefaultQuery("format", utils.QRFormatPNG)
+	if format != utils.QRFormatPNG && format != utils.QRFormatSVG {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be png or svg"})
+		return
+	}
+	size := 512
+	if s := c.Query("size"); s != "" {
+		fmt.Sscanf(s, "%d", &size)
+	}
+	if size < 128 || size > 1024 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "size must be between 128 and 1024"})
+		return
+	}
+
+	transfer := donationTransfer(standard)
+	if transfer.IBAN == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Bank details for " + standard + " transfers are not configured"})
+		return
+	}
+	if amount := c.Query("amount"); amount != "" {
+		minor, err := payments.ParseAmount(amount, "UAH")
+		if err != nil || minor == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "amount must be a positive decimal with at most two decimal places"})
+			return
+		}
+		transfer.Amount = minor
+	}
+	customPurpose := strings.TrimSpace(c.Query("purpose")) != ""
+	if customPurpose {
+		transfer.Purpose = strings.TrimSpace(c.Query("purpose"))
+	}
+	if campaignID := c.Query("campaign_id"); campaignID != "" {
+		var campaign models.Campaign
+		if err := config.DB.Where("id = ? AND status = ?", campaignID, models.CampaignActive).First(&campaign).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+			return
+		}
+		lang := "ua"
+		if standard == qrStandardEPC {
+			lang = "en"
+		}
+		transfer.Purpose += ": " + localized(lang, campaign.TitleEn, campaign.TitleUa)
+		if purpose := []rune(transfer.Purpose); len(purpose) > 140 {
+			// Long campaign titles are cut to the limit of both formats
+			transfer.Purpose = strings.TrimSpace(string(purpose[:140]))
+		}
+	}
+
+	var payload string
+	var err error
+	if standard == qrStandardEPC {
+		payload, err = payments.EPCPayload(transfer)
+	} else {
+		payload, err = payments.NBUPayload(transfer)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s\n%s\n%d\n%s", standard, format, size, payload)))
+	objectKey := fmt.Sprintf("qr/%s.%s", hex.EncodeToString(hash[:]), format)
+	contentType := utils.QRContentType(format)
+
+	url := ""
+	store := middleware.GetStorage(c.Request.Context())
+	if !donationQRCacheable(size, transfer.Amount, customPurpose) {
+		store = nil
+	}
+	if store != nil {
+		exists, err := store.ObjectExists(c.Request.Context(), objectKey)
+		if err != nil {
+			log.Printf("Failed to check cached QR code %s: %v", objectKey, err)
+		} else if exists {
+			url = store.GetObjectURL(objectKey)
+		}
+	}
+
+	var image []byte
+	if url == "" {
+		if image, err = utils.RenderQRCode(payload, format, size); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate QR code"})
+			return
+		}
+		if store != nil {
+			if url, err = store.UploadBytes(c.Request.Context(), objectKey, image, contentType); err != nil {
+				log.Printf("Failed to cache QR code %s: %v", objectKey, err)
+				url = ""
+			}
+		}
+	}
+
+	if c.Query("output") == "json" {
+		c.JSON(http.StatusOK, gin.H{
+			"url":          url,
+			"payload":      payload,
+			"content_type": contentType,
+			"recipient":    transfer.Name,
+			"iban":         transfer.IBAN,
+			"amount":       transfer.Amount,
+			"purpose":      transfer.Purpose,
+		})
+		return
+	}
+
+	// The object key changes with the content, so the image never goes stale
+	c.Header("Cache-Control", "public, max-age=86400")
+	if url != "" {
+		c.Redirect(http.StatusFound, url)
+		return
+	}
+	// Without storage, or for variants that are not cached, the image is served directly
+	c.Data(http.StatusOK, contentType, image)
+}
@@ -3,6 +3,7 @@ package controllers
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kholodihor/cows-shelter-backend/config"
@@ -12,26 +13,55 @@ import (
 
 // CreateExcursionRequest represents the JSON request body for creating an excursion
 type CreateExcursionRequest struct {
-	TitleEn         string `json:"title_en" binding:"required"`
-	TitleUa         string `json:"title_ua"`
-	DescriptionEn   string `json:"description_en"`
-	DescriptionUa   string `json:"description_ua"`
-	TimeTo          string `json:"time_to" binding:"required"`
-	TimeFrom        string `json:"time_from" binding:"required"`
-	AmountOfPersons string `json:"amount_of_persons" binding:"required"`
-	ImageData       string `json:"image_data" binding:"required"` // base64-encoded image data
+	TitleEn         string     `json:"title_en" binding:"required"`
+	TitleUa         string     `json:"title_ua"`
+	DescriptionEn   string     `json:"description_en"`
+	DescriptionUa   string     `json:"description_ua"`
+	TimeTo          string     `json:"time_to"`
+	TimeFrom        string     `json:"time_from"`
+	StartsAt        *time.Time `json:"starts_at"` // RFC 3339, for one-off excursions
+	EndsAt          *time.Time `json:"ends_at"`
+	AmountOfPersons string     `json:"amount_of_persons" binding:"required"`
+	ImageData       string     `json:"image_data" binding:"required"` // base64-encoded image data
 }
 
 // UpdateExcursionRequest represents the JSON request body for updating an excursion
 type UpdateExcursionRequest struct {
-	TitleEn         string `json:"title_en"`
-	TitleUa         string `json:"title_ua"`
-	DescriptionEn   string `json:"description_en"`
-	DescriptionUa   string `json:"description_ua"`
-	TimeTo          string `json:"time_to"`
-	TimeFrom        string `json:"time_from"`
-	AmountOfPersons string `json:"amount_of_persons"`
-	ImageData       string `json:"image_data"` // base64-encoded image data (optional)
+	TitleEn         string     `json:"title_en"`
+	TitleUa         string     `json:"title_ua"`
+	DescriptionEn   string     `json:"description_en"`
+	DescriptionUa   string     `json:"description_ua"`
+	TimeTo          string     `json:"time_to"`
+	TimeFrom        string     `json:"time_from"`
+	StartsAt        *time.Time `json:"starts_at"`
+	EndsAt          *time.Time `json:"ends_at"`
+	AmountOfPersons string     `json:"amount_of_persons"`
+	ImageData       string     `json:"image_data"` // base64-encoded image data (optional)
+}
+
+// applyExcursionTimes sets the start and end datetimes of a one-off excursion.
+// Both must be given together; the free-form TimeFrom/TimeTo strings shown by
+// the frontend are filled from them when left empty.
+func applyExcursionTimes(excursion *models.Excursion, startsAt, endsAt *time.Time) error {
+	if startsAt == nil && endsAt == nil {
+		return nil
+	}
+	if startsAt == nil || endsAt == nil {
+		return fmt.Errorf("starts_at and ends_at must be set together")
+	}
+	if !endsAt.After(*startsAt) {
+		return fmt.Errorf("ends_at must be after starts_at")
+	}
+	start, end := startsAt.UTC(), endsAt.UTC()
+	excursion.StartsAt = &start
+	excursion.EndsAt = &end
+	if excursion.TimeFrom == "" {
+		excursion.TimeFrom = start.In(config.ShelterLocation()).Format("15:04")
+	}
+	if excursion.TimeTo == "" {
+		excursion.TimeTo = end.In(config.ShelterLocation()).Format("15:04")
+	}
+	return nil
 }
 
 func GetAllExcursions(c *gin.Context) {
@@ -119,6 +149,14 @@ func CreateExcursion(c *gin.Context) {
 		TimeFrom:        req.TimeFrom,
 		AmountOfPersons: req.AmountOfPersons,
 	}
+	if err := applyExcursionTimes(&excursion, req.StartsAt, req.EndsAt); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if excursion.TimeFrom == "" || excursion.TimeTo == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "time_from and time_to, or starts_at and ends_at, are required"})
+		return
+	}
 
 	// Handle image upload
 	if req.ImageData == "" {
@@ -148,6 +186,7 @@ func CreateExcursion(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create excursion: " + err.Error()})
 		return
 	}
+	recordAudit(c, models.AuditCreate, "excursion", excursion.ID, nil, excursion)
 
 	c.JSON(http.StatusCreated, excursion)
 }
@@ -172,6 +211,7 @@ func UpdateExcursion(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Excursion not found"})
 		return
 	}
+	before := excursion
 
 	var req UpdateExcursionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -201,6 +241,27 @@ func UpdateExcursion(c *gin.Context) {
 	if req.AmountOfPersons != "" {
 		excursion.AmountOfPersons = req.AmountOfPersons
 	}
+	startsAt, endsAt := req.StartsAt, req.EndsAt
+	if startsAt != nil && endsAt == nil && excursion.StartsAt != nil {
+		// Moving a one-off excursion keeps its duration
+		end := startsAt.Add(excursion.EndsAt.Sub(*excursion.StartsAt))
+		endsAt = &end
+	} else if startsAt == nil && endsAt != nil && excursion.StartsAt != nil {
+		startsAt = excursion.StartsAt
+	}
+	if startsAt != nil || endsAt != nil {
+		// The displayed times follow the new datetimes unless they were sent too
+		if req.TimeFrom == "" {
+			excursion.TimeFrom = ""
+		}
+		if req.TimeTo == "" {
+			excursion.TimeTo = ""
+		}
+	}
+	if err := applyExcursionTimes(&excursion, startsAt, endsAt); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Handle image upload if new image data is provided
 	if req.ImageData != "" {
@@ -235,6 +296,7 @@ func UpdateExcursion(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update excursion: " + err.Error()})
 		return
 	}
+	recordAudit(c, models.AuditUpdate, "excursion", excursion.ID, before, excursion)
 
 	c.JSON(http.StatusOK, excursion)
 }
@@ -257,6 +319,19 @@ func DeleteExcursion(c *gin.Context) {
 		return
 	}
 
+	// Visitors holding seats must be told first, so booked sessions block deletion
+	var booked int64
+	if err := config.DB.Model(&models.ExcursionSession{}).
+		Where("excursion_id = ? AND status = ? AND booked_seats > 0 AND ends_at > ?", excursion.ID, models.SessionScheduled, time.Now()).
+		Count(&booked).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking sessions"})
+		return
+	}
+	if booked > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Excursion has booked upcoming sessions; cancel them first"})
+		return
+	}
+
 	// Get storage service from context
 	store := middleware.GetStorage(c.Request.Context())
 	if store == nil {
@@ -278,6 +353,7 @@ func DeleteExcursion(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete excursion"})
 		return
 	}
+	recordAudit(c, models.AuditDelete, "excursion", excursion.ID, excursion, nil)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Excursion deleted successfully"})
 }
\ No newline at end of file
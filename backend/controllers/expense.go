@@ -0,0 +1,388 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kholodihor/cows-shelter-backend/config"
+	"github.com/kholodihor/cows-shelter-backend/middleware"
+	"github.com/kholodihor/cows-shelter-backend/models"
+	"gorm.io/gorm"
+)
+
+// expenseScanFolder is the storage folder of expense receipt scans, under
+// the private prefix
+const expenseScanFolder = "expenses"
+
+// receiptScanTypes are the content types accepted for receipt scans
+var receiptScanTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
+
+// validateReceiptScan checks that a base64 data URL holds an image or a PDF,
+// both by its declared type and by its content
+func validateReceiptScan(dataURL string) error {
+	header, data, ok := strings.Cut(dataURL, ",")
+	contentType, isBase64 := strings.CutSuffix(strings.TrimPrefix(header, "data:"), ";base64")
+	if !ok || !strings.HasPrefix(header, "data:") || !isBase64 {
+		return errors.New("receipt_data must be a base64 data URL")
+	}
+	if !receiptScanTypes[contentType] {
+		return errors.New("receipt scans must be JPEG, PNG or WebP images or PDF documents")
+	}
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return errors.New("receipt_data is not valid base64")
+	}
+	if sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(decoded)); sniffed != contentType {
+		return fmt.Errorf("receipt_data is declared as %s but its content is not", contentType)
+	}
+	return nil
+}
+
+// findExpense loads the expense named by the :id parameter
+func findExpense(c *gin.Context) (*models.Expense, bool) {
+	var expense models.Expense
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&expense).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Expense not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching expense"})
+		return nil, false
+	}
+	return &expense, true
+}
+
+// validateExpense checks category and amount
+func validateExpense(expense *models.Expense) error {
+	if !models.ValidExpenseCategory(expense.Category) {
+		return fmt.Errorf("category must be one of %s", strings.Join(models.ExpenseCategories, ", "))
+	}
+	if expense.Amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+	return nil
+}
+
+// GetExpenses - List ledger entries with pagination, newest first. Filters:
+// ?category=, ?currency=, ?from=, ?to= (YYYY-MM-DD, inclusive)
+func GetExpenses(c *gin.Context) {
+	var expenses []models.Expense
+	var total int64
+
+	// Default values for pagination
+	limit := 20
+	page := 1
+
+	if l := c.Query("limit"); l != "" {
+		fmt.Sscanf(l, "%d", &limit)
+	}
+	if p := c.Query("page"); p != "" {
+		fmt.Sscanf(p, "%d", &page)
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	if page < 1 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	query := config.DB.Model(&models.Expense{})
+	if category := c.Query("category"); category != "" {
+		query = query.Where("category = ?", category)
+	}
+	if currency := c.Query("currency"); currency != "" {
+		query = query.Where("currency = ?", strings.ToUpper(currency))
+	}
+	from, err := parseDate(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if from != nil {
+		query = query.Where("spent_on >= ?", from.Format("2006-01-02"))
+	}
+	to, err := parseDate(c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if to != nil {
+		query = query.Where("spent_on <= ?", to.Format("2006-01-02"))
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching expenses"})
+		return
+	}
+	if err := query.Order("spent_on DESC, id DESC").Limit(limit).Offset(offset).Find(&expenses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching expenses"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       expenses,
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+		"totalPages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// GetExpenseByID - Retrieve a ledger entry
+func GetExpenseByID(c *gin.Context) {
+	expense, ok := findExpense(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, expense)
+}
+
+// CreateExpense - Add an expense to the ledger, optionally with a scan of its
+// receipt as a base64 data URL (image or PDF)
+func CreateExpense(c *gin.Context) {
+	var requestBody struct {
+		Category    string `json:"category" binding:"required"`
+		Description string `json:"description"`
+		Vendor      string `json:"vendor"`
+		Amount      int64  `json:"amount" binding:"required"` // in minor currency units
+		Currency    string `json:"currency"`
+		SpentOn     string `json:"spent_on" binding:"required"` // YYYY-MM-DD
+		ReceiptData string `json:"receipt_data"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+
+	spentOn, err := parseDate(requestBody.SpentOn)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	currency, err := normalizeCurrency(requestBody.Currency, sponsorshipCurrency())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	expense := models.Expense{
+		Category:    requestBody.Category,
+		Description: strings.TrimSpace(requestBody.Description),
+		Vendor:      strings.TrimSpace(requestBody.Vendor),
+		Amount:      requestBody.Amount,
+		Currency:    currency,
+		SpentOn:     *spentOn,
+	}
+	if user, ok := c.Get("user"); ok {
+		if actor, ok := user.(models.User); ok {
+			expense.CreatedByID = &actor.ID
+		}
+	}
+	if err := validateExpense(&expense); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	store := middleware.GetStorage(c.Request.Context())
+	if requestBody.ReceiptData != "" {
+		if err := validateReceiptScan(requestBody.ReceiptData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if store == nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Storage service not available"})
+			return
+		}
+		// Receipts name staff and suppliers, so they never get a public URL
+		scanKey, err := store.UploadPrivateBase64(c.Request.Context(), requestBody.ReceiptData, expenseScanFolder)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload receipt scan: " + err.Error()})
+			return
+		}
+		expense.ReceiptScanKey = scanKey
+	}
+
+	if err := config.DB.Create(&expense).Error; err != nil {
+		if expense.ReceiptScanKey != "" {
+			_ = store.DeleteFile(c.Request.Context(), expense.ReceiptScanKey)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record expense"})
+		return
+	}
+	recordAudit(c, models.AuditCreate, "expense", expense.ID, nil, expense)
+
+	c.JSON(http.StatusCreated, expense)
+}
+
+// UpdateExpense - Change a ledger entry; omitted fields are left unchanged.
+// receipt_data replaces the receipt scan and remove_receipt deletes it.
+func UpdateExpense(c *gin.Context) {
+	expense, ok := findExpense(c)
+	if !ok {
+		return
+	}
+	before := *expense
+
+	var requestBody struct {
+		Category      *string `json:"category"`
+		Description   *string `json:"description"`
+		Vendor        *string `json:"vendor"`
+		Amount        *int64  `json:"amount"`
+		Currency      *string `json:"currency"`
+		SpentOn       *string `json:"spent_on"`
+		ReceiptData   string  `json:"receipt_data"`
+		RemoveReceipt bool    `json:"remove_receipt"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+
+	if requestBody.Category != nil {
+		expense.Category = *requestBody.Category
+	}
+	if requestBody.Description != nil {
+		expense.Description = strings.TrimSpace(*requestBody.Description)
+	}
+	if requestBody.Vendor != nil {
+		expense.Vendor = strings.TrimSpace(*requestBody.Vendor)
+	}
+	if requestBody.Amount != nil {
+		expense.Amount = *requestBody.Amount
+	}
+	if requestBody.Currency != nil {
+		currency, err := normalizeCurrency(*requestBody.Currency, expense.Currency)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		expense.Currency = currency
+	}
+	if requestBody.SpentOn != nil {
+		spentOn, err := parseDate(*requestBody.SpentOn)
+		if err != nil || spentOn == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "spent_on must be a date in YYYY-MM-DD format"})
+			return
+		}
+		expense.SpentOn = *spentOn
+	}
+	if err := validateExpense(expense); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if requestBody.ReceiptData != "" {
+		if err := validateReceiptScan(requestBody.ReceiptData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// The old scan is only deleted once the entry no longer points at it
+	store := middleware.GetStorage(c.Request.Context())
+	oldScanKey := ""
+	if requestBody.ReceiptData != "" || requestBody.RemoveReceipt {
+		if store == nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Storage service not available"})
+			return
+		}
+
+		newScanKey := ""
+		if requestBody.ReceiptData != "" {
+			var err error
+			if newScanKey, err = store.UploadPrivateBase64(c.Request.Context(), requestBody.ReceiptData, expenseScanFolder); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload receipt scan: " + err.Error()})
+				return
+			}
+		}
+		oldScanKey = expense.ReceiptScanKey
+		expense.ReceiptScanKey = newScanKey
+	}
+
+	if err := config.DB.Save(expense).Error; err != nil {
+		if expense.ReceiptScanKey != "" && expense.ReceiptScanKey != before.ReceiptScanKey {
+			_ = store.DeleteFile(c.Request.Context(), expense.ReceiptScanKey)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update expense"})
+		return
+	}
+	if oldScanKey != "" {
+		_ = store.DeleteFile(c.Request.Context(), oldScanKey)
+	}
+	recordAudit(c, models.AuditUpdate, "expense", expense.ID, before, *expense)
+
+	c.JSON(http.StatusOK, expense)
+}
+
+// DeleteExpense - Remove a ledger entry and its receipt scan
+func DeleteExpense(c *gin.Context) {
+	expense, ok := findExpense(c)
+	if !ok {
+		return
+	}
+
+	store := middleware.GetStorage(c.Request.Context())
+	if expense.ReceiptScanKey != "" && store == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Storage service not available"})
+		return
+	}
+
+	if err := config.DB.Delete(expense).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete expense"})
+		return
+	}
+	if expense.ReceiptScanKey != "" {
+		_ = store.DeleteFile(c.Request.Context(), expense.ReceiptScanKey)
+	}
+	recordAudit(c, models.AuditDelete, "expense", expense.ID, *expense, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Expense deleted successfully"})
+}
+
+// DownloadExpenseReceipt - Send an expense's receipt scan to a user allowed
+// to read the ledger
+func DownloadExpenseReceipt(c *gin.Context) {
+	expense, ok := findExpense(c)
+	if !ok {
+		return
+	}
+	if expense.ReceiptScanKey == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Expense has no receipt scan"})
+		return
+	}
+
+	store := middleware.GetStorage(c.Request.Context())
+	if store == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Storage service not available"})
+		return
+	}
+
+	body, contentType, err := store.GetObject(c.Request.Context(), expense.ReceiptScanKey)
+	if err != nil {
+		log.Printf("Reading receipt scan of expense %d failed: %v", expense.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read receipt scan"})
+		return
+	}
+	defer body.Close()
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	fileName := fmt.Sprintf("expense-%d-receipt%s", expense.ID, path.Ext(expense.ReceiptScanKey))
+	c.DataFromReader(http.StatusOK, -1, contentType, body, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": fileName}),
+		"Cache-Control":       "private, no-store",
+	})
+}
@@ -0,0 +1,387 @@
+package controllers
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kholodihor/cows-shelter-backend/config"
+	"github.com/kholodihor/cows-shelter-backend/middleware"
+	"github.com/kholodihor/cows-shelter-backend/models"
+	"github.com/kholodihor/cows-shelter-backend/payments"
+	"github.com/kholodihor/cows-shelter-backend/utils"
+	"gorm.io/gorm"
+)
+
+// FinanceLine is one row of a monthly financial report
+type FinanceLine struct {
+	Category string `json:"category"` // donation source or expense category
+	Currency string `json:"currency"`
+	Count    int64  `json:"count"`
+	Total    int64  `json:"total"`
+}
+
+// FinanceTotals compares income and expenses in one currency
+type FinanceTotals struct {
+	Currency string `json:"currency"`
+	Income   int64  `json:"income"`
+	Expenses int64  `json:"expenses"`
+	Balance  int64  `json:"balance"`
+}
+
+// MonthlyFinance is the public financial report of a month. Amounts are in
+// minor currency units and never converted between currencies.
+type MonthlyFinance struct {
+	Month       string          `json:"month"`    // YYYY-MM
+	Closed      bool            `json:"closed"`   // the month is over
+	Income      []FinanceLine   `json:"income"`   // completed donations by source
+	Expenses    []FinanceLine   `json:"expenses"` // ledger entries by category
+	Totals      []FinanceTotals `json:"totals"`
+	DocumentUrl string          `json:"document_url,omitempty"` // published PDF
+}
+
+// expenseCategoryLabels names the expense categories in published reports
+var expenseCategoryLabels = map[string]string{
+	models.ExpenseFeed:       "Feed / Корми",
+	models.ExpenseVeterinary: "Veterinary care / Ветеринарна допомога",
+	models.ExpenseHousing:    "Housing and repairs / Утримання та ремонт",
+	models.ExpenseStaff:      "Staff / Персонал",
+	models.ExpenseTransport:  "Transport / Транспорт",
+	models.ExpenseUtilities:  "Utilities / Комунальні послуги",
+	models.ExpenseEquipment:  "Equipment / Обладнання",
+	models.ExpenseOther:      "Other / Інше",
+}
+
+// ukrainianMonths are the month names in Ukrainian
+var ukrainianMonths = [...]string{"січень", "лютий", "березень", "квітень", "травень", "червень",
+	"липень", "серпень", "вересень", "жовтень", "листопад", "грудень"}
+
+// monthKey formats the month of t as YYYY-MM
+func monthKey(t time.Time) string {
+	return t.Format("2006-01")
+}
+
+// parseReportMonth reads a YYYY-MM month in the shelter time zone, defaulting
+// to the previous month. Months that have not started are rejected.
+func parseReportMonth(value string) (time.Time, error) {
+	loc := config.ShelterLocation()
+	now := time.Now().In(loc)
+	current := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+	if value == "" {
+		return current.AddDate(0, -1, 0), nil
+	}
+	month, err := time.ParseInLocation("2006-01", value, loc)
+	if err != nil {
+		return month, fmt.Errorf("invalid month %q, expected YYYY-MM", value)
+	}
+	if month.After(current) {
+		return month, errors.New("the month has not started yet")
+	}
+	return month, nil
+}
+
+// monthlyFinance adds up the completed donations and the expenses of the
+// month starting at month
+func monthlyFinance(month time.Time) (*MonthlyFinance, error) {
+	end := month.AddDate(0, 1, 0)
+	report := MonthlyFinance{
+		Month:    monthKey(month),
+		Closed:   !time.Now().Before(end),
+		Income:   []FinanceLine{},
+		Expenses: []FinanceLine{},
+		Totals:   []FinanceTotals{},
+	}
+
+	err := config.DB.Model(&models.Donation{}).
+		Select("source AS category, currency, COUNT(*) AS count, COALESCE(SUM(amount), 0) AS total").
+		Where("status = ? AND received_at >= ? AND received_at < ?", models.DonationCompleted, month, end).
+		Group("source, currency").Order("source, currency").
+		Scan(&report.Income).Error
+	if err != nil {
+		return nil, err
+	}
+	err = config.DB.Model(&models.Expense{}).
+		Select("category, currency, COUNT(*) AS count, COALESCE(SUM(amount), 0) AS total").
+		Where("spent_on >= ? AND spent_on < ?", month.Format("2006-01-02"), end.Format("2006-01-02")).
+		Group("category, currency").
+		Scan(&report.Expenses).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Expenses follow the category order of the ledger
+	order := make(map[string]int, len(models.ExpenseCategories))
+	for i, category := range models.ExpenseCategories {
+		order[category] = i
+	}
+	sort.SliceStable(report.Expenses, func(i, j int) bool {
+		a, b := report.Expenses[i], report.Expenses[j]
+		if a.Category != b.Category {
+			return order[a.Category] < order[b.Category]
+		}
+		return a.Currency < b.Currency
+	})
+
+	totals := map[string]*FinanceTotals{}
+	total := func(currency string) *FinanceTotals {
+		if totals[currency] == nil {
+			totals[currency] = &FinanceTotals{Currency: currency}
+		}
+		return totals[currency]
+	}
+	for _, line := range report.Income {
+		total(line.Currency).Income += line.Total
+	}
+	for _, line := range report.Expenses {
+		total(line.Currency).Expenses += line.Total
+	}
+	for _, t := range totals {
+		t.Balance = t.Income - t.Expenses
+		report.Totals = append(report.Totals, *t)
+	}
+	sort.Slice(report.Totals, func(i, j int) bool { return report.Totals[i].Currency < report.Totals[j].Currency })
+
+	var published models.MonthlyReport
+	if err := config.DB.Preload("Pdf").Where("month = ?", report.Month).First(&published).Error; err == nil && published.Pdf != nil {
+		report.DocumentUrl = published.Pdf.DocumentUrl
+	}
+	return &report, nil
+}
+
+// writeFinanceCSV writes a report as CSV rows of type, category, currency,
+// count and amount, with amounts as decimals
+func writeFinanceCSV(c *gin.Context, report *MonthlyFinance) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="financial-report-%s.csv"`, report.Month))
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write([]string{"type", "category", "currency", "count", "amount"})
+	for _, line := range report.Income {
+		_ = w.Write([]string{"income", line.Category, line.Currency, fmt.Sprint(line.Count), payments.FormatAmount(line.Total, line.Currency)})
+	}
+	for _, line := range report.Expenses {
+		_ = w.Write([]string{"expense", line.Category, line.Currency, fmt.Sprint(line.Count), payments.FormatAmount(line.Total, line.Currency)})
+	}
+	for _, t := range report.Totals {
+		_ = w.Write([]string{"total_income", "", t.Currency, "", payments.FormatAmount(t.Income, t.Currency)})
+		_ = w.Write([]string{"total_expenses", "", t.Currency, "", payments.FormatAmount(t.Expenses, t.Currency)})
+		_ = w.Write([]string{"balance", "", t.Currency, "", payments.FormatAmount(t.Balance, t.Currency)})
+	}
+	w.Flush()
+}
+
+// financeDocument lays out a report for the published PDF
+func financeDocument(report *MonthlyFinance, month time.Time) utils.FinanceReport {
+	document := utils.FinanceReport{
+		Organization: shelterParty().Name,
+		PeriodEn:     fmt.Sprintf("%s %d", month.Month(), month.Year()),
+		PeriodUa:     fmt.Sprintf("%s %d", ukrainianMonths[month.Month()-1], month.Year()),
+		GeneratedAt:  time.Now().In(config.ShelterLocation()),
+	}
+	for _, line := range report.Income {
+		label := receiptMethods[line.Category]
+		if label == "" {
+			label = line.Category
+		}
+		document.Income = append(document.Income, utils.FinanceRow{
+			Label: label, Currency: line.Currency, Count: line.Count,
+			Amount: payments.FormatAmount(line.Total, line.Currency),
+		})
+	}
+	for _, line := range report.Expenses {
+		document.Expenses = append(document.Expenses, utils.FinanceRow{
+			Label: expenseCategoryLabels[line.Category], Currency: line.Currency, Count: line.Count,
+			Amount: payments.FormatAmount(line.Total, line.Currency),
+		})
+	}
+	for _, t := range report.Totals {
+		document.Balances = append(document.Balances, utils.FinanceBalance{
+			Currency: t.Currency,
+			Income:   payments.FormatAmount(t.Income, t.Currency),
+			Expenses: payments.FormatAmount(t.Expenses, t.Currency),
+			Balance:  payments.FormatAmount(t.Balance, t.Currency),
+		})
+	}
+	return document
+}
+
+// PublishMonthlyReport renders the financial report of a month as a PDF and
+// publishes it in the documents list, replacing the document if the month
+// was published before. The storage service comes from ctx.
+func PublishMonthlyReport(ctx context.Context, month time.Time) (*models.MonthlyReport, error) {
+	store := middleware.GetStorage(ctx)
+	if store == nil {
+		return nil, errors.New("storage service not available")
+	}
+	report, err := monthlyFinance(month)
+	if err != nil {
+		return nil, err
+	}
+	fonts, err := loadPDFFonts()
+	if err != nil {
+		return nil, err
+	}
+	document := financeDocument(report, month)
+	data, err := utils.RenderFinanceReport(document, fonts)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("documents/financial-report-%s-%d.pdf", report.Month, time.Now().UnixNano())
+	url, err := store.UploadBytes(ctx, key, data, "application/pdf")
+	if err != nil {
+		return nil, err
+	}
+
+	title := fmt.Sprintf("Financial report %s / Фінансовий звіт за %s", document.PeriodEn, document.PeriodUa)
+	previous := ""
+	var record models.MonthlyReport
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Preload("Pdf").Where("month = ?", report.Month).First(&record).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if record.Pdf != nil {
+			previous = record.Pdf.DocumentUrl
+			record.Pdf.Title = title
+			record.Pdf.DocumentUrl = url
+			if err := tx.Save(record.Pdf).Error; err != nil {
+				return err
+			}
+		} else {
+			// First publication, or the document was deleted from the list
+			pdf := models.Pdf{Title: title, DocumentUrl: url}
+			if err := tx.Create(&pdf).Error; err != nil {
+				return err
+			}
+			record.PdfID = pdf.ID
+			record.Pdf = &pdf
+		}
+		record.Month = report.Month
+		record.PublishedAt = time.Now()
+		return tx.Omit("Pdf").Save(&record).Error
+	})
+	if err != nil {
+		_ = store.DeleteFile(ctx, store.ExtractObjectName(url))
+		return nil, err
+	}
+	if previous != "" {
+		if err := store.DeleteFile(ctx, store.ExtractObjectName(previous)); err != nil {
+			log.Printf("Failed to delete previous financial report for %s: %v", report.Month, err)
+		}
+	}
+	return &record, nil
+}
+
+// PublishClosedMonthReport publishes the report of the previous month once
+// FINANCIAL_REPORT_PUBLISH_DAY of the current month is reached, leaving time
+// to enter late bank transfers and expenses. It returns the published
+// report, or nil if there was nothing to publish.
+func PublishClosedMonthReport(ctx context.Context) (*models.MonthlyReport, error) {
+	now := time.Now().In(config.ShelterLocation())
+	if now.Day() < envInt("FINANCIAL_REPORT_PUBLISH_DAY", 1) {
+		return nil, nil
+	}
+	month, _ := parseReportMonth("")
+
+	var count int64
+	if err := config.DB.Model(&models.MonthlyReport{}).Where("month = ?", monthKey(month)).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, nil
+	}
+	return PublishMonthlyReport(ctx, month)
+}
+
+// StartMonthlyReports publishes the report of each closed month, checking
+// now and then every FINANCIAL_REPORT_INTERVAL until ctx is cancelled. ctx
+// must carry the storage service.
+func StartMonthlyReports(ctx context.Context) {
+	interval := envDuration("FINANCIAL_REPORT_INTERVAL", 6*time.Hour)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if record, err := PublishClosedMonthReport(ctx); err != nil {
+				log.Printf("Failed to publish the monthly financial report: %v", err)
+			} else if record != nil {
+				log.Printf("Published the financial report for %s", record.Month)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// GetMonthlyFinance - Public report of a month's income and expenses by
+// category. ?month=YYYY-MM (the previous month by default) and ?format=csv
+// to download it as CSV. Published months include the PDF's document_url.
+func GetMonthlyFinance(c *gin.Context) {
+	month, err := parseReportMonth(c.Query("month"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	report, err := monthlyFinance(month)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error building financial report"})
+		return
+	}
+
+	switch strings.ToLower(c.DefaultQuery("format", "json")) {
+	case "json":
+		c.JSON(http.StatusOK, report)
+	case "csv":
+		writeFinanceCSV(c, report)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+	}
+}
+
+// PublishMonthlyFinance - Publish the financial report of a closed month now,
+// or publish it again after corrections, replacing the earlier document
+func PublishMonthlyFinance(c *gin.Context) {
+	month, err := parseReportMonth(c.Param("month"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if time.Now().Before(month.AddDate(0, 1, 0)) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only closed months can be published"})
+		return
+	}
+
+	var before *models.MonthlyReport
+	var existing models.MonthlyReport
+	if err := config.DB.Where("month = ?", monthKey(month)).First(&existing).Error; err == nil {
+		before = &existing
+	}
+
+	record, err := PublishMonthlyReport(c.Request.Context(), month)
+	if err != nil {
+		log.Printf("Failed to publish the financial report for %s: %v", monthKey(month), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish financial report"})
+		return
+	}
+	if before == nil {
+		recordAudit(c, models.AuditCreate, "monthly_report", record.ID, nil, *record)
+	} else {
+		recordAudit(c, models.AuditUpdate, "monthly_report", record.ID, *before, *record)
+	}
+
+	c.JSON(http.StatusOK, record)
+}
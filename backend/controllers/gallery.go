@@ -122,6 +122,7 @@ func CreateGallery(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create gallery entry: " + err.Error()})
 		return
 	}
+	recordAudit(c, models.AuditCreate, "gallery", gallery.ID, nil, gallery)
 
 	c.JSON(http.StatusCreated, gin.H{
 		"id":       gallery.ID,
@@ -149,6 +150,7 @@ func UpdateGallery(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Gallery not found"})
 		return
 	}
+	before := gallery
 
 	var req UpdateGalleryRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -185,6 +187,7 @@ func UpdateGallery(c *gin.Context) {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update gallery: " + err.Error()})
 			return
 		}
+		recordAudit(c, models.AuditUpdate, "gallery", gallery.ID, before, gallery)
 	}
 
 	c.JSON(http.StatusOK, &gallery)
@@ -229,6 +232,7 @@ func DeleteGallery(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete gallery"})
 		return
 	}
+	recordAudit(c, models.AuditDelete, "gallery", gallery.ID, gallery, nil)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Gallery item deleted successfully"})
 }
@@ -0,0 +1,230 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kholodihor/cows-shelter-backend/config"
+	"github.com/kholodihor/cows-shelter-backend/mailer"
+	"github.com/kholodihor/cows-shelter-backend/models"
+	"github.com/kholodihor/cows-shelter-backend/utils"
+	"gorm.io/gorm"
+)
+
+// invitationTTL returns how long an invitation can be accepted
+func invitationTTL() time.Duration {
+	return envDuration("INVITATION_TTL", 7*24*time.Hour)
+}
+
+// findValidInvitation returns the pending, unexpired invitation for a plain token
+func findValidInvitation(db *gorm.DB, token string) (*models.Invitation, error) {
+	var invitation models.Invitation
+	err := db.Where("token_hash = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?",
+		utils.HashToken(token), time.Now()).
+		First(&invitation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// CreateInvitation - Invite someone to register with a preassigned role. With
+// self-registration disabled the account is created right away instead, and
+// its owner is emailed a link to choose a password.
+func CreateInvitation(c *gin.Context) {
+	var requestBody struct {
+		Email string `json:"email" binding:"required,email"`
+		Name  string `json:"name"`
+		Role  string `json:"role"`
+	}
+
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	if requestBody.Role == "" {
+		requestBody.Role = models.RoleEditor
+	}
+	if !models.ValidRole(requestBody.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown role"})
+		return
+	}
+
+	var existingUser models.User
+	if err := config.DB.Where("email = ?", requestBody.Email).First(&existingUser).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already taken"})
+		return
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking existing user"})
+		return
+	}
+
+	if !config.SelfRegistrationEnabled() {
+		provisionUser(c, requestBody.Email, requestBody.Name, requestBody.Role)
+		return
+	}
+
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating invitation"})
+		return
+	}
+
+	now := time.Now()
+	ttl := invitationTTL()
+	invitation := models.Invitation{
+		Email:       requestBody.Email,
+		Name:        requestBody.Name,
+		Role:        requestBody.Role,
+		TokenHash:   utils.HashToken(token),
+		InvitedByID: c.MustGet("user").(models.User).ID,
+		ExpiresAt:   now.Add(ttl),
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// A new invitation replaces any pending one for the same email
+		if err := tx.Model(&models.Invitation{}).
+			Where("email = ? AND accepted_at IS NULL AND revoked_at IS NULL", invitation.Email).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(&invitation).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating invitation"})
+		return
+	}
+	recordAudit(c, models.AuditCreate, "invitation", invitation.ID, nil, invitation)
+
+	link := fmt.Sprintf("%s/register/%s", config.GetEnv("FRONTEND_URL", "http://localhost:5173"), token)
+	if err := config.Mailer.Send(c.Request.Context(), mailer.Message{
+		To:      invitation.Email,
+		Subject: "You are invited to Cows Shelter",
+		Body: fmt.Sprintf("You have been invited to join the Cows Shelter admin panel as %s.\n\n"+
+			"Open the link below to create your account. It expires in %s and can be used once.\n\n%s\n",
+			invitation.Role, ttl, link),
+	}); err != nil {
+		// The admin can still pass the link on by hand
+		log.Printf("Invitation email for %s failed: %v", invitation.Email, err)
+		c.JSON(http.StatusCreated, gin.H{
+			"invitation": invitation,
+			"link":       link,
+			"warning":    "The invitation email could not be sent; pass the link on yourself",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"invitation": invitation})
+}
+
+// provisionUser creates an account for an admin while self-registration is
+// disabled and emails its owner a link to set the password
+func provisionUser(c *gin.Context, email, name, role string) {
+	password, err := unusablePassword()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating user"})
+		return
+	}
+
+	user := models.User{Email: email, Name: name, Password: password, Role: role}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Pending invitations for the email can no longer be accepted anyway
+		if err := tx.Model(&models.Invitation{}).
+			Where("email = ? AND accepted_at IS NULL AND revoked_at IS NULL", email).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(&user).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating user"})
+		return
+	}
+	recordAudit(c, models.AuditCreate, "user", user.ID, nil, user)
+
+	if err := issuePasswordReset(c, &user, accountCreatedEmail); err != nil {
+		log.Printf("Account email for user %d failed: %v", user.ID, err)
+		c.JSON(http.StatusCreated, gin.H{
+			"user":    user,
+			"warning": "The account email could not be sent; send a new link with the password reset action",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"user": user})
+}
+
+// GetInvitations - List invitations; ?status=pending|accepted|revoked|expired filters the list
+func GetInvitations(c *gin.Context) {
+	var invitations []models.Invitation
+	query := config.DB.Order("created_at DESC")
+
+	now := time.Now()
+	switch c.Query("status") {
+	case "pending":
+		query = query.Where("accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", now)
+	case "accepted":
+		query = query.Where("accepted_at IS NOT NULL")
+	case "revoked":
+		query = query.Where("revoked_at IS NOT NULL")
+	case "expired":
+		query = query.Where("accepted_at IS NULL AND revoked_at IS NULL AND expires_at <= ?", now)
+	}
+
+	if err := query.Find(&invitations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching invitations"})
+		return
+	}
+	c.JSON(http.StatusOK, &invitations)
+}
+
+// RevokeInvitation - Revoke a pending invitation
+func RevokeInvitation(c *gin.Context) {
+	var invitation models.Invitation
+	if err := config.DB.Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", c.Param("id")).
+		First(&invitation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Pending invitation not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching invitation"})
+		return
+	}
+	before := invitation
+
+	now := time.Now()
+	invitation.RevokedAt = &now
+	if err := config.DB.Model(&invitation).Update("revoked_at", now).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error revoking invitation"})
+		return
+	}
+	recordAudit(c, models.AuditUpdate, "invitation", invitation.ID, before, invitation)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked"})
+}
+
+// GetInvitationByToken - Check whether an invitation token can still be used
+func GetInvitationByToken(c *gin.Context) {
+	if !config.SelfRegistrationEnabled() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Self-registration is disabled"})
+		return
+	}
+
+	invitation, err := findValidInvitation(config.DB, c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation is invalid or expired"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"email":      invitation.Email,
+		"name":       invitation.Name,
+		"role":       invitation.Role,
+		"expires_at": invitation.ExpiresAt,
+	})
+}
@@ -0,0 +1,217 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kholodihor/cows-shelter-backend/config"
+	"github.com/kholodihor/cows-shelter-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// lockoutPolicy controls how failed logins are throttled
+type lockoutPolicy struct {
+	MaxAccountFailures int           // failures per account before locking
+	MaxIPFailures      int           // failures per client IP before locking
+	BaseLockout        time.Duration // first lockout, doubled on each further failure
+	MaxLockout         time.Duration // upper bound for a single lockout
+	FailureWindow      time.Duration // failures older than this are forgotten
+}
+
+func envInt(key string, fallback int) int {
+	value, err := strconv.Atoi(config.GetEnv(key, ""))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(config.GetEnv(key, ""))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+func currentLockoutPolicy() lockoutPolicy {
+	return lockoutPolicy{
+		MaxAccountFailures: envInt("LOGIN_MAX_FAILURES", 5),
+		MaxIPFailures:      envInt("LOGIN_MAX_FAILURES_PER_IP", 20),
+		BaseLockout:        envDuration("LOGIN_LOCKOUT_BASE", time.Minute),
+		MaxLockout:         envDuration("LOGIN_LOCKOUT_MAX", time.Hour),
+		FailureWindow:      envDuration("LOGIN_FAILURE_WINDOW", time.Hour),
+	}
+}
+
+// lockoutDuration returns the exponential backoff for the given failure count
+func (p lockoutPolicy) lockoutDuration(failures, threshold int) time.Duration {
+	if failures < threshold {
+		return 0
+	}
+	exponent := failures - threshold
+	if exponent > 30 {
+		return p.MaxLockout
+	}
+	duration := p.BaseLockout << exponent
+	if duration <= 0 || duration > p.MaxLockout {
+		return p.MaxLockout
+	}
+	return duration
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// loginLockedFor returns how long the account or IP remains locked, or zero
+func loginLockedFor(email, ip string) (time.Duration, error) {
+	var throttles []models.LoginThrottle
+	err := config.DB.
+		Where("(kind = ? AND subject = ?) OR (kind = ? AND subject = ?)",
+			models.ThrottleAccount, normalizeEmail(email), models.ThrottleIP, ip).
+		Where("locked_until > ?", time.Now()).
+		Find(&throttles).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var remaining time.Duration
+	for _, throttle := range throttles {
+		if left := time.Until(*throttle.LockedUntil); left > remaining {
+			remaining = left
+		}
+	}
+	return remaining, nil
+}
+
+// recordAttempt counts a failed login or a public request and locks the
+// subject once the threshold is reached
+func recordAttempt(kind, subject string, threshold int, policy lockoutPolicy) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		// Create the row first so there is always one to lock; concurrent first
+		// failures would otherwise both insert and trip the unique index
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.LoginThrottle{Kind: kind, Subject: subject}).Error; err != nil {
+			return err
+		}
+
+		var throttle models.LoginThrottle
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("kind = ? AND subject = ?", kind, subject).
+			First(&throttle).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if throttle.Failures > 0 && now.Sub(throttle.LastFailureAt) > policy.FailureWindow &&
+			(throttle.LockedUntil == nil || throttle.LockedUntil.Before(now)) {
+			throttle.Failures = 0
+		}
+
+		throttle.Failures++
+		throttle.LastFailureAt = now
+		if lockout := policy.lockoutDuration(throttle.Failures, threshold); lockout > 0 {
+			lockedUntil := now.Add(lockout)
+			throttle.LockedUntil = &lockedUntil
+		}
+
+		return tx.Save(&throttle).Error
+	})
+}
+
+// recordLoginFailures counts a failed attempt for both the account and the IP
+func recordLoginFailures(email, ip string) error {
+	policy := currentLockoutPolicy()
+	if err := recordAttempt(models.ThrottleAccount, normalizeEmail(email), policy.MaxAccountFailures, policy); err != nil {
+		return err
+	}
+	return recordAttempt(models.ThrottleIP, ip, policy.MaxIPFailures, policy)
+}
+
+// clearAccountFailures forgets failed attempts after a successful login
+func clearAccountFailures(email string) error {
+	return config.DB.
+		Where("kind = ? AND subject = ?", models.ThrottleAccount, normalizeEmail(email)).
+		Delete(&models.LoginThrottle{}).Error
+}
+
+// respondLocked answers a request for a locked account or IP
+func respondLocked(c *gin.Context, remaining time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(remaining.Seconds()))))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed login attempts, please try again later"})
+}
+
+// allowPublicRequest counts a request to a public form and returns how long the
+// subject has to wait before it may send another, or zero if this one may go
+// ahead. After limit requests the subject is locked for window.
+func allowPublicRequest(kind, subject string, limit int, window time.Duration) (time.Duration, error) {
+	var throttles []models.LoginThrottle
+	if err := config.DB.Where("kind = ? AND subject = ? AND locked_until > ?", kind, subject, time.Now()).
+		Find(&throttles).Error; err != nil {
+		return 0, err
+	}
+	if len(throttles) > 0 {
+		return time.Until(*throttles[0].LockedUntil), nil
+	}
+
+	policy := lockoutPolicy{BaseLockout: window, MaxLockout: window, FailureWindow: window}
+	return 0, recordAttempt(kind, subject, limit, policy)
+}
+
+// publicFormWindow is the period the per-IP limits of public forms apply to
+func publicFormWindow() time.Duration {
+	return envDuration("PUBLIC_FORM_WINDOW", time.Hour)
+}
+
+// respondThrottled answers a public request over its limit
+func respondThrottled(c *gin.Context, remaining time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(remaining.Seconds()))))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, please try again later"})
+}
+
+// GetLockouts - List tracked login failures and throttled public requests; ?locked=true returns only active lockouts
+func GetLockouts(c *gin.Context) {
+	var throttles []models.LoginThrottle
+	query := config.DB.Order("last_failure_at DESC")
+	if c.Query("locked") == "true" {
+		query = query.Where("locked_until > ?", time.Now())
+	}
+	if kind := c.Query("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+
+	if err := query.Find(&throttles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching lockouts"})
+		return
+	}
+	c.JSON(http.StatusOK, &throttles)
+}
+
+// DeleteLockout - Clear the failures and lockout of one account or IP
+func DeleteLockout(c *gin.Context) {
+	var throttle models.LoginThrottle
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&throttle).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Lockout not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching lockout"})
+		return
+	}
+
+	if err := config.DB.Delete(&throttle).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear lockout"})
+		return
+	}
+	recordAudit(c, models.AuditDelete, "lockout", throttle.ID, throttle, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Lockout for %s %s cleared", throttle.Kind, throttle.Subject)})
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -9,6 +10,10 @@ import (
 	"github.com/kholodihor/cows-shelter-backend/utils"
 )
 
+// dummyPasswordHash is compared against when the email is unknown so that
+// response times do not reveal which accounts exist
+const dummyPasswordHash = "$2a$14$XuTwnmCfFlX4Nw.Cf1tnoOS.W4vdmSV3B5HrTv7uDxQJqJ2zwdHp6"
+
 func LoginUser(c *gin.Context) {
 	var requestBody struct {
 		Email    string `json:"email"`
@@ -20,30 +25,59 @@ func LoginUser(c *gin.Context) {
 		return
 	}
 
+	ip := c.ClientIP()
+
+	// Refuse attempts while the account or the client IP is locked out
+	remaining, err := loginLockedFor(requestBody.Email, ip)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking login attempts"})
+		return
+	}
+	if remaining > 0 {
+		respondLocked(c, remaining)
+		return
+	}
+
 	var user models.User
-	if err := config.DB.Where("email = ?", requestBody.Email).First(&user).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email"})
+	found := config.DB.Where("email = ?", requestBody.Email).First(&user).Error == nil
+
+	passwordHash := dummyPasswordHash
+	if found {
+		passwordHash = user.Password
+	}
+
+	// Check if password is correct; unknown emails fail the same way
+	if !utils.CheckPasswordHash(requestBody.Password, passwordHash) || !found {
+		if err := recordLoginFailures(requestBody.Email, ip); err != nil {
+			log.Printf("Failed to record login failure: %v", err)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+		return
+	}
+
+	if user.DeactivatedAt != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is deactivated"})
 		return
 	}
 
-	// Check if password is correct
-	if !utils.CheckPasswordHash(requestBody.Password, user.Password) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
+	// Accounts with 2FA get a challenge instead of tokens. Their failures
+	// are only cleared once the second factor succeeds, so logging in again
+	// with the password cannot reset the lockout on code guesses.
+	if loginChallenge(c, &user) {
 		return
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateJWT(user.Email)
+	if err := clearAccountFailures(user.Email); err != nil {
+		log.Printf("Failed to clear login failures: %v", err)
+	}
+
+	// Issue an access token and start a new refresh token family
+	tokens, err := issueTokens(c, &user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating token"})
 		return
 	}
 
-	// Return the token and user info
-	c.JSON(http.StatusOK, gin.H{
-		"token": token,
-		"user": gin.H{
-			"email": user.Email,
-		},
-	})
-}
\ No newline at end of file
+	// Return the tokens and user info
+	c.JSON(http.StatusOK, tokenResponse(&user, tokens))
+}
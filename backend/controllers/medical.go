@@ -0,0 +1,538 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kholodihor/cows-shelter-backend/config"
+	"github.com/kholodihor/cows-shelter-backend/mailer"
+	"github.com/kholodihor/cows-shelter-backend/middleware"
+	"github.com/kholodihor/cows-shelter-backend/models"
+	"gorm.io/gorm"
+)
+
+// findMedicalAttachment loads the attachment named by the :attachment_id route
+// parameter, which must belong to the record named by :id
+func findMedicalAttachment(c *gin.Context) (*models.MedicalAttachment, bool) {
+	var attachment models.MedicalAttachment
+	if err := config.DB.Where("id = ? AND medical_record_id = ?", c.Param("attachment_id"), c.Param("id")).First(&attachment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching attachment"})
+		return nil, false
+	}
+	return &attachment, true
+}
+
+// CreateMedicalRecordRequest represents the JSON request body for a medical record
+type CreateMedicalRecordRequest struct {
+	Type         string   `json:"type" binding:"required"`
+	Title        string   `json:"title"`
+	Notes        string   `json:"notes"`
+	Veterinarian string   `json:"veterinarian"`
+	PerformedAt  string   `json:"performed_at" binding:"required"` // YYYY-MM-DD
+	WeightKg     *float64 `json:"weight_kg"`
+	NextDueAt    string   `json:"next_due_at"` // YYYY-MM-DD
+}
+
+// UpdateMedicalRecordRequest represents the JSON request body for updating a
+// medical record. Omitted fields are left unchanged; an empty next_due_at clears it.
+type UpdateMedicalRecordRequest struct {
+	Type         *string  `json:"type"`
+	Title        *string  `json:"title"`
+	Notes        *string  `json:"notes"`
+	Veterinarian *string  `json:"veterinarian"`
+	PerformedAt  *string  `json:"performed_at"`
+	WeightKg     *float64 `json:"weight_kg"`
+	NextDueAt    *string  `json:"next_due_at"`
+}
+
+// AddMedicalAttachmentRequest represents the JSON request body for an attachment
+type AddMedicalAttachmentRequest struct {
+	FileName string `json:"file_name" binding:"required"`
+	FileData string `json:"file_data" binding:"required"` // base64 data URL
+}
+
+// MedicalReminder is a record whose follow-up is due, with the animal's name
+type MedicalReminder struct {
+	models.MedicalRecord
+	AnimalNameEn string `json:"animal_name_en"`
+	AnimalNameUa string `json:"animal_name_ua"`
+	Overdue      bool   `json:"overdue" gorm:"-"`
+}
+
+// validateMedicalRecord checks the fields that depend on the record type
+func validateMedicalRecord(record *models.MedicalRecord) error {
+	if !models.ValidMedicalType(record.Type) {
+		return errors.New("type must be vaccination, treatment, checkup or weight")
+	}
+	if record.Type == models.MedicalWeight && (record.WeightKg == nil || *record.WeightKg <= 0) {
+		return errors.New("weight_kg is required for weight measurements")
+	}
+	if record.Type == models.MedicalVaccination && strings.TrimSpace(record.Title) == "" {
+		return errors.New("title is required for vaccinations")
+	}
+	if record.NextDueAt != nil && record.NextDueAt.Before(record.PerformedAt) {
+		return errors.New("next_due_at cannot be before performed_at")
+	}
+	return nil
+}
+
+// findMedicalRecord loads the record named by the :id route parameter with its attachments
+func findMedicalRecord(c *gin.Context) (*models.MedicalRecord, bool) {
+	var record models.MedicalRecord
+	if err := config.DB.Preload("Attachments").Where("id = ?", c.Param("id")).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Medical record not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching medical record"})
+		return nil, false
+	}
+	return &record, true
+}
+
+// GetAnimalMedicalRecords - List an animal's medical history, newest first; ?type= filters by type
+func GetAnimalMedicalRecords(c *gin.Context) {
+	animal, ok := findAnimal(c)
+	if !ok {
+		return
+	}
+
+	records := []models.MedicalRecord{}
+	query := config.DB.Preload("Attachments").Where("animal_id = ?", animal.ID).Order("performed_at DESC, id DESC")
+	if kind := c.Query("type"); kind != "" {
+		query = query.Where("type = ?", kind)
+	}
+	if err := query.Find(&records).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching medical records"})
+		return
+	}
+	c.JSON(http.StatusOK, &records)
+}
+
+// CreateMedicalRecord - Add a medical event to an animal's history
+func CreateMedicalRecord(c *gin.Context) {
+	animal, ok := findAnimal(c)
+	if !ok {
+		return
+	}
+
+	var req CreateMedicalRecordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+
+	performedAt, err := parseDate(req.PerformedAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "performed_at: " + err.Error()})
+		return
+	}
+	nextDueAt, err := parseDate(req.NextDueAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "next_due_at: " + err.Error()})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+	record := models.MedicalRecord{
+		AnimalID:     animal.ID,
+		Type:         req.Type,
+		Title:        strings.TrimSpace(req.Title),
+		Notes:        req.Notes,
+		Veterinarian: req.Veterinarian,
+		PerformedAt:  *performedAt,
+		WeightKg:     req.WeightKg,
+		NextDueAt:    nextDueAt,
+		CreatedByID:  user.ID,
+	}
+	if err := validateMedicalRecord(&record); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := config.DB.Create(&record).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create medical record"})
+		return
+	}
+	recordAudit(c, models.AuditCreate, "medical_record", record.ID, nil, record)
+
+	c.JSON(http.StatusCreated, record)
+}
+
+// GetMedicalRecordByID - Get a medical record with its attachments
+func GetMedicalRecordByID(c *gin.Context) {
+	record, ok := findMedicalRecord(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, record)
+}
+
+// UpdateMedicalRecord - Update a medical record; omitted fields are left unchanged
+func UpdateMedicalRecord(c *gin.Context) {
+	record, ok := findMedicalRecord(c)
+	if !ok {
+		return
+	}
+	before := *record
+
+	var req UpdateMedicalRecordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+
+	if req.Type != nil {
+		record.Type = *req.Type
+	}
+	if req.Title != nil {
+		record.Title = strings.TrimSpace(*req.Title)
+	}
+	if req.Notes != nil {
+		record.Notes = *req.Notes
+	}
+	if req.Veterinarian != nil {
+		record.Veterinarian = *req.Veterinarian
+	}
+	if req.WeightKg != nil {
+		record.WeightKg = req.WeightKg
+	}
+	if req.PerformedAt != nil {
+		performedAt, err := parseDate(*req.PerformedAt)
+		if err != nil || performedAt == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "performed_at must be a YYYY-MM-DD date"})
+			return
+		}
+		record.PerformedAt = *performedAt
+	}
+	if req.NextDueAt != nil {
+		nextDueAt, err := parseDate(*req.NextDueAt)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "next_due_at: " + err.Error()})
+			return
+		}
+		// A new due date deserves a new reminder
+		if !sameDate(record.NextDueAt, nextDueAt) {
+			record.ReminderSentAt = nil
+		}
+		record.NextDueAt = nextDueAt
+	}
+	if err := validateMedicalRecord(record); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Attachments are managed through their own endpoints
+	if err := config.DB.Omit("Attachments").Save(record).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update medical record"})
+		return
+	}
+	recordAudit(c, models.AuditUpdate, "medical_record", record.ID, before, *record)
+
+	c.JSON(http.StatusOK, record)
+}
+
+// DeleteMedicalRecord - Delete a medical record and its attachments
+func DeleteMedicalRecord(c *gin.Context) {
+	record, ok := findMedicalRecord(c)
+	if !ok {
+		return
+	}
+
+	store := middleware.GetStorage(c.Request.Context())
+	if store == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Storage service not available"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("medical_record_id = ?", record.ID).Delete(&models.MedicalAttachment{}).Error; err != nil {
+			return err
+		}
+		return tx.Omit("Attachments").Delete(record).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete medical record"})
+		return
+	}
+
+	for i := range record.Attachments {
+		if objectName := record.Attachments[i].ObjectKey; objectName != "" {
+			_ = store.DeleteFile(c.Request.Context(), objectName)
+		}
+	}
+	recordAudit(c, models.AuditDelete, "medical_record", record.ID, *record, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Medical record deleted successfully"})
+}
+
+// AddMedicalAttachment - Upload a file and attach it to a medical record
+func AddMedicalAttachment(c *gin.Context) {
+	record, ok := findMedicalRecord(c)
+	if !ok {
+		return
+	}
+
+	var req AddMedicalAttachmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+
+	store := middleware.GetStorage(c.Request.Context())
+	if store == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Storage service not available"})
+		return
+	}
+
+	// Medical files never get a public URL
+	objectKey, err := store.UploadPrivateBase64(c.Request.Context(), req.FileData, "medical")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload file: " + err.Error()})
+		return
+	}
+
+	attachment := models.MedicalAttachment{
+		MedicalRecordID: record.ID,
+		FileName:        req.FileName,
+		ObjectKey:       objectKey,
+	}
+	if err := config.DB.Create(&attachment).Error; err != nil {
+		_ = store.DeleteFile(c.Request.Context(), objectKey)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add attachment"})
+		return
+	}
+	recordAudit(c, models.AuditCreate, "medical_attachment", attachment.ID, nil, attachment)
+
+	c.JSON(http.StatusCreated, attachment)
+}
+
+// DownloadMedicalAttachment - Send an attachment's file to a user allowed to
+// read medical records
+func DownloadMedicalAttachment(c *gin.Context) {
+	attachment, ok := findMedicalAttachment(c)
+	if !ok {
+		return
+	}
+
+	store := middleware.GetStorage(c.Request.Context())
+	if store == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Storage service not available"})
+		return
+	}
+
+	body, contentType, err := store.GetObject(c.Request.Context(), attachment.ObjectKey)
+	if err != nil {
+		log.Printf("Reading medical attachment %d failed: %v", attachment.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read attachment"})
+		return
+	}
+	defer body.Close()
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	c.DataFromReader(http.StatusOK, -1, contentType, body, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}),
+		"Cache-Control":       "private, no-store",
+	})
+}
+
+// DeleteMedicalAttachment - Remove an attachment from a medical record
+func DeleteMedicalAttachment(c *gin.Context) {
+	attachment, ok := findMedicalAttachment(c)
+	if !ok {
+		return
+	}
+
+	store := middleware.GetStorage(c.Request.Context())
+	if store == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Storage service not available"})
+		return
+	}
+
+	if err := config.DB.Delete(attachment).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete attachment"})
+		return
+	}
+	if objectName := attachment.ObjectKey; objectName != "" {
+		_ = store.DeleteFile(c.Request.Context(), objectName)
+	}
+	recordAudit(c, models.AuditDelete, "medical_attachment", attachment.ID, *attachment, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Attachment deleted successfully"})
+}
+
+func sameDate(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// medicalReminderDays is how far ahead follow-ups count as upcoming
+func medicalReminderDays() int {
+	return envInt("MEDICAL_REMINDER_DAYS", 14)
+}
+
+// dueMedicalReminders returns follow-ups due on or before the given date for
+// resident animals. A follow-up is settled once a later record of the same
+// type and title is added for the animal.
+func dueMedicalReminders(db *gorm.DB, until time.Time) ([]MedicalReminder, error) {
+	reminders := []MedicalReminder{}
+	err := db.Model(&models.MedicalRecord{}).
+		Select("medical_records.*, animals.name_en AS animal_name_en, animals.name_ua AS animal_name_ua").
+		Joins("JOIN animals ON animals.id = medical_records.animal_id AND animals.deleted_at IS NULL").
+		Where("animals.status = ?", models.AnimalResident).
+		Where("medical_records.next_due_at IS NOT NULL AND medical_records.next_due_at <= ?", until).
+		Where(`NOT EXISTS (
+			SELECT 1 FROM medical_records later
+			WHERE later.animal_id = medical_records.animal_id
+				AND later.type = medical_records.type
+				AND LOWER(later.title) = LOWER(medical_records.title)
+				AND later.deleted_at IS NULL
+				AND (later.performed_at > medical_records.performed_at
+					OR (later.performed_at = medical_records.performed_at AND later.id > medical_records.id)))`).
+		Order("medical_records.next_due_at, medical_records.id").
+		Scan(&reminders).Error
+	if err != nil {
+		return nil, err
+	}
+
+	today := time.Now().Truncate(24 * time.Hour)
+	for i := range reminders {
+		reminders[i].Overdue = reminders[i].NextDueAt.Before(today)
+	}
+	return reminders, nil
+}
+
+// GetMedicalReminders - List overdue and upcoming follow-ups; ?days= sets how far
+// ahead to look (default MEDICAL_REMINDER_DAYS)
+func GetMedicalReminders(c *gin.Context) {
+	days := medicalReminderDays()
+	if d := c.Query("days"); d != "" {
+		if _, err := fmt.Sscanf(d, "%d", &days); err != nil || days < 0 || days > 365 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 0 and 365"})
+			return
+		}
+	}
+
+	reminders, err := dueMedicalReminders(config.DB, time.Now().AddDate(0, 0, days))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching reminders"})
+		return
+	}
+	c.JSON(http.StatusOK, reminders)
+}
+
+// medicalReminderRecipients returns MEDICAL_REMINDER_EMAILS, or every active
+// admin when it is not set
+func medicalReminderRecipients() ([]string, error) {
+	if emails := config.GetEnv("MEDICAL_REMINDER_EMAILS", ""); emails != "" {
+		var recipients []string
+		for _, email := range strings.Split(emails, ",") {
+			if email = strings.TrimSpace(email); email != "" {
+				recipients = append(recipients, email)
+			}
+		}
+		return recipients, nil
+	}
+
+	var recipients []string
+	err := config.DB.Model(&models.User{}).
+		Where("role = ? AND deactivated_at IS NULL", models.RoleAdmin).
+		Pluck("email", &recipients).Error
+	return recipients, err
+}
+
+// SendMedicalReminders emails a digest of follow-ups that are due soon and
+// have not been announced yet, then marks them as sent
+func SendMedicalReminders(ctx context.Context) (int, error) {
+	reminders, err := dueMedicalReminders(config.DB, time.Now().AddDate(0, 0, medicalReminderDays()))
+	if err != nil {
+		return 0, err
+	}
+
+	var pending []MedicalReminder
+	for _, reminder := range reminders {
+		if reminder.ReminderSentAt == nil {
+			pending = append(pending, reminder)
+		}
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	recipients, err := medicalReminderRecipients()
+	if err != nil {
+		return 0, err
+	}
+	if len(recipients) == 0 {
+		return 0, errors.New("no recipients for medical reminders")
+	}
+
+	var body strings.Builder
+	body.WriteString("The following veterinary follow-ups are due:\n\n")
+	ids := make([]uint, len(pending))
+	for i, reminder := range pending {
+		ids[i] = reminder.ID
+		status := "due"
+		if reminder.Overdue {
+			status = "OVERDUE"
+		}
+		fmt.Fprintf(&body, "- %s: %s %s, %s %s\n", reminder.AnimalNameEn, reminder.Type, reminder.Title,
+			status, reminder.NextDueAt.Format("2006-01-02"))
+	}
+
+	for _, recipient := range recipients {
+		if err := config.Mailer.Send(ctx, mailer.Message{
+			To:      recipient,
+			Subject: fmt.Sprintf("Cows Shelter: %d veterinary follow-ups due", len(pending)),
+			Body:    body.String(),
+		}); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := config.DB.Model(&models.MedicalRecord{}).Where("id IN ?", ids).
+		Update("reminder_sent_at", time.Now()).Error; err != nil {
+		return 0, err
+	}
+	return len(pending), nil
+}
+
+// StartMedicalReminders sends due follow-up reminders now and then every
+// MEDICAL_REMINDER_INTERVAL until ctx is cancelled
+func StartMedicalReminders(ctx context.Context) {
+	interval := envDuration("MEDICAL_REMINDER_INTERVAL", 24*time.Hour)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if sent, err := SendMedicalReminders(ctx); err != nil {
+				log.Printf("Failed to send medical reminders: %v", err)
+			} else if sent > 0 {
+				log.Printf("Sent reminders for %d medical follow-ups", sent)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
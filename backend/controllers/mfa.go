@@ -0,0 +1,374 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kholodihor/cows-shelter-backend/config"
+	"github.com/kholodihor/cows-shelter-backend/models"
+	"github.com/kholodihor/cows-shelter-backend/utils"
+	"gorm.io/gorm"
+)
+
+const recoveryCodeCount = 10
+
+// mfaCodeRequest carries either a TOTP code or a recovery code
+type mfaCodeRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// loginChallenge answers a successful password check with a 2FA challenge
+// when one is needed. It reports whether a response was written.
+func loginChallenge(c *gin.Context, user *models.User) bool {
+	var purpose string
+	switch {
+	case user.TOTPEnabled:
+		purpose = utils.PurposeMFA
+	case config.RequireAdminMFA() && user.Role == models.RoleAdmin:
+		purpose = utils.PurposeMFASetup
+	default:
+		return false
+	}
+
+	challenge, err := utils.GenerateChallengeToken(user.Email, purpose)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating token"})
+		return true
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"mfa_required":       purpose == utils.PurposeMFA,
+		"mfa_setup_required": purpose == utils.PurposeMFASetup,
+		"challenge_token":    challenge,
+	})
+	return true
+}
+
+// verifySecondFactor checks a TOTP code or consumes a recovery code
+func verifySecondFactor(tx *gorm.DB, user *models.User, req mfaCodeRequest) (bool, error) {
+	if req.RecoveryCode != "" {
+		result := tx.Model(&models.RecoveryCode{}).
+			Where("user_id = ? AND code_hash = ? AND used_at IS NULL",
+				user.ID, utils.HashToken(utils.NormalizeRecoveryCode(req.RecoveryCode))).
+			Update("used_at", time.Now())
+		return result.RowsAffected == 1, result.Error
+	}
+
+	step, ok := utils.ValidateTOTP(user.TOTPSecret, req.Code, time.Now())
+	if !ok || step <= user.TOTPLastCounter {
+		// Unknown code, or a code that was already used
+		return false, nil
+	}
+
+	user.TOTPLastCounter = step
+	return true, tx.Model(user).Update("totp_last_counter", step).Error
+}
+
+// replaceRecoveryCodes generates a fresh set of recovery codes for the user
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate recovery codes: %w", err)
+	}
+
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	records := make([]models.RecoveryCode, len(codes))
+	for i, code := range codes {
+		records[i] = models.RecoveryCode{UserID: userID, CodeHash: utils.HashToken(utils.NormalizeRecoveryCode(code))}
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// VerifyMFA - Complete a login by exchanging the challenge token and a code for tokens
+func VerifyMFA(c *gin.Context) {
+	var requestBody struct {
+		ChallengeToken string `json:"challenge_token" binding:"required"`
+		mfaCodeRequest
+	}
+
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	claims, err := utils.ValidateChallengeToken(requestBody.ChallengeToken, utils.PurposeMFA)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge, please log in again"})
+		return
+	}
+
+	// Codes are short, so they share the password lockout
+	ip := c.ClientIP()
+	remaining, err := loginLockedFor(claims.Email, ip)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking login attempts"})
+		return
+	}
+	if remaining > 0 {
+		respondLocked(c, remaining)
+		return
+	}
+
+	var user models.User
+	if err := config.DB.Where("email = ?", claims.Email).First(&user).Error; err != nil || !user.TOTPEnabled || user.DeactivatedAt != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge, please log in again"})
+		return
+	}
+
+	var valid bool
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		valid, err = verifySecondFactor(tx, &user, requestBody.mfaCodeRequest)
+		return err
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verifying code"})
+		return
+	}
+
+	if !valid {
+		if err := recordLoginFailures(user.Email, ip); err != nil {
+			log.Printf("Failed to record login failure: %v", err)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authentication code"})
+		return
+	}
+
+	if err := clearAccountFailures(user.Email); err != nil {
+		log.Printf("Failed to clear login failures: %v", err)
+	}
+
+	tokens, err := issueTokens(c, &user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokenResponse(&user, tokens))
+}
+
+// SetupMFA - Start TOTP enrollment and return the secret and provisioning URI
+func SetupMFA(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	if user.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating secret"})
+		return
+	}
+
+	if err := config.DB.Model(&user).Updates(map[string]interface{}{
+		"totp_secret":       secret,
+		"totp_last_counter": 0,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving secret"})
+		return
+	}
+
+	issuer := config.GetEnv("TOTP_ISSUER", "Cows Shelter")
+	c.JSON(http.StatusOK, gin.H{
+		"secret":           secret,
+		"provisioning_uri": utils.TOTPProvisioningURI(issuer, user.Email, secret),
+	})
+}
+
+// EnableMFA - Confirm enrollment with a first code and receive recovery codes
+func EnableMFA(c *gin.Context) {
+	var requestBody struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+	if user.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+	if user.TOTPSecret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Start enrollment with /api/auth/2fa/setup first"})
+		return
+	}
+
+	var codes []string
+	var valid bool
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		valid, err = verifySecondFactor(tx, &user, mfaCodeRequest{Code: requestBody.Code})
+		if err != nil || !valid {
+			return err
+		}
+
+		if err := tx.Model(&user).Update("totp_enabled", true).Error; err != nil {
+			return err
+		}
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error enabling two-factor authentication"})
+		return
+	}
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid authentication code"})
+		return
+	}
+
+	response := gin.H{"recovery_codes": codes}
+
+	// Enrollment forced at login finishes the login as well
+	if c.GetString("auth_purpose") == utils.PurposeMFASetup {
+		if err := clearAccountFailures(user.Email); err != nil {
+			log.Printf("Failed to clear login failures: %v", err)
+		}
+		tokens, err := issueTokens(c, &user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating token"})
+			return
+		}
+		for key, value := range tokenResponse(&user, tokens) {
+			response[key] = value
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// secondFactorLocked answers the request and returns true while the account or
+// IP is locked, so a stolen access token cannot be used to guess codes
+func secondFactorLocked(c *gin.Context, user *models.User) bool {
+	remaining, err := loginLockedFor(user.Email, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking login attempts"})
+		return true
+	}
+	if remaining > 0 {
+		respondLocked(c, remaining)
+		return true
+	}
+	return false
+}
+
+// recordSecondFactorResult counts a wrong code like a failed login and
+// forgets earlier failures once a code was right
+func recordSecondFactorResult(c *gin.Context, user *models.User, valid bool) {
+	if !valid {
+		if err := recordLoginFailures(user.Email, c.ClientIP()); err != nil {
+			log.Printf("Failed to record login failure: %v", err)
+		}
+		return
+	}
+	if err := clearAccountFailures(user.Email); err != nil {
+		log.Printf("Failed to clear login failures: %v", err)
+	}
+}
+
+// DisableMFA - Turn off two-factor authentication after confirming a code
+func DisableMFA(c *gin.Context) {
+	var requestBody mfaCodeRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+	if !user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+	if config.RequireAdminMFA() && user.Role == models.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication is required for admin accounts"})
+		return
+	}
+	if secondFactorLocked(c, &user) {
+		return
+	}
+
+	var valid bool
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		valid, err = verifySecondFactor(tx, &user, requestBody)
+		if err != nil || !valid {
+			return err
+		}
+
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"totp_enabled":      false,
+			"totp_secret":       "",
+			"totp_last_counter": 0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error disabling two-factor authentication"})
+		return
+	}
+	recordSecondFactorResult(c, &user, valid)
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid authentication code"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes - Replace all recovery codes after confirming a code
+func RegenerateRecoveryCodes(c *gin.Context) {
+	var requestBody struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+	if !user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+	if secondFactorLocked(c, &user) {
+		return
+	}
+
+	var codes []string
+	var valid bool
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		valid, err = verifySecondFactor(tx, &user, mfaCodeRequest{Code: requestBody.Code})
+		if err != nil || !valid {
+			return err
+		}
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating recovery codes"})
+		return
+	}
+	recordSecondFactorResult(c, &user, valid)
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid authentication code"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
@@ -126,6 +126,7 @@ func CreateNews(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create news entry"})
 		return
 	}
+	recordAudit(c, models.AuditCreate, "news", news.ID, nil, news)
 
 	c.JSON(http.StatusCreated, news)
 }
@@ -162,6 +163,7 @@ func UpdateNews(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "News not found"})
 		return
 	}
+	before := news
 
 	var req UpdateNewsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -222,6 +224,7 @@ func UpdateNews(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update news"})
 		return
 	}
+	recordAudit(c, models.AuditUpdate, "news", news.ID, before, news)
 
 	c.JSON(http.StatusOK, &news)
 }
@@ -265,6 +268,7 @@ func DeleteNews(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete news"})
 		return
 	}
+	recordAudit(c, models.AuditDelete, "news", news.ID, news, nil)
 
 	c.JSON(http.StatusOK, gin.H{"message": "News item deleted successfully"})
 }
\ No newline at end of file
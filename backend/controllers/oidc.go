@@ -0,0 +1,467 @@
+package controllers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gin-gonic/gin"
+	"github.com/kholodihor/cows-shelter-backend/config"
+	"github.com/kholodihor/cows-shelter-backend/models"
+	"github.com/kholodihor/cows-shelter-backend/utils"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	oidcStateTTL     = 10 * time.Minute
+	oidcLoginCodeTTL = 2 * time.Minute
+	oidcStateCookie  = "oidc_state"
+)
+
+var (
+	errOIDCDisabled  = errors.New("single sign-on is not configured")
+	errOIDCNoAccount = errors.New("no account for this identity")
+)
+
+// oidcCallbackError is a failed callback step, reported to the frontend as
+// ?sso_error=
+type oidcCallbackError string
+
+func (e oidcCallbackError) Error() string { return string(e) }
+
+const (
+	errOIDCExchange  oidcCallbackError = "exchange_failed"
+	errOIDCNoIDToken oidcCallbackError = "missing_id_token"
+	errOIDCIDToken   oidcCallbackError = "invalid_id_token"
+	errOIDCNonce     oidcCallbackError = "invalid_nonce"
+)
+
+// oidcClient is the discovered provider together with our client settings
+type oidcClient struct {
+	cfg      *config.OIDCConfig
+	oauth2   oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+var (
+	oidcMu     sync.Mutex
+	oidcCached *oidcClient
+)
+
+// oidcContext carries the HTTP client used to talk to the provider. It is not
+// tied to a request because the provider keeps using it to refresh its keys.
+func oidcContext() context.Context {
+	return oidc.ClientContext(context.Background(), &http.Client{Timeout: 10 * time.Second})
+}
+
+// getOIDCClient runs provider discovery on first use. Failures are not cached
+// so a provider that was down at startup is picked up later.
+func getOIDCClient() (*oidcClient, error) {
+	oidcMu.Lock()
+	defer oidcMu.Unlock()
+
+	if oidcCached != nil {
+		return oidcCached, nil
+	}
+
+	cfg, err := config.LoadOIDCConfig()
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		return nil, errOIDCDisabled
+	}
+
+	client, err := newOIDCClient(oidcContext(), cfg)
+	if err != nil {
+		return nil, err
+	}
+	oidcCached = client
+	return oidcCached, nil
+}
+
+// newOIDCClient discovers the provider at cfg.IssuerURL. ctx carries the HTTP
+// client used for discovery and, later, for fetching the provider's keys.
+func newOIDCClient(ctx context.Context, cfg *config.OIDCConfig) (*oidcClient, error) {
+	provider, err := oidc.NewProvider(ctx, cfg.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("oidc discovery failed: %w", err)
+	}
+
+	return &oidcClient{
+		cfg: cfg,
+		oauth2: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       append([]string{oidc.ScopeOpenID}, cfg.Scopes...),
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+	}, nil
+}
+
+// verifyCallback exchanges an authorization code with the PKCE verifier of
+// the login attempt and checks the returned ID token: its signature, issuer,
+// audience and expiry, and that it carries the attempt's nonce
+func (o *oidcClient) verifyCallback(ctx context.Context, code, verifier, nonce string) (*oidc.IDToken, map[string]interface{}, error) {
+	token, err := o.oauth2.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		log.Printf("OIDC code exchange failed: %v", err)
+		return nil, nil, errOIDCExchange
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, nil, errOIDCNoIDToken
+	}
+	idToken, err := o.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		log.Printf("OIDC ID token rejected: %v", err)
+		return nil, nil, errOIDCIDToken
+	}
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(nonce)) != 1 {
+		return nil, nil, errOIDCNonce
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, nil, errOIDCIDToken
+	}
+	return idToken, claims, nil
+}
+
+// setOIDCStateCookie binds a login attempt to the browser that started it.
+// The callback only accepts a state that matches this cookie, so nobody can
+// complete their own login in someone else's browser (login CSRF).
+func setOIDCStateCookie(c *gin.Context, state string, secure bool) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/api/auth/oidc",
+		MaxAge:   int(oidcStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// oidcStateMatches reports whether the callback state is the one stored in
+// this browser's cookie, and clears the cookie as it is single-use
+func oidcStateMatches(c *gin.Context, state string, secure bool) bool {
+	cookie, err := c.Cookie(oidcStateCookie)
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Path:     "/api/auth/oidc",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return err == nil && state != "" && subtle.ConstantTimeCompare([]byte(cookie), []byte(state)) == 1
+}
+
+// respondOIDCUnavailable answers when single sign-on cannot be used
+func respondOIDCUnavailable(c *gin.Context, err error) {
+	if errors.Is(err, errOIDCDisabled) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Single sign-on is not configured"})
+		return
+	}
+	log.Printf("OIDC unavailable: %v", err)
+	c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Identity provider is unavailable"})
+}
+
+// oidcRoleRank orders roles so the most privileged mapped role wins
+var oidcRoleRank = map[string]int{
+	models.RoleViewer: 1,
+	models.RoleEditor: 2,
+	models.RoleAdmin:  3,
+}
+
+// mappedRole returns the role granted by the configured claim, or "" when no
+// claim value is mapped. The claim may be a single string or a list.
+func mappedRole(cfg *config.OIDCConfig, claim interface{}) string {
+	var values []string
+	switch v := claim.(type) {
+	case string:
+		values = []string{v}
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	role := ""
+	for _, value := range values {
+		if mapped, ok := cfg.RoleMap[value]; ok && oidcRoleRank[mapped] > oidcRoleRank[role] {
+			role = mapped
+		}
+	}
+	return role
+}
+
+// oidcUser finds or provisions the local user for a verified identity. Known
+// identities are matched by issuer and subject, new ones are linked to an
+// existing user by verified email, and otherwise a user is created when the
+// claims map to a role or a default role is configured.
+func oidcUser(c *gin.Context, cfg *config.OIDCConfig, issuer, subject string, claims map[string]interface{}) (*models.User, error) {
+	email, _ := claims["email"].(string)
+	emailVerified, _ := claims["email_verified"].(bool)
+	name, _ := claims["name"].(string)
+	role := mappedRole(cfg, claims[cfg.RoleClaim])
+
+	var user models.User
+	var identity models.UserIdentity
+	err := config.DB.Where("issuer = ? AND subject = ?", issuer, subject).First(&identity).Error
+	switch {
+	case err == nil:
+		if err := config.DB.First(&user, identity.UserID).Error; err != nil {
+			return nil, err
+		}
+
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if email == "" || !emailVerified {
+			return nil, errOIDCNoAccount
+		}
+
+		err := config.DB.Where("LOWER(email) = LOWER(?)", email).First(&user).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if role == "" {
+				role = cfg.DefaultRole
+			}
+			if role == "" {
+				return nil, errOIDCNoAccount
+			}
+
+			password, err := unusablePassword()
+			if err != nil {
+				return nil, err
+			}
+			user = models.User{Email: email, Name: name, Password: password, Role: role}
+			if err := config.DB.Create(&user).Error; err != nil {
+				return nil, err
+			}
+			recordAudit(c, models.AuditCreate, "user", user.ID, nil, user)
+		} else if err != nil {
+			return nil, err
+		}
+
+		identity = models.UserIdentity{UserID: user.ID, Issuer: issuer, Subject: subject}
+
+	default:
+		return nil, err
+	}
+
+	identity.Email = email
+	identity.LastLoginAt = time.Now()
+	if err := config.DB.Save(&identity).Error; err != nil {
+		return nil, err
+	}
+
+	// Keep the local role in step with the provider when the claims map to one
+	if role != "" && role != user.Role {
+		before := user
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			if user.Role == models.RoleAdmin && user.DeactivatedAt == nil {
+				if err := ensureOtherActiveAdmin(tx, user.ID); err != nil {
+					return err
+				}
+			}
+			return tx.Model(&user).Update("role", role).Error
+		})
+		switch {
+		case errors.Is(err, errLastAdmin):
+			log.Printf("Not demoting user %d: last active admin", user.ID)
+		case err != nil:
+			return nil, err
+		default:
+			user.Role = role
+			recordAudit(c, models.AuditUpdate, "user", user.ID, before, user)
+		}
+	}
+
+	return &user, nil
+}
+
+// OIDCLogin - Start a single sign-on login by redirecting to the identity provider
+func OIDCLogin(c *gin.Context) {
+	client, err := getOIDCClient()
+	if err != nil {
+		respondOIDCUnavailable(c, err)
+		return
+	}
+
+	state, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error starting login"})
+		return
+	}
+	nonce, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error starting login"})
+		return
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	now := time.Now()
+	// Abandoned attempts are cleaned up as new ones start
+	config.DB.Where("expires_at < ?", now).Delete(&models.OIDCLogin{})
+
+	attempt := models.OIDCLogin{
+		StateHash:    utils.HashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    now.Add(oidcStateTTL),
+	}
+	if err := config.DB.Create(&attempt).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error starting login"})
+		return
+	}
+
+	setOIDCStateCookie(c, state, strings.HasPrefix(client.cfg.RedirectURL, "https://"))
+	c.Redirect(http.StatusFound, client.oauth2.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)))
+}
+
+// OIDCCallback - Handle the provider redirect, verify the ID token and send the
+// browser back to the frontend with a one-time login code
+func OIDCCallback(c *gin.Context) {
+	frontendURL := config.GetEnv("FRONTEND_URL", "http://localhost:5173")
+	fail := func(reason string) {
+		c.Redirect(http.StatusFound, frontendURL+"/login?sso_error="+url.QueryEscape(reason))
+	}
+
+	client, err := getOIDCClient()
+	if err != nil {
+		respondOIDCUnavailable(c, err)
+		return
+	}
+
+	// The state must come back to the browser that started the login
+	state := c.Query("state")
+	if !oidcStateMatches(c, state, strings.HasPrefix(client.cfg.RedirectURL, "https://")) {
+		fail("invalid_state")
+		return
+	}
+
+	if providerError := c.Query("error"); providerError != "" {
+		fail(providerError)
+		return
+	}
+
+	var attempt models.OIDCLogin
+	if err := config.DB.Where("state_hash = ? AND user_id IS NULL AND expires_at > ?",
+		utils.HashToken(state), time.Now()).First(&attempt).Error; err != nil {
+		fail("invalid_state")
+		return
+	}
+
+	idToken, claims, err := client.verifyCallback(oidcContext(), c.Query("code"), attempt.CodeVerifier, attempt.Nonce)
+	if err != nil {
+		fail(err.Error())
+		return
+	}
+
+	user, err := oidcUser(c, client.cfg, idToken.Issuer, idToken.Subject, claims)
+	if errors.Is(err, errOIDCNoAccount) {
+		fail("no_account")
+		return
+	}
+	if err != nil {
+		log.Printf("OIDC user lookup failed: %v", err)
+		fail("server_error")
+		return
+	}
+	if user.DeactivatedAt != nil {
+		fail("account_deactivated")
+		return
+	}
+
+	// Tokens are not put in the URL; the frontend exchanges this code for them
+	loginCode, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		fail("server_error")
+		return
+	}
+	codeHash := utils.HashToken(loginCode)
+	result := config.DB.Model(&attempt).Where("user_id IS NULL").Updates(map[string]interface{}{
+		"user_id":         user.ID,
+		"login_code_hash": codeHash,
+		"expires_at":      time.Now().Add(oidcLoginCodeTTL),
+	})
+	if result.Error != nil || result.RowsAffected == 0 {
+		fail("invalid_state")
+		return
+	}
+
+	c.Redirect(http.StatusFound, frontendURL+"/login/sso?code="+url.QueryEscape(loginCode))
+}
+
+// OIDCExchange - Exchange the one-time login code from the callback for tokens
+func OIDCExchange(c *gin.Context) {
+	var requestBody struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	var user models.User
+	errInvalidCode := errors.New("invalid login code")
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var attempt models.OIDCLogin
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("login_code_hash = ? AND expires_at > ?", utils.HashToken(requestBody.Code), time.Now()).
+			First(&attempt).Error; err != nil {
+			return errInvalidCode
+		}
+
+		// The code is single-use
+		if err := tx.Delete(&attempt).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&user, *attempt.UserID).Error; err != nil {
+			return errInvalidCode
+		}
+		return nil
+	})
+	if errors.Is(err, errInvalidCode) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login code is invalid or expired"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error completing login"})
+		return
+	}
+
+	if user.DeactivatedAt != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is deactivated"})
+		return
+	}
+
+	// Local two-factor rules apply to single sign-on as well
+	if loginChallenge(c, &user) {
+		return
+	}
+
+	tokens, err := issueTokens(c, &user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokenResponse(&user, tokens))
+}
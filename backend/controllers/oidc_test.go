@@ -0,0 +1,331 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kholodihor/cows-shelter-backend/config"
+	"golang.org/x/oauth2"
+)
+
+const (
+	stubClientID    = "cows-shelter"
+	stubRedirectURL = "https://api.example.test/api/auth/oidc/callback"
+)
+
+// stubGrant is what the stub provider remembers about an issued code
+type stubGrant struct {
+	challenge string
+	nonce     string
+}
+
+// stubProvider is a minimal OpenID Connect provider: discovery, a JWKS with
+// one RSA key, an authorization endpoint enforcing S256 PKCE and a token
+// endpoint that checks the verifier before issuing a signed ID token
+type stubProvider struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu     sync.Mutex
+	grants map[string]stubGrant
+	// claims lets a test change the ID token before it is signed
+	claims func(jwt.MapClaims)
+}
+
+func newStubProvider(t *testing.T) *stubProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &stubProvider{t: t, key: key, grants: map[string]stubGrant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/jwks", p.jwks)
+	mux.HandleFunc("/authorize", p.authorize)
+	mux.HandleFunc("/token", p.token)
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *stubProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeStubJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.server.URL,
+		"authorization_endpoint":                p.server.URL + "/authorize",
+		"token_endpoint":                        p.server.URL + "/token",
+		"jwks_uri":                              p.server.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *stubProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	writeStubJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "stub",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (p *stubProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("response_type") != "code" || q.Get("client_id") != stubClientID ||
+		q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+
+	code := "code-" + q.Get("state")
+	p.mu.Lock()
+	p.grants[code] = stubGrant{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	p.mu.Unlock()
+
+	redirect, _ := url.Parse(q.Get("redirect_uri"))
+	values := redirect.Query()
+	values.Set("code", code)
+	values.Set("state", q.Get("state"))
+	redirect.RawQuery = values.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (p *stubProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeStubJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	p.mu.Lock()
+	grant, ok := p.grants[r.PostForm.Get("code")]
+	delete(p.grants, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge {
+		writeStubJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            p.server.URL,
+		"sub":            "user-42",
+		"aud":            stubClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          grant.nonce,
+		"email":          "editor@example.com",
+		"email_verified": true,
+		"groups":         []string{"shelter-editors"},
+	}
+	if p.claims != nil {
+		p.claims(claims)
+	}
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = "stub"
+	signed, err := idToken.SignedString(p.key)
+	if err != nil {
+		p.t.Errorf("signing ID token: %v", err)
+		writeStubJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeStubJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "stub-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     signed,
+	})
+}
+
+func writeStubJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// stubLogin discovers the stub provider and goes through its authorization
+// endpoint the way a browser would, returning the client, the code and the
+// PKCE verifier and nonce of the attempt
+func stubLogin(t *testing.T, p *stubProvider) (context.Context, *oidcClient, string, string, string) {
+	t.Helper()
+	ctx := oidc.ClientContext(context.Background(), p.server.Client())
+	client, err := newOIDCClient(ctx, &config.OIDCConfig{
+		IssuerURL:    p.server.URL,
+		ClientID:     stubClientID,
+		ClientSecret: "stub-secret",
+		RedirectURL:  stubRedirectURL,
+		Scopes:       []string{"email", "profile"},
+	})
+	if err != nil {
+		t.Fatalf("discovery: %v", err)
+	}
+
+	verifier := oauth2.GenerateVerifier()
+	nonce := "nonce-" + t.Name()
+	authURL := client.oauth2.AuthCodeURL("state-1", oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+
+	browser := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := browser.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize: status %d", resp.StatusCode)
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(location.String(), stubRedirectURL) || location.Query().Get("state") != "state-1" {
+		t.Fatalf("unexpected redirect %s", location)
+	}
+	return ctx, client, location.Query().Get("code"), verifier, nonce
+}
+
+func TestOIDCStubProviderLogin(t *testing.T) {
+	p := newStubProvider(t)
+	ctx, client, code, verifier, nonce := stubLogin(t, p)
+
+	idToken, claims, err := client.verifyCallback(ctx, code, verifier, nonce)
+	if err != nil {
+		t.Fatalf("verifyCallback: %v", err)
+	}
+	if idToken.Issuer != p.server.URL || idToken.Subject != "user-42" {
+		t.Errorf("got issuer %q subject %q", idToken.Issuer, idToken.Subject)
+	}
+	if claims["email"] != "editor@example.com" || claims["email_verified"] != true {
+		t.Errorf("unexpected claims %v", claims)
+	}
+
+	cfg := &config.OIDCConfig{RoleMap: map[string]string{"shelter-editors": "editor"}}
+	if role := mappedRole(cfg, claims["groups"]); role != "editor" {
+		t.Errorf("mapped role %q, want editor", role)
+	}
+}
+
+func TestOIDCStubProviderRejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		verifier func(string) string
+		nonce    func(string) string
+		claims   func(jwt.MapClaims)
+		want     error
+	}{
+		{
+			name:     "wrong PKCE verifier",
+			verifier: func(string) string { return oauth2.GenerateVerifier() },
+			want:     errOIDCExchange,
+		},
+		{
+			name:  "nonce of another attempt",
+			nonce: func(string) string { return "other-nonce" },
+			want:  errOIDCNonce,
+		},
+		{
+			name:   "token for another client",
+			claims: func(c jwt.MapClaims) { c["aud"] = "someone-else" },
+			want:   errOIDCIDToken,
+		},
+		{
+			name:   "token from another issuer",
+			claims: func(c jwt.MapClaims) { c["iss"] = "https://evil.example.test" },
+			want:   errOIDCIDToken,
+		},
+		{
+			name:   "expired token",
+			claims: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() },
+			want:   errOIDCIDToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newStubProvider(t)
+			p.claims = tt.claims
+			ctx, client, code, verifier, nonce := stubLogin(t, p)
+			if tt.verifier != nil {
+				verifier = tt.verifier(verifier)
+			}
+			if tt.nonce != nil {
+				nonce = tt.nonce(nonce)
+			}
+
+			if _, _, err := client.verifyCallback(ctx, code, verifier, nonce); !errors.Is(err, tt.want) {
+				t.Errorf("got error %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOIDCStateCookie(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login", nil)
+	setOIDCStateCookie(c, "state-1", true)
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("got %d cookies, want 1", len(cookies))
+	}
+	cookie := cookies[0]
+	if cookie.Name != oidcStateCookie || cookie.Value != "state-1" || !cookie.HttpOnly || !cookie.Secure ||
+		cookie.SameSite != http.SameSiteLaxMode || cookie.Path != "/api/auth/oidc" {
+		t.Errorf("unexpected cookie %+v", cookie)
+	}
+
+	callback := func(state string, cookie *http.Cookie) (bool, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback?state="+url.QueryEscape(state), nil)
+		if cookie != nil {
+			c.Request.AddCookie(cookie)
+		}
+		return oidcStateMatches(c, state, true), w
+	}
+
+	ok, w := callback("state-1", cookie)
+	if !ok {
+		t.Error("matching state was rejected")
+	}
+	if cleared := w.Result().Cookies(); len(cleared) != 1 || cleared[0].MaxAge >= 0 {
+		t.Errorf("state cookie was not cleared: %+v", cleared)
+	}
+	if ok, _ := callback("state-2", cookie); ok {
+		t.Error("state from another login was accepted")
+	}
+	if ok, _ := callback("state-1", nil); ok {
+		t.Error("state without the browser cookie was accepted")
+	}
+	if ok, _ := callback("", &http.Cookie{Name: oidcStateCookie, Value: ""}); ok {
+		t.Error("empty state was accepted")
+	}
+}
@@ -124,6 +124,7 @@ func CreatePartner(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create partner"})
 		return
 	}
+	recordAudit(c, models.AuditCreate, "partner", partner.ID, nil, partner)
 
 	c.JSON(http.StatusCreated, &partner)
 }
@@ -149,6 +150,7 @@ func UpdatePartner(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Partner not found"})
 		return
 	}
+	before := partner
 
 	// Parse the request body
 	var req UpdatePartnerRequest
@@ -198,6 +200,7 @@ func UpdatePartner(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update partner"})
 		return
 	}
+	recordAudit(c, models.AuditUpdate, "partner", partner.ID, before, partner)
 
 	c.JSON(http.StatusOK, &partner)
 }
@@ -231,6 +234,7 @@ func DeletePartner(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete partner"})
 		return
 	}
+	recordAudit(c, models.AuditDelete, "partner", partner.ID, partner, nil)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Partner deleted successfully"})
 }
\ No newline at end of file
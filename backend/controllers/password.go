@@ -1,17 +1,136 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"github.com/kholodihor/cows-shelter-backend/config"
+	"github.com/kholodihor/cows-shelter-backend/mailer"
 	"github.com/kholodihor/cows-shelter-backend/models"
+	"github.com/kholodihor/cows-shelter-backend/utils"
+	"gorm.io/gorm"
 )
 
-// CreatePassword - Create a new password reset token
-func CreatePassword(c *gin.Context) {
+// passwordResetTTL returns how long a password reset token stays valid
+func passwordResetTTL() time.Duration {
+	ttl, err := time.ParseDuration(config.GetEnv("PASSWORD_RESET_TTL", "1h"))
+	if err != nil || ttl <= 0 {
+		return time.Hour
+	}
+	return ttl
+}
+
+// setUserPassword stores a new bcrypt password for the user and invalidates
+// every outstanding reset token and every session issued before the change.
+func setUserPassword(tx *gorm.DB, user *models.User, password string) error {
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	now := time.Now()
+	user.Password = hashedPassword
+	user.PasswordChangedAt = &now
+	if err := tx.Save(user).Error; err != nil {
+		return fmt.Errorf("failed to save password: %w", err)
+	}
+
+	if err := tx.Model(&models.Password{}).
+		Where("user_id = ? AND used_at IS NULL", user.ID).
+		Update("used_at", now).Error; err != nil {
+		return fmt.Errorf("failed to invalidate reset tokens: %w", err)
+	}
+
+	if err := revokeUserRefreshTokens(tx, user.ID); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	return nil
+}
+
+// resetEmail is the wording of an email carrying a password link
+type resetEmail struct {
+	Subject string
+	Intro   string
+	TTL     func() time.Duration // how long the link works, PASSWORD_RESET_TTL if nil
+}
+
+var (
+	forgotPasswordEmail = resetEmail{
+		Subject: "Cows Shelter password reset",
+		Intro:   "A password reset was requested for your account.",
+	}
+	forcedResetEmail = resetEmail{
+		Subject: "Cows Shelter password reset required",
+		Intro:   "An administrator has reset your password. Choose a new one to log in again.",
+	}
+	accountCreatedEmail = resetEmail{
+		Subject: "Your Cows Shelter account",
+		Intro:   "An administrator has created an account for you in the Cows Shelter admin panel.",
+		TTL:     invitationTTL,
+	}
+)
+
+// issuePasswordReset creates a new reset token for the user, replacing any
+// previous ones, and emails the link using the given wording.
+func issuePasswordReset(c *gin.Context, user *models.User, email resetEmail) error {
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	now := time.Now()
+	ttl := passwordResetTTL()
+	if email.TTL != nil {
+		ttl = email.TTL()
+	}
+	reset := models.Password{
+		UserID:    user.ID,
+		Email:     user.Email,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: now.Add(ttl),
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Only the most recent link should work
+		if err := tx.Model(&models.Password{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(&reset).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store reset token: %w", err)
+	}
+
+	link := fmt.Sprintf("%s/reset/%s", config.GetEnv("FRONTEND_URL", "http://localhost:5173"), token)
+	return config.Mailer.Send(c.Request.Context(), mailer.Message{
+		To:      user.Email,
+		Subject: email.Subject,
+		Body: fmt.Sprintf("%s\n\n"+
+			"Open the link below to choose a new password. It expires in %s and can be used once.\n\n%s\n\n"+
+			"If you did not expect this email, you can ignore it.\n", email.Intro, ttl, link),
+	})
+}
+
+// findValidReset returns the unused, unexpired reset entry for a plain token
+func findValidReset(db *gorm.DB, token string) (*models.Password, error) {
+	var reset models.Password
+	err := db.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", utils.HashToken(token), time.Now()).
+		First(&reset).Error
+	if err != nil {
+		return nil, err
+	}
+	return &reset, nil
+}
+
+// ForgotPassword - Send a password reset link to the given email
+func ForgotPassword(c *gin.Context) {
 	var requestBody struct {
 		Email string `json:"email" binding:"required,email"`
 	}
@@ -22,36 +141,152 @@ func CreatePassword(c *gin.Context) {
 		return
 	}
 
-	// Generate a unique token
-	token := uuid.New().String()
+	// Always answer the same way so the endpoint cannot be used to probe emails
+	response := gin.H{"message": "If an account with that email exists, a reset link has been sent"}
+
+	// Both limits apply to every email, known or not, so they reveal nothing
+	email := normalizeEmail(requestBody.Email)
+	for _, limit := range []struct {
+		kind, subject string
+		max           int
+	}{
+		{models.ThrottlePasswordResetIP, c.ClientIP(), envInt("PASSWORD_RESETS_PER_IP", 5)},
+		{models.ThrottlePasswordResetEmail, email, envInt("PASSWORD_RESETS_PER_EMAIL", 3)},
+	} {
+		wait, err := allowPublicRequest(limit.kind, limit.subject, limit.max, publicFormWindow())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error looking up user"})
+			return
+		}
+		if wait > 0 {
+			respondThrottled(c, wait)
+			return
+		}
+	}
+
+	var user models.User
+	if err := config.DB.Where("LOWER(email) = ?", email).First(&user).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error looking up user"})
+			return
+		}
+		c.JSON(http.StatusOK, response)
+		return
+	}
 
-	// Create the new Password entry
-	passwordReset := models.Password{
-		Email: requestBody.Email,
-		Token: token,
+	// Deactivated accounts cannot recover access on their own
+	if user.DeactivatedAt != nil {
+		c.JSON(http.StatusOK, response)
+		return
 	}
 
-	if err := config.DB.Create(&passwordReset).Error; err != nil {
+	if err := issuePasswordReset(c, &user, forgotPasswordEmail); err != nil {
+		log.Printf("Password reset for user %d failed: %v", user.ID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating password reset token"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"email": passwordReset.Email,
-		"token": passwordReset.Token,
-		"created_at": time.Now(),
-	})
+	c.JSON(http.StatusOK, response)
 }
 
-// GetPasswordByToken - Retrieve a password reset entry by token
+// GetPasswordByToken - Check whether a password reset token is still usable
 func GetPasswordByToken(c *gin.Context) {
-	token := c.Param("token")
+	reset, err := findValidReset(config.DB, c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Password reset token is invalid or expired"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"valid":      true,
+		"expires_at": reset.ExpiresAt,
+	})
+}
+
+// ResetPassword - Consume a reset token and set a new password
+func ResetPassword(c *gin.Context) {
+	var requestBody struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required,min=8"`
+	}
+
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	errInvalidToken := errors.New("invalid token")
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		reset, err := findValidReset(tx, requestBody.Token)
+		if err != nil {
+			return errInvalidToken
+		}
+
+		// Claim the token first so concurrent requests cannot both use it
+		result := tx.Model(reset).Where("used_at IS NULL").Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errInvalidToken
+		}
+
+		var user models.User
+		if err := tx.First(&user, reset.UserID).Error; err != nil || user.DeactivatedAt != nil {
+			return errInvalidToken
+		}
+
+		return setUserPassword(tx, &user, requestBody.Password)
+	})
+
+	if errors.Is(err, errInvalidToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password reset token is invalid or expired"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error resetting password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset"})
+}
+
+// ChangePassword - Change the password of the authenticated user
+func ChangePassword(c *gin.Context) {
+	var requestBody struct {
+		CurrentPassword string `json:"current_password" binding:"required"`
+		Password        string `json:"password" binding:"required,min=8"`
+	}
+
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	// A stolen access token alone must not be enough to take over the account
+	if !utils.CheckPasswordHash(requestBody.CurrentPassword, user.Password) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return setUserPassword(tx, &user, requestBody.Password)
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error changing password"})
+		return
+	}
 
-	var passwordReset models.Password
-	if err := config.DB.Where("token = ?", token).First(&passwordReset).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Password reset token not found"})
+	// Older tokens are now revoked, so hand out a fresh pair
+	tokens, err := issueTokens(c, &user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, &passwordReset)
+	response := tokenResponse(&user, tokens)
+	response["email"] = user.Email
+	response["role"] = user.Role
+	c.JSON(http.StatusOK, response)
 }
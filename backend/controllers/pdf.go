@@ -79,6 +79,7 @@ func CreatePdf(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create PDF"})
 		return
 	}
+	recordAudit(c, models.AuditCreate, "pdf", pdf.ID, nil, pdf)
 
 	c.JSON(http.StatusCreated, &pdf)
 }
@@ -110,6 +111,10 @@ func DeletePdf(c *gin.Context) {
 	}
 
 	// Delete the PDF from the database
-	config.DB.Delete(&pdf)
+	if err := config.DB.Delete(&pdf).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete PDF"})
+		return
+	}
+	recordAudit(c, models.AuditDelete, "pdf", pdf.ID, pdf, nil)
 	c.JSON(http.StatusOK, gin.H{"message": "PDF deleted successfully"})
 }
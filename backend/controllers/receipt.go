@@ -0,0 +1,503 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kholodihor/cows-shelter-backend/config"
+	"github.com/kholodihor/cows-shelter-backend/mailer"
+	"github.com/kholodihor/cows-shelter-backend/middleware"
+	"github.com/kholodihor/cows-shelter-backend/models"
+	"github.com/kholodihor/cows-shelter-backend/payments"
+	"github.com/kholodihor/cows-shelter-backend/storage"
+	"github.com/kholodihor/cows-shelter-backend/utils"
+	"gorm.io/gorm"
+)
+
+// receiptFolder is the storage folder of receipt PDFs. It is under the
+// private prefix because receipts show donors' tax codes and addresses.
+const receiptFolder = storage.PrivatePrefix + "receipts"
+
+// receiptSerialPrefix starts every receipt serial number
+const receiptSerialPrefix = "CS"
+
+// receiptLockKey namespaces the advisory lock that serializes the allocation
+// of receipt numbers within a year
+const receiptLockKey = 7303
+
+// errReceiptExists reports that a donation already has a valid receipt
+var errReceiptExists = errors.New("donation already has a receipt")
+
+// receiptFontDirs are searched for the DejaVu fonts when RECEIPT_FONT and
+// RECEIPT_FONT_BOLD are not set (Debian, Alpine and Arch locations)
+var receiptFontDirs = []string{
+	"/usr/share/fonts/truetype/dejavu",
+	"/usr/share/fonts/dejavu",
+	"/usr/share/fonts/TTF",
+}
+
+// receiptMethods names the donation sources on receipts
+var receiptMethods = map[string]string{
+	models.SourcePrivatbank:   "PrivatBank",
+	models.SourceMonobank:     "Monobank",
+	models.SourcePayPal:       "PayPal",
+	models.SourceWesternUnion: "Western Union",
+	models.SourceSwift:        "SWIFT transfer / SWIFT-переказ",
+	models.SourceCash:         "Cash / Готівка",
+	models.SourceOther:        "Other / Інше",
+}
+
+// receiptFont reads the font file named by env, or finds file in the
+// standard font directories
+func receiptFont(env, file string) ([]byte, error) {
+	if path := config.GetEnv(env, ""); path != "" {
+		return os.ReadFile(path)
+	}
+	for _, dir := range receiptFontDirs {
+		if data, err := os.ReadFile(filepath.Join(dir, file)); err == nil {
+			return data, nil
+		}
+	}
+	return nil, fmt.Errorf("font %s not found, install DejaVu fonts or set %s", file, env)
+}
+
+// loadPDFFonts reads the regular and bold fonts of receipts and reports
+func loadPDFFonts() (utils.PDFFonts, error) {
+	var fonts utils.PDFFonts
+	var err error
+	if fonts.Regular, err = receiptFont("RECEIPT_FONT", "DejaVuSans.ttf"); err != nil {
+		return fonts, err
+	}
+	fonts.Bold, err = receiptFont("RECEIPT_FONT_BOLD", "DejaVuSans-Bold.ttf")
+	return fonts, err
+}
+
+// shelterParty returns the shelter details printed on receipts
+func shelterParty() utils.ReceiptParty {
+	return utils.ReceiptParty{
+		Name:    config.GetEnv("SHELTER_NAME", config.GetEnv("DONATION_RECIPIENT_NAME", "Cows Shelter")),
+		Code:    config.GetEnv("DONATION_RECIPIENT_CODE", ""),
+		Address: config.GetEnv("SHELTER_ADDRESS", ""),
+		Email:   config.GetEnv("SHELTER_EMAIL", ""),
+	}
+}
+
+// renderReceipt typesets the receipt of a donation and uploads it under a
+// new object key, which it returns
+func renderReceipt(ctx context.Context, store storage.Service, receipt *models.Receipt, donation *models.Donation) (string, error) {
+	fonts, err := loadPDFFonts()
+	if err != nil {
+		return "", err
+	}
+
+	loc := config.ShelterLocation()
+	document := utils.Receipt{
+		Serial:   receipt.Serial,
+		IssuedAt: receipt.IssuedAt.In(loc),
+		Shelter:  shelterParty(),
+		Donor: utils.ReceiptParty{
+			Name:    receipt.DonorName,
+			Code:    receipt.DonorTaxCode,
+			Address: receipt.DonorAddress,
+			Email:   donation.DonorEmail,
+		},
+		Amount:     payments.FormatAmount(donation.Amount, donation.Currency),
+		Currency:   donation.Currency,
+		ReceivedAt: donation.ReceivedAt.In(loc),
+		Method:     receiptMethods[donation.Source],
+		PurposeEn:  "Charitable donation to support the shelter",
+		PurposeUa:  "Благодійний внесок на підтримку притулку",
+		VoidReason: receipt.VoidReason,
+	}
+	if donation.ExternalID != nil {
+		document.Reference = *donation.ExternalID
+	}
+	if donation.Campaign != nil {
+		document.PurposeEn = "Donation to the campaign: " + donation.Campaign.TitleEn
+		document.PurposeUa = "Внесок на збір: " + donation.Campaign.TitleUa
+	}
+	if receipt.VoidedAt != nil {
+		voidedAt := receipt.VoidedAt.In(loc)
+		document.VoidedAt = &voidedAt
+	}
+
+	data, err := utils.RenderReceipt(document, fonts)
+	if err != nil {
+		return "", err
+	}
+	key := fmt.Sprintf("%s/%s-%d.pdf", receiptFolder, receipt.Serial, time.Now().UnixNano())
+	if _, err := store.UploadBytes(ctx, key, data, "application/pdf"); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// replaceReceiptDocument renders a receipt again and saves its new object key,
+// deleting the previous document
+func replaceReceiptDocument(c *gin.Context, receipt *models.Receipt) error {
+	store := middleware.GetStorage(c.Request.Context())
+	if store == nil {
+		return errors.New("storage service not available")
+	}
+	var donation models.Donation
+	if err := config.DB.Unscoped().Preload("Campaign").Where("id = ?", receipt.DonationID).First(&donation).Error; err != nil {
+		return err
+	}
+
+	previous := receipt.ObjectKey
+	key, err := renderReceipt(c.Request.Context(), store, receipt, &donation)
+	if err != nil {
+		return err
+	}
+	receipt.ObjectKey = key
+	if err := config.DB.Omit("Donation").Save(receipt).Error; err != nil {
+		_ = store.DeleteFile(c.Request.Context(), key)
+		return err
+	}
+	if previous != "" {
+		if err := store.DeleteFile(c.Request.Context(), previous); err != nil {
+			log.Printf("Failed to delete previous document of receipt %s: %v", receipt.Serial, err)
+		}
+	}
+	return nil
+}
+
+// sendReceiptEmail issues a new secret link to the receipt, replacing any
+// earlier one, and emails it to the donor. The link keeps working when the
+// receipt is regenerated or voided.
+func sendReceiptEmail(c *gin.Context, receipt *models.Receipt, email string) error {
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return err
+	}
+	if err := config.DB.Model(receipt).Update("access_token_hash", utils.HashToken(token)).Error; err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/api/receipts/%s", config.GetEnv("FRONTEND_URL", "http://localhost:5173"), token)
+	shelter := shelterParty().Name
+	return config.Mailer.Send(c.Request.Context(), mailer.Message{
+		To:      email,
+		Subject: fmt.Sprintf("Your donation receipt %s / Квитанція %s", receipt.Serial, receipt.Serial),
+		Body: fmt.Sprintf("Thank you for supporting %s! Your donation receipt is available here:\n\n%s\n\n"+
+			"Дякуємо за підтримку! Квитанцію про ваш благодійний внесок можна завантажити за посиланням вище.\n",
+			shelter, link),
+	})
+}
+
+// sendReceiptDocument streams a receipt's PDF to the client
+func sendReceiptDocument(c *gin.Context, receipt *models.Receipt) {
+	store := middleware.GetStorage(c.Request.Context())
+	if store == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Storage service not available"})
+		return
+	}
+
+	body, _, err := store.GetObject(c.Request.Context(), receipt.ObjectKey)
+	if err != nil {
+		log.Printf("Reading receipt %s failed: %v", receipt.Serial, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read receipt"})
+		return
+	}
+	defer body.Close()
+
+	c.DataFromReader(http.StatusOK, -1, "application/pdf", body, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("inline", map[string]string{"filename": receipt.Serial + ".pdf"}),
+		"Cache-Control":       "private, no-store",
+	})
+}
+
+// findReceipt loads the receipt named by the :id parameter
+func findReceipt(c *gin.Context) (*models.Receipt, bool) {
+	var receipt models.Receipt
+	if err := config.DB.Preload("Donation").Where("id = ?", c.Param("id")).First(&receipt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Receipt not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching receipt"})
+		return nil, false
+	}
+	return &receipt, true
+}
+
+// receiptDetailsRequest holds the donor details printed on a receipt;
+// omitted fields keep their value
+type receiptDetailsRequest struct {
+	DonorName    *string `json:"donor_name"`
+	DonorTaxCode *string `json:"donor_tax_code"`
+	DonorAddress *string `json:"donor_address"`
+	SendEmail    bool    `json:"send_email"` // email the receipt to the donor
+}
+
+// apply copies the given donor details to a receipt
+func (r *receiptDetailsRequest) apply(receipt *models.Receipt) {
+	if r.DonorName != nil {
+		receipt.DonorName = strings.TrimSpace(*r.DonorName)
+	}
+	if r.DonorTaxCode != nil {
+		receipt.DonorTaxCode = strings.TrimSpace(*r.DonorTaxCode)
+	}
+	if r.DonorAddress != nil {
+		receipt.DonorAddress = strings.TrimSpace(*r.DonorAddress)
+	}
+}
+
+// IssueReceipt - Issue a PDF receipt for a completed donation. The donor name
+// defaults to the one on the donation; donor_tax_code and donor_address can
+// be added for donors who need them, and send_email emails the receipt link
+// to the donor. A donation has at most one valid receipt at a time.
+func IssueReceipt(c *gin.Context) {
+	donation, ok := findDonation(c)
+	if !ok {
+		return
+	}
+	var requestBody receiptDetailsRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+	if donation.Status != models.DonationCompleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Receipts can only be issued for completed donations"})
+		return
+	}
+	store := middleware.GetStorage(c.Request.Context())
+	if store == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Storage service not available"})
+		return
+	}
+
+	receipt := models.Receipt{
+		DonationID: donation.ID,
+		Status:     models.ReceiptIssued,
+		DonorName:  donation.DonorName,
+		IssuedAt:   time.Now(),
+	}
+	requestBody.apply(&receipt)
+	if user, ok := c.Get("user"); ok {
+		if actor, ok := user.(models.User); ok {
+			receipt.IssuedByID = &actor.ID
+		}
+	}
+
+	var existing models.Receipt
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Receipts are issued one at a time, so each year's numbers have no
+		// gaps and a donation cannot get two valid receipts
+		receipt.Year = receipt.IssuedAt.In(config.ShelterLocation()).Year()
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", receiptLockKey, receipt.Year).Error; err != nil {
+			return err
+		}
+		err := tx.Where("donation_id = ? AND status = ?", donation.ID, models.ReceiptIssued).First(&existing).Error
+		if err == nil {
+			return errReceiptExists
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		var last int
+		if err := tx.Model(&models.Receipt{}).Unscoped().Where("year = ?", receipt.Year).
+			Select("COALESCE(MAX(number), 0)").Scan(&last).Error; err != nil {
+			return err
+		}
+		receipt.Number = last + 1
+		receipt.Serial = fmt.Sprintf("%s-%d-%06d", receiptSerialPrefix, receipt.Year, receipt.Number)
+
+		key, err := renderReceipt(c.Request.Context(), store, &receipt, donation)
+		if err != nil {
+			return err
+		}
+		receipt.ObjectKey = key
+		if err := tx.Omit("Donation").Create(&receipt).Error; err != nil {
+			_ = store.DeleteFile(c.Request.Context(), key)
+			return err
+		}
+		return nil
+	})
+	if errors.Is(err, errReceiptExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": "The donation already has receipt " + existing.Serial + "; regenerate or void it instead"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to issue receipt for donation %d: %v", donation.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue receipt"})
+		return
+	}
+	recordAudit(c, models.AuditCreate, "receipt", receipt.ID, nil, receipt)
+
+	if requestBody.SendEmail && donation.DonorEmail != "" {
+		if err := sendReceiptEmail(c, &receipt, donation.DonorEmail); err != nil {
+			log.Printf("Failed to email receipt %s: %v", receipt.Serial, err)
+		}
+	}
+	receipt.Donation = donation
+	c.JSON(http.StatusCreated, receipt)
+}
+
+// GetReceipts - List receipts with pagination, newest first. Filters:
+// ?status=, ?year=, ?donation_id=
+func GetReceipts(c *gin.Context) {
+	var receipts []models.Receipt
+	var total int64
+
+	// Default values for pagination
+	limit := 20
+	page := 1
+
+	if l := c.Query("limit"); l != "" {
+		fmt.Sscanf(l, "%d", &limit)
+	}
+	if p := c.Query("page"); p != "" {
+		fmt.Sscanf(p, "%d", &page)
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	if page < 1 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	query := config.DB.Model(&models.Receipt{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if year := c.Query("year"); year != "" {
+		query = query.Where("year = ?", year)
+	}
+	if donationID := c.Query("donation_id"); donationID != "" {
+		query = query.Where("donation_id = ?", donationID)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching receipts"})
+		return
+	}
+	if err := query.Preload("Donation").Order("year DESC, number DESC").Limit(limit).Offset(offset).Find(&receipts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching receipts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       receipts,
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+		"totalPages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// GetReceiptByID - Retrieve a receipt with its donation
+func GetReceiptByID(c *gin.Context) {
+	receipt, ok := findReceipt(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, receipt)
+}
+
+// DownloadReceipt - Send a receipt's PDF to an admin
+func DownloadReceipt(c *gin.Context) {
+	receipt, ok := findReceipt(c)
+	if !ok {
+		return
+	}
+	sendReceiptDocument(c, receipt)
+}
+
+// GetReceiptByToken - Send a receipt's PDF to the donor holding its emailed link
+func GetReceiptByToken(c *gin.Context) {
+	var receipt models.Receipt
+	if err := config.DB.Where("access_token_hash = ?", utils.HashToken(c.Param("token"))).First(&receipt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Receipt not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching receipt"})
+		return
+	}
+	sendReceiptDocument(c, &receipt)
+}
+
+// RegenerateReceipt - Render a receipt again from the current donation, for
+// example after correcting it, keeping its serial number. The donor details
+// can be changed in the same request.
+func RegenerateReceipt(c *gin.Context) {
+	receipt, ok := findReceipt(c)
+	if !ok {
+		return
+	}
+	var requestBody receiptDetailsRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+	if receipt.Status == models.ReceiptVoided {
+		c.JSON(http.StatusConflict, gin.H{"error": "Voided receipts cannot be regenerated; issue a new receipt instead"})
+		return
+	}
+	if receipt.Donation == nil || receipt.Donation.Status != models.DonationCompleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "The donation is no longer completed; void the receipt instead"})
+		return
+	}
+	before := *receipt
+	requestBody.apply(receipt)
+
+	if err := replaceReceiptDocument(c, receipt); err != nil {
+		log.Printf("Failed to regenerate receipt %s: %v", receipt.Serial, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to regenerate receipt"})
+		return
+	}
+	recordAudit(c, models.AuditUpdate, "receipt", receipt.ID, before, *receipt)
+
+	if requestBody.SendEmail && receipt.Donation.DonorEmail != "" {
+		if err := sendReceiptEmail(c, receipt, receipt.Donation.DonorEmail); err != nil {
+			log.Printf("Failed to email receipt %s: %v", receipt.Serial, err)
+		}
+	}
+	c.JSON(http.StatusOK, receipt)
+}
+
+// VoidReceipt - Void a receipt issued in error or for a refunded donation. Its
+// document is replaced by a copy stamped VOID and the serial number is not
+// reused; a corrected receipt gets a new number.
+func VoidReceipt(c *gin.Context) {
+	receipt, ok := findReceipt(c)
+	if !ok {
+		return
+	}
+	var requestBody struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+	if receipt.Status == models.ReceiptVoided {
+		c.JSON(http.StatusConflict, gin.H{"error": "The receipt is already voided"})
+		return
+	}
+	before := *receipt
+
+	now := time.Now()
+	receipt.Status = models.ReceiptVoided
+	receipt.VoidedAt = &now
+	receipt.VoidReason = strings.TrimSpace(requestBody.Reason)
+	if err := replaceReceiptDocument(c, receipt); err != nil {
+		log.Printf("Failed to void receipt %s: %v", receipt.Serial, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to void receipt"})
+		return
+	}
+	recordAudit(c, models.AuditUpdate, "receipt", receipt.ID, before, *receipt)
+
+	c.JSON(http.StatusOK, receipt)
+}
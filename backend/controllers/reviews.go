@@ -94,6 +94,7 @@ func CreateReview(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating review"})
 		return
 	}
+	recordAudit(c, models.AuditCreate, "review", review.ID, nil, review)
 
 	c.JSON(http.StatusCreated, gin.H{
 		"id":         review.ID,
@@ -115,6 +116,7 @@ func UpdateReview(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
 		return
 	}
+	before := review
 
 	// Define a struct to hold the updateable fields
 	var updateData struct {
@@ -157,6 +159,7 @@ func UpdateReview(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated review"})
 		return
 	}
+	recordAudit(c, models.AuditUpdate, "review", updatedReview.ID, before, updatedReview)
 
 	c.JSON(http.StatusOK, updatedReview)
 }
@@ -177,6 +180,7 @@ func DeleteReview(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete review"})
 		return
 	}
+	recordAudit(c, models.AuditDelete, "review", review.ID, review, nil)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Review deleted successfully"})
 }
\ No newline at end of file
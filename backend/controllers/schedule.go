@@ -0,0 +1,566 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kholodihor/cows-shelter-backend/config"
+	"github.com/kholodihor/cows-shelter-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ScheduleRequest represents the JSON request body for a recurring schedule.
+// On update, omitted fields are left unchanged.
+type ScheduleRequest struct {
+	Weekdays      *[]string `json:"weekdays"`       // MO, TU, WE, TH, FR, SA, SU
+	IntervalWeeks *int      `json:"interval_weeks"` // every n weeks, default 1
+	StartTime     *string   `json:"start_time"`     // HH:MM, shelter time
+	EndTime       *string   `json:"end_time"`       // HH:MM, shelter time
+	Capacity      *int      `json:"capacity"`
+	ValidFrom     *string   `json:"valid_from"`  // YYYY-MM-DD, default today
+	ValidUntil    *string   `json:"valid_until"` // YYYY-MM-DD, empty for no end
+	ExcludedDates *[]string `json:"excluded_dates"`
+	Active        *bool     `json:"active"`
+}
+
+// sessionTime is one occurrence of a schedule
+type sessionTime struct {
+	StartsAt time.Time
+	EndsAt   time.Time
+}
+
+// scheduleHorizon is how far ahead schedules are expanded into sessions
+func scheduleHorizon() time.Duration {
+	return envDuration("SCHEDULE_HORIZON", 90*24*time.Hour)
+}
+
+// parseClock parses an HH:MM time of day
+func parseClock(value string) (hour, minute int, err error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// civilDate strips the time of day, keeping the calendar date as seen in t's zone
+func civilDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// applyScheduleRequest copies the provided fields onto the schedule
+func applyScheduleRequest(schedule *models.ExcursionSchedule, req ScheduleRequest) error {
+	if req.Weekdays != nil {
+		schedule.Weekdays = *req.Weekdays
+	}
+	if req.IntervalWeeks != nil {
+		schedule.IntervalWeeks = *req.IntervalWeeks
+	}
+	if req.StartTime != nil {
+		schedule.StartTime = *req.StartTime
+	}
+	if req.EndTime != nil {
+		schedule.EndTime = *req.EndTime
+	}
+	if req.Capacity != nil {
+		schedule.Capacity = *req.Capacity
+	}
+	if req.ValidFrom != nil {
+		validFrom, err := parseDate(*req.ValidFrom)
+		if err != nil || validFrom == nil {
+			return errors.New("valid_from must be a YYYY-MM-DD date")
+		}
+		schedule.ValidFrom = *validFrom
+	}
+	if req.ValidUntil != nil {
+		validUntil, err := parseDate(*req.ValidUntil)
+		if err != nil {
+			return errors.New("valid_until: " + err.Error())
+		}
+		schedule.ValidUntil = validUntil
+	}
+	if req.ExcludedDates != nil {
+		schedule.ExcludedDates = *req.ExcludedDates
+	}
+	if req.Active != nil {
+		schedule.Active = *req.Active
+	}
+	return nil
+}
+
+// normalizeSchedule validates a schedule and puts its lists in canonical form
+func normalizeSchedule(schedule *models.ExcursionSchedule) error {
+	seen := map[string]bool{}
+	weekdays := []string{}
+	for _, day := range schedule.Weekdays {
+		day = strings.ToUpper(strings.TrimSpace(day))
+		if _, ok := models.WeekdayCodes[day]; !ok {
+			return fmt.Errorf("unknown weekday %q, expected MO, TU, WE, TH, FR, SA or SU", day)
+		}
+		if !seen[day] {
+			seen[day] = true
+			weekdays = append(weekdays, day)
+		}
+	}
+	if len(weekdays) == 0 {
+		return errors.New("weekdays must list at least one day")
+	}
+	sort.Slice(weekdays, func(i, j int) bool {
+		// Monday first, as in the rest of the week-based calendar
+		return (models.WeekdayCodes[weekdays[i]]+6)%7 < (models.WeekdayCodes[weekdays[j]]+6)%7
+	})
+	schedule.Weekdays = weekdays
+
+	if schedule.IntervalWeeks < 1 {
+		return errors.New("interval_weeks must be at least 1")
+	}
+	startHour, startMinute, err := parseClock(schedule.StartTime)
+	if err != nil {
+		return errors.New("start_time: " + err.Error())
+	}
+	endHour, endMinute, err := parseClock(schedule.EndTime)
+	if err != nil {
+		return errors.New("end_time: " + err.Error())
+	}
+	if endHour*60+endMinute <= startHour*60+startMinute {
+		return errors.New("end_time must be after start_time")
+	}
+	if schedule.Capacity < 1 {
+		return errors.New("capacity must be at least 1")
+	}
+	if schedule.ValidUntil != nil && schedule.ValidUntil.Before(schedule.ValidFrom) {
+		return errors.New("valid_until cannot be before valid_from")
+	}
+
+	excluded := []string{}
+	seen = map[string]bool{}
+	for _, value := range schedule.ExcludedDates {
+		date, err := parseDate(strings.TrimSpace(value))
+		if err != nil || date == nil {
+			return fmt.Errorf("excluded_dates: invalid date %q, expected YYYY-MM-DD", value)
+		}
+		if key := date.Format("2006-01-02"); !seen[key] {
+			seen[key] = true
+			excluded = append(excluded, key)
+		}
+	}
+	sort.Strings(excluded)
+	schedule.ExcludedDates = excluded
+	return nil
+}
+
+// scheduleOccurrences lists the sessions a schedule produces that start
+// within [from, to]. Dates are walked on the calendar of loc, the shelter's
+// time zone, and each occurrence is placed at its local wall-clock time, so
+// sessions stay at 11:00 in Kyiv across daylight saving changes.
+func scheduleOccurrences(schedule *models.ExcursionSchedule, from, to time.Time, blackouts map[string]bool, loc *time.Location) []sessionTime {
+	startHour, startMinute, err := parseClock(schedule.StartTime)
+	if err != nil {
+		return nil
+	}
+	endHour, endMinute, err := parseClock(schedule.EndTime)
+	if err != nil {
+		return nil
+	}
+
+	weekdays := map[time.Weekday]bool{}
+	for _, day := range schedule.Weekdays {
+		weekdays[models.WeekdayCodes[day]] = true
+	}
+	excluded := map[string]bool{}
+	for _, date := range schedule.ExcludedDates {
+		excluded[date] = true
+	}
+
+	validFrom := civilDate(schedule.ValidFrom)
+	// Weeks are counted from the Monday of the first valid week
+	anchor := validFrom.AddDate(0, 0, -((int(validFrom.Weekday()) + 6) % 7))
+	interval := schedule.IntervalWeeks
+	if interval < 1 {
+		interval = 1
+	}
+
+	day := civilDate(from.In(loc))
+	if day.Before(validFrom) {
+		day = validFrom
+	}
+	last := civilDate(to.In(loc))
+	if schedule.ValidUntil != nil && civilDate(*schedule.ValidUntil).Before(last) {
+		last = civilDate(*schedule.ValidUntil)
+	}
+
+	var occurrences []sessionTime
+	for ; !day.After(last); day = day.AddDate(0, 0, 1) {
+		if !weekdays[day.Weekday()] {
+			continue
+		}
+		if weeks := int(day.Sub(anchor).Hours()/24) / 7; weeks%interval != 0 {
+			continue
+		}
+		key := day.Format("2006-01-02")
+		if excluded[key] || blackouts[key] {
+			continue
+		}
+
+		startsAt := time.Date(day.Year(), day.Month(), day.Day(), startHour, startMinute, 0, 0, loc)
+		endsAt := time.Date(day.Year(), day.Month(), day.Day(), endHour, endMinute, 0, 0, loc)
+		if startsAt.Before(from) || startsAt.After(to) {
+			continue
+		}
+		occurrences = append(occurrences, sessionTime{StartsAt: startsAt, EndsAt: endsAt})
+	}
+	return occurrences
+}
+
+// blackoutSet returns the blackout dates between from and to as YYYY-MM-DD keys
+func blackoutSet(from, to time.Time) (map[string]bool, error) {
+	var blackouts []models.BlackoutDate
+	loc := config.ShelterLocation()
+	if err := config.DB.Where("date BETWEEN ? AND ?",
+		civilDate(from.In(loc)).Format("2006-01-02"), civilDate(to.In(loc)).Format("2006-01-02")).
+		Find(&blackouts).Error; err != nil {
+		return nil, err
+	}
+
+	set := map[string]bool{}
+	for _, blackout := range blackouts {
+		set[civilDate(blackout.Date).Format("2006-01-02")] = true
+	}
+	return set, nil
+}
+
+// expansionEnd caps until at horizon from now. It returns false when there
+// is nothing to expand.
+func expansionEnd(now, until time.Time, horizon time.Duration) (time.Time, bool) {
+	if limit := now.Add(horizon); until.After(limit) {
+		until = limit
+	}
+	return until, until.After(now)
+}
+
+// expandSchedules creates the sessions of active schedules that start
+// between now and until, capped at SCHEDULE_HORIZON. excursionID 0 expands
+// the schedules of every excursion. Existing sessions are kept as they are,
+// so concurrent expansions and repeated calls are harmless.
+func expandSchedules(excursionID uint, until time.Time) error {
+	now := time.Now()
+	until, ok := expansionEnd(now, until, scheduleHorizon())
+	if !ok {
+		return nil
+	}
+
+	var schedules []models.ExcursionSchedule
+	query := config.DB.Where("active = ?", true)
+	if excursionID != 0 {
+		query = query.Where("excursion_id = ?", excursionID)
+	}
+	if err := query.Find(&schedules).Error; err != nil {
+		return err
+	}
+	if len(schedules) == 0 {
+		return nil
+	}
+
+	blackouts, err := blackoutSet(now, until)
+	if err != nil {
+		return err
+	}
+
+	var sessions []models.ExcursionSession
+	for i := range schedules {
+		schedule := &schedules[i]
+		for _, occurrence := range scheduleOccurrences(schedule, now, until, blackouts, config.ShelterLocation()) {
+			sessions = append(sessions, models.ExcursionSession{
+				ExcursionID: schedule.ExcursionID,
+				ScheduleID:  &schedule.ID,
+				StartsAt:    occurrence.StartsAt,
+				EndsAt:      occurrence.EndsAt,
+				Capacity:    schedule.Capacity,
+				Status:      models.SessionScheduled,
+			})
+		}
+	}
+	if len(sessions) == 0 {
+		return nil
+	}
+
+	return config.DB.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&sessions, 100).Error
+}
+
+// pruneScheduleSessions removes the upcoming sessions of a schedule that no
+// one has booked, so they are generated again from the current rule.
+// Sessions with bookings are kept.
+func pruneScheduleSessions(scheduleID uint) error {
+	return config.DB.Unscoped().
+		Where("schedule_id = ? AND starts_at > ?", scheduleID, time.Now()).
+		Where("NOT EXISTS (SELECT 1 FROM bookings WHERE bookings.session_id = excursion_sessions.id)").
+		Delete(&models.ExcursionSession{}).Error
+}
+
+// closeDate removes or cancels the upcoming sessions on a shelter calendar
+// date: unbooked sessions are deleted so they can come back if the date
+// reopens, booked sessions are cancelled and their visitors emailed. With a
+// schedule ID only that schedule's sessions are affected.
+func closeDate(c *gin.Context, date time.Time, scheduleID *uint) error {
+	loc := config.ShelterLocation()
+	dayStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+	dayEnd := dayStart.AddDate(0, 0, 1)
+	if now := time.Now(); dayStart.Before(now) {
+		dayStart = now
+	}
+
+	query := config.DB.Preload("Excursion").
+		Where("status = ? AND starts_at >= ? AND starts_at < ?", models.SessionScheduled, dayStart, dayEnd)
+	if scheduleID != nil {
+		query = query.Where("schedule_id = ?", *scheduleID)
+	}
+	var sessions []models.ExcursionSession
+	if err := query.Find(&sessions).Error; err != nil {
+		return err
+	}
+
+	for i := range sessions {
+		session := &sessions[i]
+		var bookings int64
+		if err := config.DB.Model(&models.Booking{}).Where("session_id = ?", session.ID).Count(&bookings).Error; err != nil {
+			return err
+		}
+		if bookings == 0 {
+			if err := config.DB.Unscoped().Delete(&models.ExcursionSession{}, session.ID).Error; err != nil {
+				return err
+			}
+			continue
+		}
+
+		before := *session
+		if _, err := cancelSession(c.Request.Context(), session); err != nil {
+			return err
+		}
+		recordAudit(c, models.AuditUpdate, "excursion_session", session.ID, before, *session)
+	}
+	return nil
+}
+
+// findSchedule loads the schedule named by the :id route parameter
+func findSchedule(c *gin.Context) (*models.ExcursionSchedule, bool) {
+	var schedule models.ExcursionSchedule
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&schedule).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching schedule"})
+		return nil, false
+	}
+	return &schedule, true
+}
+
+// GetExcursionSchedules - List the recurring schedules of an excursion.
+// Inactive schedules are only included with ?all=true.
+func GetExcursionSchedules(c *gin.Context) {
+	schedules := []models.ExcursionSchedule{}
+	query := config.DB.Where("excursion_id = ?", c.Param("id")).Order("id")
+	if c.Query("all") != "true" {
+		query = query.Where("active = ?", true)
+	}
+	if err := query.Find(&schedules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching schedules"})
+		return
+	}
+	c.JSON(http.StatusOK, &schedules)
+}
+
+// CreateExcursionSchedule - Add a recurring schedule to an excursion
+func CreateExcursionSchedule(c *gin.Context) {
+	var excursion models.Excursion
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&excursion).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Excursion not found"})
+		return
+	}
+
+	var req ScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+
+	schedule := models.ExcursionSchedule{
+		ExcursionID:   excursion.ID,
+		IntervalWeeks: 1,
+		ValidFrom:     civilDate(time.Now().In(config.ShelterLocation())),
+		Active:        true,
+	}
+	if err := applyScheduleRequest(&schedule, req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := normalizeSchedule(&schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := config.DB.Create(&schedule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create schedule"})
+		return
+	}
+	recordAudit(c, models.AuditCreate, "excursion_schedule", schedule.ID, nil, schedule)
+
+	c.JSON(http.StatusCreated, schedule)
+}
+
+// UpdateExcursionSchedule - Change a schedule. Upcoming unbooked sessions are
+// regenerated from the new rule; newly excluded dates cancel booked sessions.
+func UpdateExcursionSchedule(c *gin.Context) {
+	schedule, ok := findSchedule(c)
+	if !ok {
+		return
+	}
+	before := *schedule
+
+	var req ScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+	if err := applyScheduleRequest(schedule, req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := normalizeSchedule(schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := config.DB.Save(schedule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update schedule"})
+		return
+	}
+	recordAudit(c, models.AuditUpdate, "excursion_schedule", schedule.ID, before, *schedule)
+
+	previouslyExcluded := map[string]bool{}
+	for _, date := range before.ExcludedDates {
+		previouslyExcluded[date] = true
+	}
+	for _, value := range schedule.ExcludedDates {
+		if previouslyExcluded[value] {
+			continue
+		}
+		date, _ := time.Parse("2006-01-02", value)
+		if err := closeDate(c, date, &schedule.ID); err != nil {
+			log.Printf("Failed to close %s for schedule %d: %v", value, schedule.ID, err)
+		}
+	}
+	if err := pruneScheduleSessions(schedule.ID); err != nil {
+		log.Printf("Failed to prune sessions of schedule %d: %v", schedule.ID, err)
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// DeleteExcursionSchedule - Delete a schedule and its upcoming unbooked
+// sessions. Booked sessions stay until they are cancelled.
+func DeleteExcursionSchedule(c *gin.Context) {
+	schedule, ok := findSchedule(c)
+	if !ok {
+		return
+	}
+
+	if err := pruneScheduleSessions(schedule.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete schedule sessions"})
+		return
+	}
+	if err := config.DB.Delete(schedule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete schedule"})
+		return
+	}
+	recordAudit(c, models.AuditDelete, "excursion_schedule", schedule.ID, *schedule, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Schedule deleted successfully"})
+}
+
+// GetBlackoutDates - List blackout dates from today on; ?all=true includes past ones
+func GetBlackoutDates(c *gin.Context) {
+	blackouts := []models.BlackoutDate{}
+	query := config.DB.Order("date")
+	if c.Query("all") != "true" {
+		query = query.Where("date >= ?", civilDate(time.Now().In(config.ShelterLocation())).Format("2006-01-02"))
+	}
+	if err := query.Find(&blackouts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching blackout dates"})
+		return
+	}
+	c.JSON(http.StatusOK, &blackouts)
+}
+
+// CreateBlackoutDate - Close a day for all excursions. Sessions already on
+// that day are removed, or cancelled with an email when they have bookings.
+// Posting an existing date closes its sessions again and answers 200.
+func CreateBlackoutDate(c *gin.Context) {
+	var requestBody struct {
+		Date   string `json:"date" binding:"required"` // YYYY-MM-DD
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+	date, err := parseDate(requestBody.Date)
+	if err != nil || date == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "date must be a YYYY-MM-DD date"})
+		return
+	}
+
+	status := http.StatusCreated
+	blackout := models.BlackoutDate{Date: *date, Reason: requestBody.Reason}
+	result := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&blackout)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create blackout date"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		if err := config.DB.Where("date = ?", requestBody.Date).First(&blackout).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching blackout date"})
+			return
+		}
+		status = http.StatusOK
+	} else {
+		recordAudit(c, models.AuditCreate, "blackout_date", blackout.ID, nil, blackout)
+	}
+
+	if err := closeDate(c, *date, nil); err != nil {
+		log.Printf("Failed to close sessions on %s: %v", requestBody.Date, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel the sessions on this date, please try again"})
+		return
+	}
+
+	c.JSON(status, blackout)
+}
+
+// DeleteBlackoutDate - Reopen a blacked out day. Scheduled sessions are
+// generated again; sessions that were cancelled stay cancelled.
+func DeleteBlackoutDate(c *gin.Context) {
+	var blackout models.BlackoutDate
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&blackout).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Blackout date not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching blackout date"})
+		return
+	}
+
+	if err := config.DB.Delete(&blackout).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete blackout date"})
+		return
+	}
+	recordAudit(c, models.AuditDelete, "blackout_date", blackout.ID, blackout, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Blackout date deleted successfully"})
+}
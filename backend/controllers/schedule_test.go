@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kholodihor/cows-shelter-backend/models"
+)
+
+func kyivLocation(t *testing.T) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation("Europe/Kyiv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+// scheduleDate returns midnight UTC of a calendar date, as schedules store them
+func scheduleDate(value string) time.Time {
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		panic(err)
+	}
+	return date
+}
+
+func TestScheduleOccurrences(t *testing.T) {
+	loc := kyivLocation(t)
+	at := func(value string) time.Time {
+		parsed, err := time.ParseInLocation("2006-01-02 15:04", value, loc)
+		if err != nil {
+			panic(err)
+		}
+		return parsed
+	}
+	until := scheduleDate("2025-05-13")
+
+	tests := []struct {
+		name      string
+		schedule  models.ExcursionSchedule
+		from, to  time.Time
+		blackouts map[string]bool
+		want      []string // local start times with their UTC offset
+	}{
+		{
+			name:     "spring DST change keeps the local time",
+			schedule: models.ExcursionSchedule{Weekdays: []string{"SA", "SU"}, ValidFrom: scheduleDate("2025-03-24")},
+			from:     at("2025-03-28 00:00"),
+			to:       at("2025-04-01 00:00"),
+			want:     []string{"2025-03-29 11:00 +0200", "2025-03-30 11:00 +0300"},
+		},
+		{
+			name:     "autumn DST change keeps the local time",
+			schedule: models.ExcursionSchedule{Weekdays: []string{"SA", "SU"}, ValidFrom: scheduleDate("2025-10-01")},
+			from:     at("2025-10-24 00:00"),
+			to:       at("2025-10-28 00:00"),
+			want:     []string{"2025-10-25 11:00 +0300", "2025-10-26 11:00 +0200"},
+		},
+		{
+			name:     "every other week counts from the Monday of the first valid week",
+			schedule: models.ExcursionSchedule{Weekdays: []string{"MO", "WE"}, IntervalWeeks: 2, ValidFrom: scheduleDate("2025-06-04")},
+			from:     at("2025-06-01 00:00"),
+			to:       at("2025-07-01 00:00"),
+			want:     []string{"2025-06-04 11:00 +0300", "2025-06-16 11:00 +0300", "2025-06-18 11:00 +0300", "2025-06-30 11:00 +0300"},
+		},
+		{
+			name:     "every other week across a DST change",
+			schedule: models.ExcursionSchedule{Weekdays: []string{"MO"}, IntervalWeeks: 2, ValidFrom: scheduleDate("2025-03-17")},
+			from:     at("2025-03-17 00:00"),
+			to:       at("2025-04-15 00:00"),
+			want:     []string{"2025-03-17 11:00 +0200", "2025-03-31 11:00 +0300", "2025-04-14 11:00 +0300"},
+		},
+		{
+			name: "excluded and blackout dates are skipped",
+			schedule: models.ExcursionSchedule{Weekdays: []string{"TU"}, ValidFrom: scheduleDate("2025-05-01"),
+				ExcludedDates: []string{"2025-05-13"}},
+			from:      at("2025-05-01 00:00"),
+			to:        at("2025-06-01 00:00"),
+			blackouts: map[string]bool{"2025-05-20": true},
+			want:      []string{"2025-05-06 11:00 +0300", "2025-05-27 11:00 +0300"},
+		},
+		{
+			name:     "valid_until is the last day",
+			schedule: models.ExcursionSchedule{Weekdays: []string{"TU"}, ValidFrom: scheduleDate("2025-05-01"), ValidUntil: &until},
+			from:     at("2025-05-01 00:00"),
+			to:       at("2025-06-01 00:00"),
+			want:     []string{"2025-05-06 11:00 +0300", "2025-05-13 11:00 +0300"},
+		},
+		{
+			name:     "sessions must start within the window",
+			schedule: models.ExcursionSchedule{Weekdays: []string{"TU"}, ValidFrom: scheduleDate("2025-05-01")},
+			from:     at("2025-05-06 11:01"),
+			to:       at("2025-05-20 11:00"),
+			want:     []string{"2025-05-13 11:00 +0300", "2025-05-20 11:00 +0300"},
+		},
+		{
+			name:     "days are taken from the shelter calendar, not UTC",
+			schedule: models.ExcursionSchedule{Weekdays: []string{"TU"}, StartTime: "00:30", EndTime: "01:30", ValidFrom: scheduleDate("2025-05-01")},
+			from:     time.Date(2025, 5, 5, 21, 0, 0, 0, time.UTC),
+			to:       time.Date(2025, 5, 6, 21, 0, 0, 0, time.UTC),
+			want:     []string{"2025-05-06 00:30 +0300"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule := tt.schedule
+			if schedule.StartTime == "" {
+				schedule.StartTime, schedule.EndTime = "11:00", "12:30"
+			}
+			if schedule.IntervalWeeks == 0 {
+				schedule.IntervalWeeks = 1
+			}
+			startHour, startMinute, _ := parseClock(schedule.StartTime)
+			endHour, endMinute, _ := parseClock(schedule.EndTime)
+			length := time.Duration(endHour*60+endMinute-startHour*60-startMinute) * time.Minute
+
+			got := []string{}
+			for _, occurrence := range scheduleOccurrences(&schedule, tt.from, tt.to, tt.blackouts, loc) {
+				got = append(got, occurrence.StartsAt.In(loc).Format("2006-01-02 15:04 -0700"))
+				if occurrence.EndsAt.Sub(occurrence.StartsAt) != length {
+					t.Errorf("session at %v ends at %v", occurrence.StartsAt, occurrence.EndsAt)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpansionEnd(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	horizon := 90 * 24 * time.Hour
+
+	tests := []struct {
+		name  string
+		until time.Time
+		want  time.Time
+		ok    bool
+	}{
+		{"within the horizon", now.Add(24 * time.Hour), now.Add(24 * time.Hour), true},
+		{"capped at the horizon", now.AddDate(1, 0, 0), now.Add(horizon), true},
+		{"already passed", now.Add(-time.Hour), now.Add(-time.Hour), false},
+		{"nothing left", now, now, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := expansionEnd(now, tt.until, horizon)
+			if !got.Equal(tt.want) || ok != tt.ok {
+				t.Errorf("got %v, %v; want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
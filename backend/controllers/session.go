@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kholodihor/cows-shelter-backend/config"
+	"github.com/kholodihor/cows-shelter-backend/models"
+	"gorm.io/gorm"
+)
+
+// sessionView is a session as shown to users, flagging the one making the request
+type sessionView struct {
+	models.Session
+	Current bool `json:"current"`
+}
+
+// activeSessions returns the user's sessions that are neither revoked nor expired
+func activeSessions(c *gin.Context, userID uint) ([]sessionView, error) {
+	var sessions []models.Session
+	if err := config.DB.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	current := c.GetString("session_id")
+	views := make([]sessionView, len(sessions))
+	for i, session := range sessions {
+		views[i] = sessionView{Session: session, Current: session.ID == current}
+	}
+	return views, nil
+}
+
+// revokeSession signs out one active session of the user
+func revokeSession(userID uint, sessionID string) (*models.Session, error) {
+	var session models.Session
+	if err := config.DB.Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		First(&session).Error; err != nil {
+		return nil, err
+	}
+
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return revokeRefreshFamily(tx, session.ID)
+	}); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session.RevokedAt = &now
+	return &session, nil
+}
+
+// respondSessions writes the active sessions of a user
+func respondSessions(c *gin.Context, userID uint) {
+	sessions, err := activeSessions(c, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching sessions"})
+		return
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+// GetMySessions - List where the authenticated user is logged in
+func GetMySessions(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	respondSessions(c, user.ID)
+}
+
+// DeleteMySession - Sign out one of the authenticated user's sessions
+func DeleteMySession(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	if _, err := revokeSession(user.ID, c.Param("id")); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error revoking session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session signed out"})
+}
+
+// DeleteMySessions - Sign out every other session of the authenticated user;
+// ?include_current=true signs out the current one as well
+func DeleteMySessions(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	keep := c.GetString("session_id")
+	if c.Query("include_current") == "true" {
+		keep = ""
+	}
+
+	var sessions []models.Session
+	if err := config.DB.Where("user_id = ? AND revoked_at IS NULL AND id <> ?", user.ID, keep).
+		Find(&sessions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching sessions"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		for _, session := range sessions {
+			if err := revokeRefreshFamily(tx, session.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error revoking sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Sessions signed out", "revoked": len(sessions)})
+}
+
+// GetUserSessions - List the active sessions of a user
+func GetUserSessions(c *gin.Context) {
+	user, ok := findUserParam(c)
+	if !ok {
+		return
+	}
+	respondSessions(c, user.ID)
+}
+
+// DeleteUserSession - Sign out one session of a user
+func DeleteUserSession(c *gin.Context) {
+	user, ok := findUserParam(c)
+	if !ok {
+		return
+	}
+
+	before, err := revokeSession(user.ID, c.Param("session_id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error revoking session"})
+		return
+	}
+	recordSessionAudit(c, models.AuditDelete, before.ID, *before, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session signed out"})
+}
+
+// DeleteUserSessions - Sign out every session of a user
+func DeleteUserSessions(c *gin.Context) {
+	user, ok := findUserParam(c)
+	if !ok {
+		return
+	}
+
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return revokeUserRefreshTokens(tx, user.ID)
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error revoking sessions"})
+		return
+	}
+	recordAudit(c, models.AuditUpdate, "user", user.ID, gin.H{"sessions": "all"}, gin.H{"sessions": "revoked"})
+
+	c.JSON(http.StatusOK, gin.H{"message": "All sessions signed out"})
+}
@@ -0,0 +1,675 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kholodihor/cows-shelter-backend/config"
+	"github.com/kholodihor/cows-shelter-backend/models"
+	"github.com/kholodihor/cows-shelter-backend/notifier"
+	"gorm.io/gorm"
+)
+
+// Errors of shift and sign-up changes that conflict with the current state
+var (
+	errShiftFull      = errors.New("no places left on this shift")
+	errCapacityTooLow = errors.New("capacity cannot be lower than the volunteers already signed up")
+	errSignupChanged  = errors.New("the sign-up was changed by someone else, try again")
+)
+
+// shiftWindow combines a date and a time window (HH:MM) in the shelter time
+// zone into the start and end of a shift
+func shiftWindow(date, from, to string) (time.Time, time.Time, error) {
+	loc := config.ShelterLocation()
+	startsAt, err := time.ParseInLocation("2006-01-02 15:04", date+" "+from, loc)
+	if err != nil {
+		return startsAt, startsAt, fmt.Errorf("invalid date %q or start_time %q, expected YYYY-MM-DD and HH:MM", date, from)
+	}
+	endsAt, err := time.ParseInLocation("2006-01-02 15:04", date+" "+to, loc)
+	if err != nil {
+		return startsAt, endsAt, fmt.Errorf("invalid end_time %q, expected HH:MM", to)
+	}
+	if !endsAt.After(startsAt) {
+		return startsAt, endsAt, errors.New("end_time must be after start_time")
+	}
+	return startsAt, endsAt, nil
+}
+
+// formatShiftTime formats a shift window in the shelter time zone for messages
+func formatShiftTime(shift *models.Shift) string {
+	loc := config.ShelterLocation()
+	return fmt.Sprintf("%s-%s", shift.StartsAt.In(loc).Format("Mon, 02 Jan 2006 15:04"), shift.EndsAt.In(loc).Format("15:04 MST"))
+}
+
+// volunteerRecipient returns the notification addresses of a volunteer
+func volunteerRecipient(volunteer *models.Volunteer) notifier.Recipient {
+	return notifier.Recipient{Name: volunteer.Name, Email: volunteer.Email, Phone: volunteer.Phone}
+}
+
+// reserveShiftPlace takes a place on a scheduled, not yet started shift. The
+// capacity check and the increment are one conditional UPDATE.
+func reserveShiftPlace(tx *gorm.DB, shiftID uint) error {
+	result := tx.Model(&models.Shift{}).
+		Where("id = ? AND status = ? AND starts_at > ? AND signed_up < capacity", shiftID, models.ShiftScheduled, time.Now()).
+		UpdateColumn("signed_up", gorm.Expr("signed_up + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != 1 {
+		return errShiftFull
+	}
+	return nil
+}
+
+// releaseShiftPlace gives a place back to a shift
+func releaseShiftPlace(tx *gorm.DB, shiftID uint) error {
+	return tx.Model(&models.Shift{}).
+		Where("id = ?", shiftID).
+		UpdateColumn("signed_up", gorm.Expr("GREATEST(signed_up - 1, 0)")).Error
+}
+
+// signUpForShift puts a volunteer on a shift, reusing an earlier cancelled
+// sign-up so each volunteer appears once per shift
+func signUpForShift(volunteerID, shiftID uint) (*models.ShiftSignup, error) {
+	var signup models.ShiftSignup
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("shift_id = ? AND volunteer_id = ?", shiftID, volunteerID).First(&signup).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if signup.Status == models.SignupConfirmed {
+			return nil
+		}
+		if err := reserveShiftPlace(tx, shiftID); err != nil {
+			return err
+		}
+		signup.ShiftID = shiftID
+		signup.VolunteerID = volunteerID
+		signup.Status = models.SignupConfirmed
+		signup.Minutes = nil
+		signup.ReminderSentAt = nil
+		return tx.Save(&signup).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &signup, nil
+}
+
+// cancelSignup withdraws a confirmed sign-up and frees its place. It reports
+// whether the sign-up changed, so a place is released only once.
+func cancelSignup(signup *models.ShiftSignup) (bool, error) {
+	var changed bool
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.ShiftSignup{}).
+			Where("id = ? AND status = ?", signup.ID, models.SignupConfirmed).
+			Update("status", models.SignupCancelled)
+		if result.Error != nil || result.RowsAffected != 1 {
+			return result.Error
+		}
+		changed = true
+		return releaseShiftPlace(tx, signup.ShiftID)
+	})
+	if changed {
+		signup.Status = models.SignupCancelled
+	}
+	return changed, err
+}
+
+// findShift loads the shift named by the :id route parameter
+func findShift(c *gin.Context) (*models.Shift, bool) {
+	var shift models.Shift
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&shift).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Shift not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching shift"})
+		return nil, false
+	}
+	return &shift, true
+}
+
+// GetShifts - List upcoming scheduled shifts that still need volunteers,
+// optionally up to ?to= (YYYY-MM-DD, inclusive)
+func GetShifts(c *gin.Context) {
+	query := config.DB.Where("status = ? AND starts_at > ? AND signed_up < capacity", models.ShiftScheduled, time.Now())
+	if to := c.Query("to"); to != "" {
+		until, err := time.ParseInLocation("2006-01-02", to, config.ShelterLocation())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a date in YYYY-MM-DD format"})
+			return
+		}
+		query = query.Where("starts_at < ?", until.AddDate(0, 0, 1))
+	}
+
+	shifts := []models.Shift{}
+	if err := query.Order("starts_at").Limit(200).Find(&shifts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching shifts"})
+		return
+	}
+	c.JSON(http.StatusOK, shifts)
+}
+
+// GetAdminShifts - List shifts with pagination, soonest first. Filters:
+// ?status=, ?from= and ?to= (YYYY-MM-DD, inclusive; from defaults to today)
+func GetAdminShifts(c *gin.Context) {
+	var shifts []models.Shift
+	var total int64
+
+	// Default values for pagination
+	limit := 20
+	page := 1
+
+	if l := c.Query("limit"); l != "" {
+		fmt.Sscanf(l, "%d", &limit)
+	}
+	if p := c.Query("page"); p != "" {
+		fmt.Sscanf(p, "%d", &page)
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	if page < 1 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	loc := config.ShelterLocation()
+	now := time.Now().In(loc)
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	if value := c.Query("from"); value != "" {
+		var err error
+		if from, err = time.ParseInLocation("2006-01-02", value, loc); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a date in YYYY-MM-DD format"})
+			return
+		}
+	}
+	query := config.DB.Model(&models.Shift{}).Where("starts_at >= ?", from)
+	if value := c.Query("to"); value != "" {
+		to, err := time.ParseInLocation("2006-01-02", value, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a date in YYYY-MM-DD format"})
+			return
+		}
+		query = query.Where("starts_at < ?", to.AddDate(0, 0, 1))
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching shifts"})
+		return
+	}
+	if err := query.Order("starts_at").Limit(limit).Offset(offset).Find(&shifts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching shifts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       shifts,
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+		"totalPages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// CreateShift - Define a shift: a date, a time window (HH:MM, shelter time),
+// the task and how many volunteers it needs
+func CreateShift(c *gin.Context) {
+	var requestBody struct {
+		Date        string `json:"date" binding:"required"`
+		StartTime   string `json:"start_time" binding:"required"`
+		EndTime     string `json:"end_time" binding:"required"`
+		Task        string `json:"task" binding:"required"`
+		Description string `json:"description"`
+		Capacity    int    `json:"capacity" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+	startsAt, endsAt, err := shiftWindow(requestBody.Date, requestBody.StartTime, requestBody.EndTime)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	shift := models.Shift{
+		Task:        strings.TrimSpace(requestBody.Task),
+		Description: requestBody.Description,
+		StartsAt:    startsAt,
+		EndsAt:      endsAt,
+		Capacity:    requestBody.Capacity,
+		Status:      models.ShiftScheduled,
+		PlacesLeft:  requestBody.Capacity,
+	}
+	if err := config.DB.Create(&shift).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create shift"})
+		return
+	}
+	recordAudit(c, models.AuditCreate, "shift", shift.ID, nil, shift)
+
+	c.JSON(http.StatusCreated, shift)
+}
+
+// UpdateShift - Change a shift; omitted fields are left unchanged. Changing
+// the time needs date, start_time and end_time together and cannot move the
+// shift into the past; the volunteers signed up are told about the new time
+// and get a new reminder for it. Capacity cannot drop below the volunteers
+// already signed up.
+func UpdateShift(c *gin.Context) {
+	shift, ok := findShift(c)
+	if !ok {
+		return
+	}
+	before := *shift
+
+	var requestBody struct {
+		Date        *string `json:"date"`
+		StartTime   *string `json:"start_time"`
+		EndTime     *string `json:"end_time"`
+		Task        *string `json:"task"`
+		Description *string `json:"description"`
+		Capacity    *int    `json:"capacity"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+
+	moved := false
+	if requestBody.Date != nil || requestBody.StartTime != nil || requestBody.EndTime != nil {
+		if requestBody.Date == nil || requestBody.StartTime == nil || requestBody.EndTime == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "date, start_time and end_time must be changed together"})
+			return
+		}
+		startsAt, endsAt, err := shiftWindow(*requestBody.Date, *requestBody.StartTime, *requestBody.EndTime)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		moved = !startsAt.Equal(shift.StartsAt)
+		if moved && !startsAt.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A shift cannot be moved to a time that has already passed"})
+			return
+		}
+		shift.StartsAt = startsAt
+		shift.EndsAt = endsAt
+	}
+	if requestBody.Task != nil {
+		if strings.TrimSpace(*requestBody.Task) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "task cannot be empty"})
+			return
+		}
+		shift.Task = strings.TrimSpace(*requestBody.Task)
+	}
+	if requestBody.Description != nil {
+		shift.Description = *requestBody.Description
+	}
+	if requestBody.Capacity != nil && *requestBody.Capacity < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "capacity must be at least 1"})
+		return
+	}
+
+	var signups []models.ShiftSignup
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{
+			"task":        shift.Task,
+			"description": shift.Description,
+			"starts_at":   shift.StartsAt,
+			"ends_at":     shift.EndsAt,
+		}
+		if requestBody.Capacity != nil {
+			// Checked in the UPDATE itself so a concurrent sign-up cannot slip past it
+			result := tx.Model(&models.Shift{}).
+				Where("id = ? AND signed_up <= ?", shift.ID, *requestBody.Capacity).
+				Update("capacity", *requestBody.Capacity)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected != 1 {
+				return errCapacityTooLow
+			}
+		}
+		if err := tx.Model(&models.Shift{}).Where("id = ?", shift.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		if moved {
+			if err := tx.Model(&models.ShiftSignup{}).Where("shift_id = ?", shift.ID).
+				Update("reminder_sent_at", nil).Error; err != nil {
+				return err
+			}
+			if err := tx.Preload("Volunteer").Where("shift_id = ? AND status = ?", shift.ID, models.SignupConfirmed).
+				Find(&signups).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Where("id = ?", shift.ID).First(shift).Error
+	})
+	if errors.Is(err, errCapacityTooLow) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Capacity cannot be lower than the volunteers already signed up"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update shift"})
+		return
+	}
+	recordAudit(c, models.AuditUpdate, "shift", shift.ID, before, *shift)
+
+	for _, signup := range signups {
+		if signup.Volunteer == nil {
+			continue
+		}
+		if err := config.Notifier.Notify(c.Request.Context(), notifier.Message{
+			To:      volunteerRecipient(signup.Volunteer),
+			Subject: fmt.Sprintf("Shift moved: %s", shift.Task),
+			Body: fmt.Sprintf("Hello %s,\n\nThe shift \"%s\" has been moved from %s to %s. "+
+				"If you cannot come at the new time, please cancel your sign-up so someone else can take your place.\n",
+				signup.Volunteer.Name, shift.Task, formatShiftTime(&before), formatShiftTime(shift)),
+		}); err != nil {
+			log.Printf("Move notice for shift sign-up %d failed: %v", signup.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, shift)
+}
+
+// CancelShift - Cancel a shift and notify the volunteers signed up for it
+func CancelShift(c *gin.Context) {
+	shift, ok := findShift(c)
+	if !ok {
+		return
+	}
+	if shift.Status == models.ShiftCancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Shift is already cancelled"})
+		return
+	}
+	before := *shift
+
+	var signups []models.ShiftSignup
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Preload("Volunteer").Where("shift_id = ? AND status = ?", shift.ID, models.SignupConfirmed).
+			Find(&signups).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.ShiftSignup{}).Where("shift_id = ? AND status = ?", shift.ID, models.SignupConfirmed).
+			Update("status", models.SignupCancelled).Error; err != nil {
+			return err
+		}
+		return tx.Model(shift).Updates(map[string]interface{}{"status": models.ShiftCancelled, "signed_up": 0}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel shift"})
+		return
+	}
+	shift.Status = models.ShiftCancelled
+	shift.SignedUp = 0
+	shift.PlacesLeft = 0
+	recordAudit(c, models.AuditUpdate, "shift", shift.ID, before, *shift)
+
+	if shift.StartsAt.After(time.Now()) {
+		for _, signup := range signups {
+			if signup.Volunteer == nil {
+				continue
+			}
+			if err := config.Notifier.Notify(c.Request.Context(), notifier.Message{
+				To:      volunteerRecipient(signup.Volunteer),
+				Subject: fmt.Sprintf("Shift cancelled: %s", shift.Task),
+				Body: fmt.Sprintf("Hello %s,\n\nThe shift \"%s\" on %s has been cancelled, so you do not need to come. "+
+					"Thank you for signing up!\n", signup.Volunteer.Name, shift.Task, formatShiftTime(shift)),
+			}); err != nil {
+				log.Printf("Cancellation notice for shift sign-up %d failed: %v", signup.ID, err)
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"shift": shift, "notified": len(signups)})
+}
+
+// DeleteShift - Delete a shift created by mistake. Shifts with volunteers
+// must be cancelled instead, so the volunteers are told.
+func DeleteShift(c *gin.Context) {
+	shift, ok := findShift(c)
+	if !ok {
+		return
+	}
+	var signups int64
+	if err := config.DB.Model(&models.ShiftSignup{}).Where("shift_id = ? AND status <> ?", shift.ID, models.SignupCancelled).
+		Count(&signups).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking sign-ups"})
+		return
+	}
+	if signups > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Shift has volunteers; cancel it instead"})
+		return
+	}
+	if err := config.DB.Delete(shift).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete shift"})
+		return
+	}
+	recordAudit(c, models.AuditDelete, "shift", shift.ID, *shift, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Shift deleted successfully"})
+}
+
+// GetShiftSignups - List the volunteers of a shift; ?status=all includes
+// cancelled sign-ups
+func GetShiftSignups(c *gin.Context) {
+	shift, ok := findShift(c)
+	if !ok {
+		return
+	}
+
+	signups := []models.ShiftSignup{}
+	query := config.DB.Preload("Volunteer").Where("shift_id = ?", shift.ID).Order("created_at")
+	switch status := c.Query("status"); status {
+	case "":
+		query = query.Where("status <> ?", models.SignupCancelled)
+	case "all":
+	default:
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Find(&signups).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching sign-ups"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"shift":   shift,
+		"signups": signups,
+	})
+}
+
+// AddShiftSignup - Put an approved volunteer on a shift on their behalf
+func AddShiftSignup(c *gin.Context) {
+	shift, ok := findShift(c)
+	if !ok {
+		return
+	}
+	var requestBody struct {
+		VolunteerID uint `json:"volunteer_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+
+	var volunteer models.Volunteer
+	if err := config.DB.Where("id = ?", requestBody.VolunteerID).First(&volunteer).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Volunteer not found"})
+		return
+	}
+	if volunteer.Status != models.VolunteerApproved {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only approved volunteers can be signed up"})
+		return
+	}
+
+	signup, err := signUpForShift(volunteer.ID, shift.ID)
+	if errors.Is(err, errShiftFull) {
+		c.JSON(http.StatusConflict, gin.H{"error": "The shift is full, cancelled or has already started"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign up volunteer"})
+		return
+	}
+	recordAudit(c, models.AuditCreate, "shift_signup", signup.ID, nil, *signup)
+
+	c.JSON(http.StatusCreated, signup)
+}
+
+// UpdateShiftSignup - Record how a sign-up went: status no_show for a
+// volunteer who did not come, cancelled to withdraw them, and minutes when
+// they worked more or less than the length of the shift
+func UpdateShiftSignup(c *gin.Context) {
+	var signup models.ShiftSignup
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&signup).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Sign-up not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching sign-up"})
+		return
+	}
+	before := signup
+
+	var requestBody struct {
+		Status  *string `json:"status"`
+		Minutes *int    `json:"minutes" binding:"omitempty,min=0"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+
+	if requestBody.Status != nil && *requestBody.Status != signup.Status {
+		to := *requestBody.Status
+		if !models.ValidSignupStatus(to) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status must be confirmed, cancelled or no_show"})
+			return
+		}
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			result := tx.Model(&models.ShiftSignup{}).Where("id = ? AND status = ?", signup.ID, signup.Status).Update("status", to)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected != 1 {
+				return errSignupChanged
+			}
+			// Only confirmed sign-ups hold a place
+			if signup.Status == models.SignupConfirmed {
+				return releaseShiftPlace(tx, signup.ShiftID)
+			}
+			if to == models.SignupConfirmed {
+				// Past shifts can be corrected, so only capacity is checked here
+				result := tx.Model(&models.Shift{}).Where("id = ? AND signed_up < capacity", signup.ShiftID).
+					UpdateColumn("signed_up", gorm.Expr("signed_up + 1"))
+				if result.Error != nil {
+					return result.Error
+				}
+				if result.RowsAffected != 1 {
+					return errShiftFull
+				}
+			}
+			return nil
+		})
+		if errors.Is(err, errShiftFull) || errors.Is(err, errSignupChanged) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update sign-up"})
+			return
+		}
+		signup.Status = to
+	}
+	if requestBody.Minutes != nil {
+		signup.Minutes = requestBody.Minutes
+		if err := config.DB.Model(&signup).Update("minutes", signup.Minutes).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update sign-up"})
+			return
+		}
+	}
+	recordAudit(c, models.AuditUpdate, "shift_signup", signup.ID, before, signup)
+
+	c.JSON(http.StatusOK, signup)
+}
+
+// SendShiftReminders notifies volunteers of their shifts starting within
+// SHIFT_REMINDER_LEAD, once per sign-up. It returns how many were sent.
+func SendShiftReminders(ctx context.Context) (int, error) {
+	now := time.Now()
+	lead := envDuration("SHIFT_REMINDER_LEAD", 24*time.Hour)
+
+	var signups []models.ShiftSignup
+	if err := config.DB.Preload("Shift").Preload("Volunteer").
+		Joins("JOIN shifts ON shifts.id = shift_signups.shift_id AND shifts.deleted_at IS NULL").
+		Where("shift_signups.status = ? AND shift_signups.reminder_sent_at IS NULL", models.SignupConfirmed).
+		Where("shifts.status = ? AND shifts.starts_at > ? AND shifts.starts_at <= ?", models.ShiftScheduled, now, now.Add(lead)).
+		Find(&signups).Error; err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, signup := range signups {
+		if signup.Shift == nil || signup.Volunteer == nil {
+			continue
+		}
+		body := fmt.Sprintf("Hello %s,\n\nThis is a reminder of your volunteer shift \"%s\" on %s.\n",
+			signup.Volunteer.Name, signup.Shift.Task, formatShiftTime(signup.Shift))
+		if signup.Shift.Description != "" {
+			body += "\n" + signup.Shift.Description + "\n"
+		}
+		if signup.Volunteer.TokenHash != nil {
+			body += "\nIf you cannot come, please cancel through your volunteer page so someone else can take your place.\n"
+		}
+		if err := config.Notifier.Notify(ctx, notifier.Message{
+			To:      volunteerRecipient(signup.Volunteer),
+			Subject: fmt.Sprintf("Reminder: %s", signup.Shift.Task),
+			Body:    body,
+		}); err != nil {
+			log.Printf("Reminder for shift sign-up %d failed: %v", signup.ID, err)
+			continue
+		}
+		if err := config.DB.Model(&models.ShiftSignup{}).Where("id = ?", signup.ID).
+			Update("reminder_sent_at", time.Now()).Error; err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// StartShiftReminders sends due shift reminders now and then every
+// SHIFT_REMINDER_INTERVAL until ctx is cancelled
+func StartShiftReminders(ctx context.Context) {
+	interval := envDuration("SHIFT_REMINDER_INTERVAL", 15*time.Minute)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if sent, err := SendShiftReminders(ctx); err != nil {
+				log.Printf("Failed to send shift reminders: %v", err)
+			} else if sent > 0 {
+				log.Printf("Sent %d shift reminders", sent)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
@@ -0,0 +1,607 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kholodihor/cows-shelter-backend/config"
+	"github.com/kholodihor/cows-shelter-backend/mailer"
+	"github.com/kholodihor/cows-shelter-backend/models"
+	"github.com/kholodihor/cows-shelter-backend/utils"
+	"gorm.io/gorm"
+)
+
+// SponsorshipCoverage shows how much of an animal's monthly upkeep is paid by
+// active sponsorships
+type SponsorshipCoverage struct {
+	AnimalID      uint   `json:"animal_id"`
+	NameEn        string `json:"name_en"`
+	NameUa        string `json:"name_ua"`
+	CoverImageUrl string `json:"cover_image_url"`
+	MonthlyCost   int64  `json:"monthly_cost"`
+	Covered       int64  `json:"covered"`
+	Remaining     int64  `json:"remaining"`
+	Percent       int64  `json:"percent"`
+	Sponsors      int    `json:"sponsors"`
+	Currency      string `json:"currency"`
+}
+
+// sponsorshipCurrency is the currency all sponsorships and animal costs use
+func sponsorshipCurrency() string {
+	return strings.ToUpper(config.GetEnv("SPONSORSHIP_CURRENCY", "UAH"))
+}
+
+// activeSponsorships limits a query to sponsorships that currently count
+// towards coverage
+func activeSponsorships(db *gorm.DB) *gorm.DB {
+	return db.Where("status = ? AND (ends_at IS NULL OR ends_at >= CURRENT_DATE)", models.SponsorshipActive)
+}
+
+// sponsorshipCoverage computes the coverage of each animal
+func sponsorshipCoverage(animals []models.Animal) ([]SponsorshipCoverage, error) {
+	ids := make([]uint, len(animals))
+	for i, animal := range animals {
+		ids[i] = animal.ID
+	}
+
+	var sponsorships []models.Sponsorship
+	if len(ids) > 0 {
+		if err := activeSponsorships(config.DB).Where("animal_id IN ?", ids).Find(&sponsorships).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	covered := map[uint]int64{}
+	sponsors := map[uint]int{}
+	for _, sponsorship := range sponsorships {
+		covered[sponsorship.AnimalID] += sponsorship.MonthlyAmount()
+		sponsors[sponsorship.AnimalID]++
+	}
+
+	currency := sponsorshipCurrency()
+	coverage := make([]SponsorshipCoverage, len(animals))
+	for i, animal := range animals {
+		entry := SponsorshipCoverage{
+			AnimalID:      animal.ID,
+			NameEn:        animal.NameEn,
+			NameUa:        animal.NameUa,
+			CoverImageUrl: animal.CoverImageUrl,
+			MonthlyCost:   animal.MonthlyCost,
+			Covered:       covered[animal.ID],
+			Sponsors:      sponsors[animal.ID],
+			Currency:      currency,
+		}
+		if entry.Covered < entry.MonthlyCost {
+			entry.Remaining = entry.MonthlyCost - entry.Covered
+		}
+		if entry.MonthlyCost > 0 {
+			entry.Percent = entry.Covered * 100 / entry.MonthlyCost
+		}
+		coverage[i] = entry
+	}
+	return coverage, nil
+}
+
+// GetAnimalsNeedingSponsor - List resident animals whose upkeep is not yet fully
+// sponsored, least covered first
+func GetAnimalsNeedingSponsor(c *gin.Context) {
+	var animals []models.Animal
+	if err := config.DB.Where("status = ? AND monthly_cost > 0", models.AnimalResident).Find(&animals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching animals"})
+		return
+	}
+
+	coverage, err := sponsorshipCoverage(animals)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching sponsorships"})
+		return
+	}
+
+	needed := []SponsorshipCoverage{}
+	for _, entry := range coverage {
+		if entry.Remaining > 0 {
+			needed = append(needed, entry)
+		}
+	}
+	sort.SliceStable(needed, func(i, j int) bool { return needed[i].Percent < needed[j].Percent })
+
+	c.JSON(http.StatusOK, needed)
+}
+
+// GetSponsorshipCoverage - Report sponsorship coverage per animal; ?status= filters animals
+func GetSponsorshipCoverage(c *gin.Context) {
+	var animals []models.Animal
+	query := config.DB.Order("name_en")
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Find(&animals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching animals"})
+		return
+	}
+
+	coverage, err := sponsorshipCoverage(animals)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching sponsorships"})
+		return
+	}
+
+	var totalCost, totalCovered int64
+	for _, entry := range coverage {
+		totalCost += entry.MonthlyCost
+		totalCovered += entry.Covered
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"animals":       coverage,
+		"total_cost":    totalCost,
+		"total_covered": totalCovered,
+		"currency":      sponsorshipCurrency(),
+	})
+}
+
+// sendSponsorLink issues a new secret link for the sponsor page, replacing any
+// earlier one, and emails it to the sponsor
+func sendSponsorLink(c *gin.Context, sponsorship *models.Sponsorship) error {
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return err
+	}
+	if err := config.DB.Model(sponsorship).Update("token_hash", utils.HashToken(token)).Error; err != nil {
+		return err
+	}
+
+	name := ""
+	if sponsorship.Animal != nil {
+		name = sponsorship.Animal.NameEn
+	} else {
+		var animal models.Animal
+		if err := config.DB.Select("name_en").Where("id = ?", sponsorship.AnimalID).First(&animal).Error; err == nil {
+			name = animal.NameEn
+		}
+	}
+
+	link := fmt.Sprintf("%s/sponsor/%s", config.GetEnv("FRONTEND_URL", "http://localhost:5173"), token)
+	return config.Mailer.Send(c.Request.Context(), mailer.Message{
+		To:      sponsorship.SponsorEmail,
+		Subject: fmt.Sprintf("Your sponsorship of %s", name),
+		Body: fmt.Sprintf("Thank you for sponsoring %s!\n\n"+
+			"Open the link below to read updates about %s and manage your sponsorship. "+
+			"Keep it private: anyone with the link can see your sponsor page.\n\n%s\n", name, name, link),
+	})
+}
+
+// findSponsorship loads the sponsorship named by the :id route parameter
+func findSponsorship(c *gin.Context) (*models.Sponsorship, bool) {
+	var sponsorship models.Sponsorship
+	if err := config.DB.Preload("Animal").Where("id = ?", c.Param("id")).First(&sponsorship).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Sponsorship not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching sponsorship"})
+		return nil, false
+	}
+	return &sponsorship, true
+}
+
+// findSponsorshipByToken loads the active or paused sponsorship for a sponsor link
+func findSponsorshipByToken(c *gin.Context) (*models.Sponsorship, bool) {
+	var sponsorship models.Sponsorship
+	err := config.DB.Preload("Animal.Photos", orderedPhotos).
+		Where("token_hash = ? AND status IN ?", utils.HashToken(c.Param("token")),
+			[]string{models.SponsorshipActive, models.SponsorshipPaused}).
+		First(&sponsorship).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invalid or expired sponsor link"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching sponsorship"})
+		return nil, false
+	}
+	return &sponsorship, true
+}
+
+// validateSponsorship checks amount, period and status
+func validateSponsorship(sponsorship *models.Sponsorship) error {
+	if sponsorship.Amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+	if models.PeriodMonths(sponsorship.Period) == 0 {
+		return errors.New("period must be monthly, quarterly or yearly")
+	}
+	if !models.ValidSponsorshipStatus(sponsorship.Status) {
+		return errors.New("status must be pending, active, paused or cancelled")
+	}
+	return nil
+}
+
+// setSponsorshipStatus applies a status change and its timestamps
+func setSponsorshipStatus(sponsorship *models.Sponsorship, status string) {
+	now := time.Now()
+	if status == models.SponsorshipActive && sponsorship.StartedAt == nil {
+		sponsorship.StartedAt = &now
+	}
+	if status == models.SponsorshipCancelled && sponsorship.CancelledAt == nil {
+		sponsorship.CancelledAt = &now
+	}
+	if status != models.SponsorshipCancelled {
+		sponsorship.CancelledAt = nil
+	}
+	sponsorship.Status = status
+}
+
+// RequestSponsorship - Let a supporter ask to sponsor an animal. The request
+// stays pending until the shelter confirms it.
+func RequestSponsorship(c *gin.Context) {
+	var requestBody struct {
+		Name     string `json:"name" binding:"required"`
+		Email    string `json:"email" binding:"required,email"`
+		Amount   int64  `json:"amount" binding:"required"`
+		Period   string `json:"period"`
+		ShowName bool   `json:"show_name"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+
+	wait, err := allowPublicRequest(models.ThrottleSponsorRequestIP, c.ClientIP(),
+		envInt("SPONSOR_REQUESTS_PER_IP", 5), publicFormWindow())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save sponsorship request"})
+		return
+	}
+	if wait > 0 {
+		respondThrottled(c, wait)
+		return
+	}
+
+	var animal models.Animal
+	if err := config.DB.Where("id = ? AND status = ?", c.Param("id"), models.AnimalResident).First(&animal).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Animal not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching animal"})
+		return
+	}
+
+	if requestBody.Period == "" {
+		requestBody.Period = models.PeriodMonthly
+	}
+	sponsorship := models.Sponsorship{
+		AnimalID:     animal.ID,
+		SponsorName:  requestBody.Name,
+		SponsorEmail: normalizeEmail(requestBody.Email),
+		ShowName:     requestBody.ShowName,
+		Amount:       requestBody.Amount,
+		Currency:     sponsorshipCurrency(),
+		Period:       requestBody.Period,
+		Status:       models.SponsorshipPending,
+	}
+	if err := validateSponsorship(&sponsorship); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := config.DB.Create(&sponsorship).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save sponsorship request"})
+		return
+	}
+	recordAudit(c, models.AuditCreate, "sponsorship", sponsorship.ID, nil, sponsorship)
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Thank you! We will contact you to confirm your sponsorship"})
+}
+
+// GetSponsorships - List sponsorships with pagination.
+// Filters: ?status=, ?animal_id= and ?email=.
+func GetSponsorships(c *gin.Context) {
+	var sponsorships []models.Sponsorship
+	var total int64
+
+	// Default values for pagination
+	limit := 20
+	page := 1
+
+	if l := c.Query("limit"); l != "" {
+		fmt.Sscanf(l, "%d", &limit)
+	}
+	if p := c.Query("page"); p != "" {
+		fmt.Sscanf(p, "%d", &page)
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	if page < 1 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	query := config.DB.Model(&models.Sponsorship{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if animalID := c.Query("animal_id"); animalID != "" {
+		query = query.Where("animal_id = ?", animalID)
+	}
+	if email := c.Query("email"); email != "" {
+		query = query.Where("sponsor_email = ?", normalizeEmail(email))
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching sponsorships"})
+		return
+	}
+	if err := query.Preload("Animal").Order("created_at DESC").Limit(limit).Offset(offset).Find(&sponsorships).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching sponsorships"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       sponsorships,
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+		"totalPages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// CreateSponsorship - Record a sponsorship arranged by the shelter. Active
+// sponsorships email the sponsor a link to their sponsor page.
+func CreateSponsorship(c *gin.Context) {
+	var requestBody struct {
+		AnimalID     uint   `json:"animal_id" binding:"required"`
+		SponsorName  string `json:"sponsor_name" binding:"required"`
+		SponsorEmail string `json:"sponsor_email" binding:"required,email"`
+		ShowName     bool   `json:"show_name"`
+		Amount       int64  `json:"amount" binding:"required"`
+		Period       string `json:"period"`
+		Status       string `json:"status"`
+		EndsAt       string `json:"ends_at"` // YYYY-MM-DD
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+
+	var animal models.Animal
+	if err := config.DB.Where("id = ?", requestBody.AnimalID).First(&animal).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Animal not found"})
+		return
+	}
+	endsAt, err := parseDate(requestBody.EndsAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ends_at: " + err.Error()})
+		return
+	}
+
+	if requestBody.Period == "" {
+		requestBody.Period = models.PeriodMonthly
+	}
+	if requestBody.Status == "" {
+		requestBody.Status = models.SponsorshipActive
+	}
+	sponsorship := models.Sponsorship{
+		AnimalID:     animal.ID,
+		SponsorName:  requestBody.SponsorName,
+		SponsorEmail: normalizeEmail(requestBody.SponsorEmail),
+		ShowName:     requestBody.ShowName,
+		Amount:       requestBody.Amount,
+		Currency:     sponsorshipCurrency(),
+		Period:       requestBody.Period,
+		EndsAt:       endsAt,
+	}
+	setSponsorshipStatus(&sponsorship, requestBody.Status)
+	if err := validateSponsorship(&sponsorship); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := config.DB.Create(&sponsorship).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create sponsorship"})
+		return
+	}
+	recordAudit(c, models.AuditCreate, "sponsorship", sponsorship.ID, nil, sponsorship)
+
+	sponsorship.Animal = &animal
+	if sponsorship.Status == models.SponsorshipActive {
+		if err := sendSponsorLink(c, &sponsorship); err != nil {
+			// The link can be sent again from the admin panel
+			log.Printf("Sponsor link for sponsorship %d failed: %v", sponsorship.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusCreated, sponsorship)
+}
+
+// UpdateSponsorship - Change a sponsorship; omitted fields are left unchanged.
+// Confirming a sponsorship emails the sponsor their link.
+func UpdateSponsorship(c *gin.Context) {
+	sponsorship, ok := findSponsorship(c)
+	if !ok {
+		return
+	}
+	before := *sponsorship
+
+	var requestBody struct {
+		SponsorName  *string `json:"sponsor_name"`
+		SponsorEmail *string `json:"sponsor_email" binding:"omitempty,email"`
+		ShowName     *bool   `json:"show_name"`
+		Amount       *int64  `json:"amount"`
+		Period       *string `json:"period"`
+		Status       *string `json:"status"`
+		EndsAt       *string `json:"ends_at"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+
+	if requestBody.SponsorName != nil {
+		sponsorship.SponsorName = *requestBody.SponsorName
+	}
+	if requestBody.SponsorEmail != nil {
+		sponsorship.SponsorEmail = normalizeEmail(*requestBody.SponsorEmail)
+	}
+	if requestBody.ShowName != nil {
+		sponsorship.ShowName = *requestBody.ShowName
+	}
+	if requestBody.Amount != nil {
+		sponsorship.Amount = *requestBody.Amount
+	}
+	if requestBody.Period != nil {
+		sponsorship.Period = *requestBody.Period
+	}
+	if requestBody.EndsAt != nil {
+		endsAt, err := parseDate(*requestBody.EndsAt)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ends_at: " + err.Error()})
+			return
+		}
+		sponsorship.EndsAt = endsAt
+	}
+	if requestBody.Status != nil {
+		setSponsorshipStatus(sponsorship, *requestBody.Status)
+	}
+	if err := validateSponsorship(sponsorship); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := config.DB.Omit("Animal").Save(sponsorship).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update sponsorship"})
+		return
+	}
+	recordAudit(c, models.AuditUpdate, "sponsorship", sponsorship.ID, before, *sponsorship)
+
+	if before.Status == models.SponsorshipPending && sponsorship.Status == models.SponsorshipActive {
+		if err := sendSponsorLink(c, sponsorship); err != nil {
+			log.Printf("Sponsor link for sponsorship %d failed: %v", sponsorship.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, sponsorship)
+}
+
+// ResendSponsorLink - Email the sponsor a new link to their sponsor page; the old link stops working
+func ResendSponsorLink(c *gin.Context) {
+	sponsorship, ok := findSponsorship(c)
+	if !ok {
+		return
+	}
+	if sponsorship.Status != models.SponsorshipActive && sponsorship.Status != models.SponsorshipPaused {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only active or paused sponsorships have a sponsor page"})
+		return
+	}
+
+	if err := sendSponsorLink(c, sponsorship); err != nil {
+		log.Printf("Sponsor link for sponsorship %d failed: %v", sponsorship.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send sponsor link"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Sponsor link sent to " + sponsorship.SponsorEmail})
+}
+
+// RequestSponsorLinks - Email fresh sponsor page links for every sponsorship of
+// an email. Links are sent at most once per SPONSOR_LINK_COOLDOWN for an email,
+// so the endpoint cannot be used to keep invalidating a sponsor's link.
+func RequestSponsorLinks(c *gin.Context) {
+	var requestBody struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email: " + err.Error()})
+		return
+	}
+	// Always answer the same way so the endpoint cannot be used to probe emails
+	sent := gin.H{"message": "If you sponsor one of our animals, a link has been sent to your email"}
+
+	wait, err := allowPublicRequest(models.ThrottleSponsorLinksIP, c.ClientIP(),
+		envInt("SPONSOR_LINK_REQUESTS_PER_IP", 10), publicFormWindow())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error looking up sponsorships"})
+		return
+	}
+	if wait > 0 {
+		respondThrottled(c, wait)
+		return
+	}
+
+	email := normalizeEmail(requestBody.Email)
+	wait, err = allowPublicRequest(models.ThrottleSponsorLinksEmail, email,
+		1, envDuration("SPONSOR_LINK_COOLDOWN", 15*time.Minute))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error looking up sponsorships"})
+		return
+	}
+	if wait > 0 {
+		// The links sent moments ago are still valid
+		c.JSON(http.StatusOK, sent)
+		return
+	}
+
+	var sponsorships []models.Sponsorship
+	if err := config.DB.Preload("Animal").
+		Where("sponsor_email = ? AND status IN ?", email,
+			[]string{models.SponsorshipActive, models.SponsorshipPaused}).
+		Find(&sponsorships).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error looking up sponsorships"})
+		return
+	}
+
+	for i := range sponsorships {
+		if err := sendSponsorLink(c, &sponsorships[i]); err != nil {
+			log.Printf("Sponsor link for sponsorship %d failed: %v", sponsorships[i].ID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, sent)
+}
+
+// GetSponsorPage - Show a sponsor their sponsorship, the animal and its sponsor-only updates
+func GetSponsorPage(c *gin.Context) {
+	sponsorship, ok := findSponsorshipByToken(c)
+	if !ok {
+		return
+	}
+
+	updates := []models.AnimalUpdate{}
+	if err := config.DB.Where("animal_id = ?", sponsorship.AnimalID).Order("created_at DESC").Find(&updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching updates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sponsorship": sponsorship,
+		"updates":     updates,
+	})
+}
+
+// CancelSponsorship - Let a sponsor end their sponsorship from the sponsor page
+func CancelSponsorship(c *gin.Context) {
+	sponsorship, ok := findSponsorshipByToken(c)
+	if !ok {
+		return
+	}
+	before := *sponsorship
+
+	setSponsorshipStatus(sponsorship, models.SponsorshipCancelled)
+	if err := config.DB.Model(sponsorship).Updates(map[string]interface{}{
+		"status":       sponsorship.Status,
+		"cancelled_at": sponsorship.CancelledAt,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel sponsorship"})
+		return
+	}
+	recordAudit(c, models.AuditUpdate, "sponsorship", sponsorship.ID, before, *sponsorship)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Your sponsorship has been cancelled. Thank you for your support!"})
+}
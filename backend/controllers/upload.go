@@ -3,19 +3,25 @@ package controllers
 import (
 	"context"
 	"net/http"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kholodihor/cows-shelter-backend/services"
 )
 
-var s3Service *services.S3Service
+var (
+	s3Once    sync.Once
+	s3Service *services.S3Service
+	s3Err     error
+)
 
-func init() {
-	var err error
-	s3Service, err = services.NewS3Service()
-	if err != nil {
-		panic("Failed to initialize S3 service: " + err.Error())
-	}
+// getS3Service connects to S3 on first use, so that loading this package
+// (in tests, for example) does not require AWS credentials
+func getS3Service() (*services.S3Service, error) {
+	s3Once.Do(func() {
+		s3Service, s3Err = services.NewS3Service()
+	})
+	return s3Service, s3Err
 }
 
 func UploadImage(c *gin.Context) {
@@ -27,6 +33,11 @@ func UploadImage(c *gin.Context) {
 	}
 
 	// Upload the file to S3
+	s3Service, err := getS3Service()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to initialize S3 service: " + err.Error()})
+		return
+	}
 	imageURL, err := s3Service.UploadFile(context.Background(), file, "uploads")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload file to S3: " + err.Error()})
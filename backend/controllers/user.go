@@ -2,7 +2,11 @@ package controllers
 
 import (
 	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kholodihor/cows-shelter-backend/config"
@@ -11,12 +15,6 @@ import (
 	"gorm.io/gorm"
 )
 
-func GetUsers(c *gin.Context){
-	users := []models.User{}
-	config.DB.Find(&users)
-	c.JSON(http.StatusOK,&users)
-}
-
 func GetUserByID(c *gin.Context) {
 	var user models.User
 	id := c.Param("id")
@@ -27,9 +25,17 @@ func GetUserByID(c *gin.Context) {
 	c.JSON(http.StatusOK, &user)
 }
 
+// CreateUser - Register an account by accepting an invitation
 func CreateUser(c *gin.Context) {
+    if !config.SelfRegistrationEnabled() {
+        c.JSON(http.StatusForbidden, gin.H{"error": "Self-registration is disabled"})
+        return
+    }
+
     var requestBody struct {
-        Email    string `json:"email" binding:"required,email"`
+        Token    string `json:"token" binding:"required"`
+        Email    string `json:"email" binding:"omitempty,email"`
+        Name     string `json:"name"`
         Password string `json:"password" binding:"required,min=8"`
     }
 
@@ -38,13 +44,9 @@ func CreateUser(c *gin.Context) {
         return
     }
 
-    // Check if the email is already taken
-    var existingUser models.User
-    if err := config.DB.Where("email = ?", requestBody.Email).First(&existingUser).Error; err == nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Email already taken"})
-        return
-    } else if !errors.Is(err, gorm.ErrRecordNotFound) {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking existing user"})
+    // Reject unknown tokens before spending time on the password hash
+    if _, err := findValidInvitation(config.DB, requestBody.Token); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invitation is invalid or expired"})
         return
     }
 
@@ -55,47 +57,403 @@ func CreateUser(c *gin.Context) {
         return
     }
 
-    user := models.User{
-        Email:    requestBody.Email,
-        Password: hashedPassword,
-    }
+    var user models.User
+    errInvalidInvitation := errors.New("invalid invitation")
+    errEmailTaken := errors.New("email taken")
+
+    // Accepting the invitation and creating the user happen atomically
+    err = config.DB.Transaction(func(tx *gorm.DB) error {
+        invitation, err := findValidInvitation(tx, requestBody.Token)
+        if err != nil {
+            return errInvalidInvitation
+        }
+        if requestBody.Email != "" && !strings.EqualFold(requestBody.Email, invitation.Email) {
+            return errInvalidInvitation
+        }
 
-    // Use a transaction to ensure atomicity
-    tx := config.DB.Begin()
-    if err := tx.Create(&user).Error; err != nil {
-        tx.Rollback()
+        // Check if the email is already taken
+        var existingUser models.User
+        if err := tx.Where("email = ?", invitation.Email).First(&existingUser).Error; err == nil {
+            return errEmailTaken
+        } else if !errors.Is(err, gorm.ErrRecordNotFound) {
+            return err
+        }
+
+        name := requestBody.Name
+        if name == "" {
+            name = invitation.Name
+        }
+        user = models.User{
+            Email:    invitation.Email,
+            Name:     name,
+            Password: hashedPassword,
+            Role:     invitation.Role,
+        }
+        if err := tx.Create(&user).Error; err != nil {
+            return err
+        }
+
+        // Claim the invitation so concurrent requests cannot both use it
+        result := tx.Model(invitation).Where("accepted_at IS NULL").Updates(map[string]interface{}{
+            "accepted_at":      time.Now(),
+            "accepted_user_id": user.ID,
+        })
+        if result.Error != nil {
+            return result.Error
+        }
+        if result.RowsAffected == 0 {
+            return errInvalidInvitation
+        }
+        return nil
+    })
+
+    if errors.Is(err, errInvalidInvitation) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invitation is invalid or expired"})
+        return
+    }
+    if errors.Is(err, errEmailTaken) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Email already taken"})
+        return
+    }
+    if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating user"})
         return
     }
+    recordAudit(c, models.AuditCreate, "user", user.ID, nil, user)
 
-    // Generate JWT token
-    token, err := utils.GenerateJWT(user.Email)
+    // Issue an access token and start a new refresh token family
+    tokens, err := issueTokens(c, &user)
     if err != nil {
-        tx.Rollback()
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating token"})
         return
     }
 
-    tx.Commit()
-
-    // Return the token and user ID (not the full user object)
+    // Return the tokens and user ID (not the full user object)
     c.JSON(http.StatusCreated, gin.H{
-        "id":    user.ID,
-        "email": user.Email,
-        "token": token,
+        "id":            user.ID,
+        "email":         user.Email,
+        "role":          user.Role,
+        "token":         tokens.AccessToken,
+        "refresh_token": tokens.RefreshToken,
     })
 }
 
-func UpdateUser(c *gin.Context){
-	var user models.User
-	config.DB.Where("id = ?", c.Param("id")).First(&user)
-	c.BindJSON(&user)
-	config.DB.Save(&user)
-	c.JSON(200,&user)
+// errLastAdmin is returned when a change would leave no active admin
+var errLastAdmin = errors.New("last active admin")
+
+// unusablePassword returns a bcrypt hash of a random secret nobody knows, so
+// the account can only be entered through a password reset link
+func unusablePassword() (string, error) {
+	secret, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return "", err
+	}
+	return utils.HashPassword(secret)
+}
+
+// ensureOtherActiveAdmin fails with errLastAdmin unless an active admin other
+// than userID exists
+func ensureOtherActiveAdmin(tx *gorm.DB, userID uint) error {
+	var count int64
+	if err := tx.Model(&models.User{}).
+		Where("role = ? AND deactivated_at IS NULL AND id <> ?", models.RoleAdmin, userID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errLastAdmin
+	}
+	return nil
 }
 
-func DeleteUser(c *gin.Context){
+// findUserParam loads the user named by the :id route parameter and writes
+// the error response when it cannot
+func findUserParam(c *gin.Context) (*models.User, bool) {
 	var user models.User
-	config.DB.Where("id = ?", c.Param("id")).Delete(&user)
-	c.JSON(200,&user)
-}
\ No newline at end of file
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching user"})
+		return nil, false
+	}
+	return &user, true
+}
+
+// GetUsers - List users with pagination; ?role= and ?status=active|deactivated filter the list
+func GetUsers(c *gin.Context) {
+	var users []models.User
+	var total int64
+
+	// Default values for pagination
+	limit := 10
+	page := 1
+
+	if l := c.Query("limit"); l != "" {
+		fmt.Sscanf(l, "%d", &limit)
+	}
+	if p := c.Query("page"); p != "" {
+		fmt.Sscanf(p, "%d", &page)
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+	if page < 1 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	query := config.DB.Model(&models.User{})
+	if role := c.Query("role"); role != "" {
+		query = query.Where("role = ?", role)
+	}
+	switch c.Query("status") {
+	case "active":
+		query = query.Where("deactivated_at IS NULL")
+	case "deactivated":
+		query = query.Where("deactivated_at IS NOT NULL")
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching users"})
+		return
+	}
+	if err := query.Order("id").Limit(limit).Offset(offset).Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       users,
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+		"totalPages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// UpdateUserRole - Change the role of another user
+func UpdateUserRole(c *gin.Context) {
+	var requestBody struct {
+		Role string `json:"role" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+	if !models.ValidRole(requestBody.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown role"})
+		return
+	}
+
+	user, ok := findUserParam(c)
+	if !ok {
+		return
+	}
+
+	current := c.MustGet("user").(models.User)
+	if user.ID == current.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot change your own role"})
+		return
+	}
+	before := *user
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if user.Role == models.RoleAdmin && requestBody.Role != models.RoleAdmin && user.DeactivatedAt == nil {
+			if err := ensureOtherActiveAdmin(tx, user.ID); err != nil {
+				return err
+			}
+		}
+		user.Role = requestBody.Role
+		return tx.Model(user).Update("role", user.Role).Error
+	})
+	if errors.Is(err, errLastAdmin) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot demote the last active admin"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating role"})
+		return
+	}
+	recordAudit(c, models.AuditUpdate, "user", user.ID, before, *user)
+
+	c.JSON(http.StatusOK, user)
+}
+
+// DeactivateUser - Block a user from logging in and revoke their sessions
+func DeactivateUser(c *gin.Context) {
+	user, ok := findUserParam(c)
+	if !ok {
+		return
+	}
+
+	current := c.MustGet("user").(models.User)
+	if user.ID == current.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot deactivate your own account"})
+		return
+	}
+	if user.DeactivatedAt != nil {
+		c.JSON(http.StatusOK, user)
+		return
+	}
+	before := *user
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if user.Role == models.RoleAdmin {
+			if err := ensureOtherActiveAdmin(tx, user.ID); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		user.DeactivatedAt = &now
+		if err := tx.Model(user).Update("deactivated_at", now).Error; err != nil {
+			return err
+		}
+		return revokeUserRefreshTokens(tx, user.ID)
+	})
+	if errors.Is(err, errLastAdmin) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot deactivate the last active admin"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deactivating user"})
+		return
+	}
+	recordAudit(c, models.AuditUpdate, "user", user.ID, before, *user)
+
+	c.JSON(http.StatusOK, user)
+}
+
+// ReactivateUser - Allow a deactivated user to log in again
+func ReactivateUser(c *gin.Context) {
+	user, ok := findUserParam(c)
+	if !ok {
+		return
+	}
+
+	before := *user
+	user.DeactivatedAt = nil
+	if err := config.DB.Model(user).Update("deactivated_at", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error reactivating user"})
+		return
+	}
+	recordAudit(c, models.AuditUpdate, "user", user.ID, before, *user)
+
+	c.JSON(http.StatusOK, user)
+}
+
+// ForcePasswordReset - Invalidate a user's password and sessions and email them a reset link
+func ForcePasswordReset(c *gin.Context) {
+	user, ok := findUserParam(c)
+	if !ok {
+		return
+	}
+	if user.DeactivatedAt != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Account is deactivated"})
+		return
+	}
+
+	secret, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error resetting password"})
+		return
+	}
+	before := *user
+
+	// Replacing the password with a random one also revokes every token
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return setUserPassword(tx, user, secret)
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error resetting password"})
+		return
+	}
+	recordAudit(c, models.AuditUpdate, "user", user.ID, before, *user)
+
+	if err := issuePasswordReset(c, user, forcedResetEmail); err != nil {
+		log.Printf("Forced password reset email for user %d failed: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Password invalidated but the reset email could not be sent"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset link sent to " + user.Email})
+}
+
+// GetMe - Return the profile of the authenticated user
+func GetMe(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	c.JSON(http.StatusOK, &user)
+}
+
+// UpdateMe - Update the name or email of the authenticated user.
+// Changing the email requires the current password.
+func UpdateMe(c *gin.Context) {
+	var requestBody struct {
+		Name            *string `json:"name"`
+		Email           *string `json:"email" binding:"omitempty,email"`
+		CurrentPassword string  `json:"current_password"`
+	}
+
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+	before := user
+	updates := map[string]interface{}{}
+
+	if requestBody.Name != nil {
+		updates["name"] = *requestBody.Name
+	}
+
+	emailChanged := requestBody.Email != nil && *requestBody.Email != user.Email
+	if emailChanged {
+		if !utils.CheckPasswordHash(requestBody.CurrentPassword, user.Password) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Current password is incorrect"})
+			return
+		}
+
+		var existingUser models.User
+		if err := config.DB.Where("email = ?", *requestBody.Email).First(&existingUser).Error; err == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Email already taken"})
+			return
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking existing user"})
+			return
+		}
+		updates["email"] = *requestBody.Email
+	}
+
+	if len(updates) > 0 {
+		if err := config.DB.Model(&user).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating profile"})
+			return
+		}
+		if requestBody.Name != nil {
+			user.Name = *requestBody.Name
+		}
+		if emailChanged {
+			user.Email = *requestBody.Email
+		}
+		recordAudit(c, models.AuditUpdate, "user", user.ID, before, user)
+	}
+
+	if !emailChanged {
+		c.JSON(http.StatusOK, &user)
+		return
+	}
+
+	// Access tokens carry the email, so hand out a pair for the new one
+	tokens, err := issueTokens(c, &user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating token"})
+		return
+	}
+
+	response := tokenResponse(&user, tokens)
+	response["profile"] = user
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,674 @@
+package controllers
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kholodihor/cows-shelter-backend/config"
+	"github.com/kholodihor/cows-shelter-backend/models"
+	"github.com/kholodihor/cows-shelter-backend/notifier"
+	"github.com/kholodihor/cows-shelter-backend/utils"
+	"gorm.io/gorm"
+)
+
+// VolunteerHours is one row of the hours-worked report. Confirmed sign-ups
+// of shifts that are over count, with their minutes or the shift's length.
+type VolunteerHours struct {
+	VolunteerID uint    `json:"volunteer_id"`
+	Name        string  `json:"name"`
+	Email       string  `json:"email"`
+	Shifts      int64   `json:"shifts"`
+	Minutes     int64   `json:"minutes"`
+	Hours       float64 `json:"hours"`
+}
+
+// volunteerHours adds up the hours worked in shifts starting between from
+// and to, per volunteer, or for one volunteer when volunteerID is not 0
+func volunteerHours(from, to time.Time, volunteerID uint) ([]VolunteerHours, error) {
+	rows := []VolunteerHours{}
+	query := config.DB.Table("shift_signups").
+		Select("volunteers.id AS volunteer_id, volunteers.name, volunteers.email, COUNT(*) AS shifts, "+
+			"COALESCE(SUM(COALESCE(shift_signups.minutes, EXTRACT(EPOCH FROM shifts.ends_at - shifts.starts_at) / 60)), 0)::bigint AS minutes").
+		Joins("JOIN shifts ON shifts.id = shift_signups.shift_id AND shifts.deleted_at IS NULL").
+		Joins("JOIN volunteers ON volunteers.id = shift_signups.volunteer_id").
+		Where("shift_signups.deleted_at IS NULL AND shift_signups.status = ? AND shifts.status = ?", models.SignupConfirmed, models.ShiftScheduled).
+		Where("shifts.ends_at <= ? AND shifts.starts_at >= ? AND shifts.starts_at < ?", time.Now(), from, to)
+	if volunteerID != 0 {
+		query = query.Where("volunteers.id = ?", volunteerID)
+	}
+	if err := query.Group("volunteers.id, volunteers.name, volunteers.email").
+		Order("minutes DESC, volunteers.name").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		rows[i].Hours = math.Round(float64(rows[i].Minutes)/60*100) / 100
+	}
+	return rows, nil
+}
+
+// volunteerLink is the frontend page where a volunteer manages their shifts
+func volunteerLink(token string) string {
+	return fmt.Sprintf("%s/volunteers/%s", config.GetEnv("FRONTEND_URL", "http://localhost:5173"), token)
+}
+
+// sendVolunteerLink issues a new secret link for the volunteer page,
+// replacing any earlier one, and sends it to the volunteer
+func sendVolunteerLink(ctx context.Context, volunteer *models.Volunteer, intro string) error {
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return err
+	}
+	hash := utils.HashToken(token)
+	if err := config.DB.Model(volunteer).Update("token_hash", hash).Error; err != nil {
+		return err
+	}
+	volunteer.TokenHash = &hash
+
+	return config.Notifier.Notify(ctx, notifier.Message{
+		To:      volunteerRecipient(volunteer),
+		Subject: "Your volunteer page at Cows Shelter",
+		Body: fmt.Sprintf("Hello %s,\n\n%s\n\n"+
+			"Open the link below to see the shifts that need help, sign up for them and cancel if your plans change. "+
+			"Keep it private: anyone with the link can manage your shifts.\n\n%s\n", volunteer.Name, intro, volunteerLink(token)),
+	})
+}
+
+// cancelUpcomingSignups withdraws a volunteer from shifts that have not
+// started, freeing their places
+func cancelUpcomingSignups(volunteerID uint) error {
+	var signups []models.ShiftSignup
+	if err := config.DB.Joins("JOIN shifts ON shifts.id = shift_signups.shift_id").
+		Where("shift_signups.volunteer_id = ? AND shift_signups.status = ? AND shifts.starts_at > ?",
+			volunteerID, models.SignupConfirmed, time.Now()).
+		Find(&signups).Error; err != nil {
+		return err
+	}
+	for i := range signups {
+		if _, err := cancelSignup(&signups[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findVolunteer loads the volunteer named by the :id route parameter
+func findVolunteer(c *gin.Context) (*models.Volunteer, bool) {
+	var volunteer models.Volunteer
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&volunteer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Volunteer not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching volunteer"})
+		return nil, false
+	}
+	return &volunteer, true
+}
+
+// findVolunteerByToken loads the approved volunteer of a volunteer link
+func findVolunteerByToken(c *gin.Context) (*models.Volunteer, bool) {
+	var volunteer models.Volunteer
+	if err := config.DB.Where("token_hash = ?", utils.HashToken(c.Param("token"))).First(&volunteer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Volunteer not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching volunteer"})
+		return nil, false
+	}
+	if volunteer.Status != models.VolunteerApproved {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Your volunteer profile is not active"})
+		return nil, false
+	}
+	return &volunteer, true
+}
+
+// ApplyAsVolunteer - Public sign-up form for new volunteers. Applications
+// wait for an admin to approve them.
+func ApplyAsVolunteer(c *gin.Context) {
+	var requestBody struct {
+		Name         string `json:"name" binding:"required"`
+		Email        string `json:"email" binding:"required,email"`
+		Phone        string `json:"phone"`
+		Skills       string `json:"skills"`
+		Availability string `json:"availability"`
+		About        string `json:"about"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+	// Always answer the same way so the form cannot be used to probe emails
+	received := gin.H{"message": "Thank you! We will contact you once your application is reviewed"}
+
+	wait, err := allowPublicRequest(models.ThrottleVolunteerApplicationIP, c.ClientIP(),
+		envInt("VOLUNTEER_APPLICATIONS_PER_IP", 5), publicFormWindow())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save application"})
+		return
+	}
+	if wait > 0 {
+		respondThrottled(c, wait)
+		return
+	}
+
+	email := normalizeEmail(requestBody.Email)
+	var existing int64
+	if err := config.DB.Model(&models.Volunteer{}).Where("email = ?", email).Count(&existing).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking volunteers"})
+		return
+	}
+	if existing > 0 {
+		// Already applied: nothing is saved or sent again
+		c.JSON(http.StatusCreated, received)
+		return
+	}
+
+	volunteer := models.Volunteer{
+		Name:         strings.TrimSpace(requestBody.Name),
+		Email:        email,
+		Phone:        strings.TrimSpace(requestBody.Phone),
+		Skills:       requestBody.Skills,
+		Availability: requestBody.Availability,
+		About:        requestBody.About,
+		Status:       models.VolunteerPending,
+	}
+	if err := config.DB.Create(&volunteer).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save application"})
+		return
+	}
+
+	if err := config.Notifier.Notify(c.Request.Context(), notifier.Message{
+		To:      volunteerRecipient(&volunteer),
+		Subject: "Thank you for offering to volunteer",
+		Body: fmt.Sprintf("Hello %s,\n\nThank you for offering to help at Cows Shelter! "+
+			"We will review your application and send you a link to sign up for shifts once it is approved.\n", volunteer.Name),
+	}); err != nil {
+		log.Printf("Application notice for volunteer %d failed: %v", volunteer.ID, err)
+	}
+
+	c.JSON(http.StatusCreated, received)
+}
+
+// GetVolunteers - List volunteers with pagination. Filters: ?status=, ?email=
+func GetVolunteers(c *gin.Context) {
+	var volunteers []models.Volunteer
+	var total int64
+
+	// Default values for pagination
+	limit := 20
+	page := 1
+
+	if l := c.Query("limit"); l != "" {
+		fmt.Sscanf(l, "%d", &limit)
+	}
+	if p := c.Query("page"); p != "" {
+		fmt.Sscanf(p, "%d", &page)
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	if page < 1 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	query := config.DB.Model(&models.Volunteer{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if email := c.Query("email"); email != "" {
+		query = query.Where("email = ?", normalizeEmail(email))
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching volunteers"})
+		return
+	}
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&volunteers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching volunteers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       volunteers,
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+		"totalPages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// GetVolunteerByID - Retrieve a volunteer with their latest sign-ups and the
+// hours they worked in total
+func GetVolunteerByID(c *gin.Context) {
+	volunteer, ok := findVolunteer(c)
+	if !ok {
+		return
+	}
+
+	signups := []models.ShiftSignup{}
+	if err := config.DB.Preload("Shift").Where("volunteer_id = ?", volunteer.ID).
+		Order("created_at DESC").Limit(50).Find(&signups).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching sign-ups"})
+		return
+	}
+	hours, err := volunteerHours(time.Time{}, time.Now(), volunteer.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching hours"})
+		return
+	}
+	total := VolunteerHours{VolunteerID: volunteer.ID, Name: volunteer.Name, Email: volunteer.Email}
+	if len(hours) > 0 {
+		total = hours[0]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"volunteer": volunteer,
+		"signups":   signups,
+		"hours":     total,
+	})
+}
+
+// UpdateVolunteer - Change a volunteer's profile or internal note; omitted
+// fields are left unchanged
+func UpdateVolunteer(c *gin.Context) {
+	volunteer, ok := findVolunteer(c)
+	if !ok {
+		return
+	}
+	before := *volunteer
+
+	var requestBody struct {
+		Name         *string `json:"name"`
+		Email        *string `json:"email" binding:"omitempty,email"`
+		Phone        *string `json:"phone"`
+		Skills       *string `json:"skills"`
+		Availability *string `json:"availability"`
+		About        *string `json:"about"`
+		Note         *string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+
+	if requestBody.Name != nil {
+		if strings.TrimSpace(*requestBody.Name) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "name cannot be empty"})
+			return
+		}
+		volunteer.Name = strings.TrimSpace(*requestBody.Name)
+	}
+	if requestBody.Email != nil {
+		email := normalizeEmail(*requestBody.Email)
+		var existing int64
+		if err := config.DB.Model(&models.Volunteer{}).Where("email = ? AND id <> ?", email, volunteer.ID).
+			Count(&existing).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking volunteers"})
+			return
+		}
+		if existing > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Another volunteer has this email"})
+			return
+		}
+		volunteer.Email = email
+	}
+	if requestBody.Phone != nil {
+		volunteer.Phone = strings.TrimSpace(*requestBody.Phone)
+	}
+	if requestBody.Skills != nil {
+		volunteer.Skills = *requestBody.Skills
+	}
+	if requestBody.Availability != nil {
+		volunteer.Availability = *requestBody.Availability
+	}
+	if requestBody.About != nil {
+		volunteer.About = *requestBody.About
+	}
+	if requestBody.Note != nil {
+		volunteer.Note = *requestBody.Note
+	}
+
+	if err := config.DB.Save(volunteer).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update volunteer"})
+		return
+	}
+	recordAudit(c, models.AuditUpdate, "volunteer", volunteer.ID, before, *volunteer)
+
+	c.JSON(http.StatusOK, volunteer)
+}
+
+// ApproveVolunteer - Approve an application, or reactivate a volunteer, and
+// send them the link to their volunteer page
+func ApproveVolunteer(c *gin.Context) {
+	volunteer, ok := findVolunteer(c)
+	if !ok {
+		return
+	}
+	if volunteer.Status == models.VolunteerApproved {
+		c.JSON(http.StatusConflict, gin.H{"error": "Volunteer is already approved"})
+		return
+	}
+	before := *volunteer
+
+	now := time.Now()
+	volunteer.Status = models.VolunteerApproved
+	volunteer.ReviewedAt = &now
+	if user, ok := c.Get("user"); ok {
+		if actor, ok := user.(models.User); ok {
+			volunteer.ReviewedByID = &actor.ID
+		}
+	}
+	if err := config.DB.Save(volunteer).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to approve volunteer"})
+		return
+	}
+	recordAudit(c, models.AuditUpdate, "volunteer", volunteer.ID, before, *volunteer)
+
+	if err := sendVolunteerLink(c.Request.Context(), volunteer,
+		"Welcome to the Cows Shelter volunteer team! Your application has been approved."); err != nil {
+		log.Printf("Volunteer link for volunteer %d failed: %v", volunteer.ID, err)
+		c.JSON(http.StatusOK, gin.H{"volunteer": volunteer, "warning": "Approved, but the volunteer link could not be sent"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"volunteer": volunteer})
+}
+
+// RejectVolunteer - Decline a pending application and let the applicant know
+func RejectVolunteer(c *gin.Context) {
+	volunteer, ok := findVolunteer(c)
+	if !ok {
+		return
+	}
+	if volunteer.Status != models.VolunteerPending {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only pending applications can be rejected"})
+		return
+	}
+	before := *volunteer
+
+	now := time.Now()
+	volunteer.Status = models.VolunteerRejected
+	volunteer.ReviewedAt = &now
+	if user, ok := c.Get("user"); ok {
+		if actor, ok := user.(models.User); ok {
+			volunteer.ReviewedByID = &actor.ID
+		}
+	}
+	if err := config.DB.Save(volunteer).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reject volunteer"})
+		return
+	}
+	recordAudit(c, models.AuditUpdate, "volunteer", volunteer.ID, before, *volunteer)
+
+	if err := config.Notifier.Notify(c.Request.Context(), notifier.Message{
+		To:      volunteerRecipient(volunteer),
+		Subject: "Your volunteer application",
+		Body: fmt.Sprintf("Hello %s,\n\nThank you for offering to volunteer at Cows Shelter. "+
+			"Unfortunately we cannot take on more volunteers at the moment. We hope you will stay in touch!\n", volunteer.Name),
+	}); err != nil {
+		log.Printf("Rejection notice for volunteer %d failed: %v", volunteer.ID, err)
+	}
+
+	c.JSON(http.StatusOK, volunteer)
+}
+
+// DeactivateVolunteer - Mark a volunteer as no longer active. Their volunteer
+// link stops working and they are withdrawn from upcoming shifts.
+func DeactivateVolunteer(c *gin.Context) {
+	volunteer, ok := findVolunteer(c)
+	if !ok {
+		return
+	}
+	if volunteer.Status != models.VolunteerApproved {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only approved volunteers can be deactivated"})
+		return
+	}
+	before := *volunteer
+
+	if err := cancelUpcomingSignups(volunteer.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel the volunteer's shifts"})
+		return
+	}
+	volunteer.Status = models.VolunteerInactive
+	volunteer.TokenHash = nil
+	if err := config.DB.Save(volunteer).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deactivate volunteer"})
+		return
+	}
+	recordAudit(c, models.AuditUpdate, "volunteer", volunteer.ID, before, *volunteer)
+
+	c.JSON(http.StatusOK, volunteer)
+}
+
+// ResendVolunteerLink - Send an approved volunteer a new link to their
+// volunteer page; the previous link stops working
+func ResendVolunteerLink(c *gin.Context) {
+	volunteer, ok := findVolunteer(c)
+	if !ok {
+		return
+	}
+	if volunteer.Status != models.VolunteerApproved {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only approved volunteers have a volunteer link"})
+		return
+	}
+	if err := sendVolunteerLink(c.Request.Context(), volunteer, "Here is a new link to your volunteer page."); err != nil {
+		log.Printf("Volunteer link for volunteer %d failed: %v", volunteer.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send the volunteer link"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "A new link was sent to the volunteer"})
+}
+
+// DeleteVolunteer - Delete a volunteer's profile, withdrawing them from
+// upcoming shifts. The hours they worked stay in the reports.
+func DeleteVolunteer(c *gin.Context) {
+	volunteer, ok := findVolunteer(c)
+	if !ok {
+		return
+	}
+	if err := cancelUpcomingSignups(volunteer.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel the volunteer's shifts"})
+		return
+	}
+	if err := config.DB.Model(volunteer).Update("token_hash", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete volunteer"})
+		return
+	}
+	if err := config.DB.Delete(volunteer).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete volunteer"})
+		return
+	}
+	recordAudit(c, models.AuditDelete, "volunteer", volunteer.ID, *volunteer, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Volunteer deleted successfully"})
+}
+
+// GetVolunteerHours - Hours worked per volunteer in shifts between ?from= and
+// ?to= (YYYY-MM-DD, current year by default); ?format=csv downloads them
+func GetVolunteerHours(c *gin.Context) {
+	from, to, err := reportRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rows, err := volunteerHours(from, to, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error building hours report"})
+		return
+	}
+
+	switch strings.ToLower(c.DefaultQuery("format", "json")) {
+	case "json":
+		var minutes int64
+		for _, row := range rows {
+			minutes += row.Minutes
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"from":        from.Format("2006-01-02"),
+			"to":          to.AddDate(0, 0, -1).Format("2006-01-02"),
+			"volunteers":  rows,
+			"total_hours": math.Round(float64(minutes)/60*100) / 100,
+		})
+	case "csv":
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="volunteer-hours-%s-%s.csv"`,
+			from.Format("2006-01-02"), to.AddDate(0, 0, -1).Format("2006-01-02")))
+		c.Status(http.StatusOK)
+		w := csv.NewWriter(c.Writer)
+		_ = w.Write([]string{"volunteer_id", "name", "email", "shifts", "hours"})
+		for _, row := range rows {
+			_ = w.Write([]string{fmt.Sprint(row.VolunteerID), row.Name, row.Email, fmt.Sprint(row.Shifts), fmt.Sprintf("%.2f", row.Hours)})
+		}
+		w.Flush()
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+	}
+}
+
+// GetVolunteerPage - Show volunteers their profile, upcoming shifts and the
+// hours they worked, through their volunteer link
+func GetVolunteerPage(c *gin.Context) {
+	volunteer, ok := findVolunteerByToken(c)
+	if !ok {
+		return
+	}
+
+	signups := []models.ShiftSignup{}
+	if err := config.DB.Preload("Shift").
+		Joins("JOIN shifts ON shifts.id = shift_signups.shift_id AND shifts.deleted_at IS NULL").
+		Where("shift_signups.volunteer_id = ? AND shift_signups.status = ? AND shifts.ends_at > ?",
+			volunteer.ID, models.SignupConfirmed, time.Now()).
+		Order("shifts.starts_at").Find(&signups).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching shifts"})
+		return
+	}
+	hours, err := volunteerHours(time.Time{}, time.Now(), volunteer.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching hours"})
+		return
+	}
+	var shifts int64
+	var worked float64
+	if len(hours) > 0 {
+		shifts, worked = hours[0].Shifts, hours[0].Hours
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"name":          volunteer.Name,
+		"email":         volunteer.Email,
+		"phone":         volunteer.Phone,
+		"skills":        volunteer.Skills,
+		"availability":  volunteer.Availability,
+		"about":         volunteer.About,
+		"upcoming":      signups,
+		"shifts_worked": shifts,
+		"hours_worked":  worked,
+	})
+}
+
+// UpdateVolunteerPage - Let volunteers update their own contact details and
+// availability through their volunteer link
+func UpdateVolunteerPage(c *gin.Context) {
+	volunteer, ok := findVolunteerByToken(c)
+	if !ok {
+		return
+	}
+
+	var requestBody struct {
+		Phone        *string `json:"phone"`
+		Skills       *string `json:"skills"`
+		Availability *string `json:"availability"`
+		About        *string `json:"about"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+	updates := map[string]interface{}{}
+	if requestBody.Phone != nil {
+		updates["phone"] = strings.TrimSpace(*requestBody.Phone)
+	}
+	if requestBody.Skills != nil {
+		updates["skills"] = *requestBody.Skills
+	}
+	if requestBody.Availability != nil {
+		updates["availability"] = *requestBody.Availability
+	}
+	if requestBody.About != nil {
+		updates["about"] = *requestBody.About
+	}
+	if len(updates) > 0 {
+		if err := config.DB.Model(volunteer).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Profile updated"})
+}
+
+// SignUpForShift - Sign up for a shift through a volunteer link
+func SignUpForShift(c *gin.Context) {
+	volunteer, ok := findVolunteerByToken(c)
+	if !ok {
+		return
+	}
+	shift, ok := findShift(c)
+	if !ok {
+		return
+	}
+
+	signup, err := signUpForShift(volunteer.ID, shift.ID)
+	if errors.Is(err, errShiftFull) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This shift is full, cancelled or has already started"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign up for shift"})
+		return
+	}
+	signup.Shift = shift
+	c.JSON(http.StatusCreated, signup)
+}
+
+// CancelShiftSignup - Withdraw from a shift that has not started through a
+// volunteer link, freeing the place for someone else
+func CancelShiftSignup(c *gin.Context) {
+	volunteer, ok := findVolunteerByToken(c)
+	if !ok {
+		return
+	}
+	shift, ok := findShift(c)
+	if !ok {
+		return
+	}
+	if !shift.StartsAt.After(time.Now()) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This shift has already started"})
+		return
+	}
+
+	var signup models.ShiftSignup
+	if err := config.DB.Where("shift_id = ? AND volunteer_id = ?", shift.ID, volunteer.ID).First(&signup).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You are not signed up for this shift"})
+		return
+	}
+	changed, err := cancelSignup(&signup)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel sign-up"})
+		return
+	}
+	if !changed {
+		c.JSON(http.StatusConflict, gin.H{"error": "You are not signed up for this shift"})
+		return
+	}
+	c.JSON(http.StatusOK, signup)
+}
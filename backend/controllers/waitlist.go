@@ -0,0 +1,521 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kholodihor/cows-shelter-backend/config"
+	"github.com/kholodihor/cows-shelter-backend/mailer"
+	"github.com/kholodihor/cows-shelter-backend/models"
+	"github.com/kholodihor/cows-shelter-backend/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errOfferGone is returned when an offer expired or was released during a claim
+var errOfferGone = errors.New("offer is no longer open")
+
+// activeWaitlistStatuses are the statuses of entries still in line or holding an offer
+var activeWaitlistStatuses = []string{models.WaitlistWaiting, models.WaitlistOffered}
+
+// waitlistOfferTTL is how long an offer holds its seats
+func waitlistOfferTTL() time.Duration {
+	return envDuration("WAITLIST_OFFER_TTL", 12*time.Hour)
+}
+
+// waitlistLink is the frontend page where a visitor manages a waitlist entry
+func waitlistLink(token string) string {
+	return fmt.Sprintf("%s/waitlist/%s", config.GetEnv("FRONTEND_URL", "http://localhost:5173"), token)
+}
+
+// waitlistOfferLink is the frontend page where a visitor claims an offer
+func waitlistOfferLink(token string) string {
+	return fmt.Sprintf("%s/waitlist/offers/%s", config.GetEnv("FRONTEND_URL", "http://localhost:5173"), token)
+}
+
+// pendingOffer is an offer made inside a transaction, emailed after commit
+type pendingOffer struct {
+	entry models.WaitlistEntry
+	token string
+}
+
+// offerFreedSeats holds free seats of a session for the waitlist and emails
+// the offers. Entries are served in the order they joined; an entry that
+// needs more seats than are free keeps its place while smaller parties
+// behind it are served.
+func offerFreedSeats(ctx context.Context, sessionID uint) {
+	var session models.ExcursionSession
+	var offers []pendingOffer
+	now := time.Now()
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Excursion").
+			Where("id = ?", sessionID).First(&session).Error; err != nil {
+			return err
+		}
+		if session.Status != models.SessionScheduled || !session.StartsAt.After(now) {
+			return nil
+		}
+
+		free := session.Capacity - session.BookedSeats
+		if free <= 0 {
+			return nil
+		}
+
+		var waiting []models.WaitlistEntry
+		if err := tx.Where("session_id = ? AND status = ?", sessionID, models.WaitlistWaiting).
+			Order("id").Find(&waiting).Error; err != nil {
+			return err
+		}
+
+		expiresAt := now.Add(waitlistOfferTTL())
+		if expiresAt.After(session.StartsAt) {
+			expiresAt = session.StartsAt
+		}
+
+		held := 0
+		for _, entry := range waiting {
+			if entry.Seats > free-held {
+				continue
+			}
+
+			token, err := utils.GenerateSecureToken(32)
+			if err != nil {
+				return err
+			}
+			claimHash := utils.HashToken(token)
+			if err := tx.Model(&entry).Updates(map[string]interface{}{
+				"status":           models.WaitlistOffered,
+				"claim_token_hash": claimHash,
+				"offered_at":       now,
+				"offer_expires_at": expiresAt,
+			}).Error; err != nil {
+				return err
+			}
+			entry.Status = models.WaitlistOffered
+			entry.OfferedAt = &now
+			entry.OfferExpiresAt = &expiresAt
+			offers = append(offers, pendingOffer{entry: entry, token: token})
+
+			held += entry.Seats
+			if held == free {
+				break
+			}
+		}
+		if held == 0 {
+			return nil
+		}
+		return tx.Model(&models.ExcursionSession{}).Where("id = ?", sessionID).
+			UpdateColumn("booked_seats", gorm.Expr("booked_seats + ?", held)).Error
+	})
+	if err != nil {
+		log.Printf("Failed to offer freed seats of session %d: %v", sessionID, err)
+		return
+	}
+
+	for _, offer := range offers {
+		if err := config.Mailer.Send(ctx, mailer.Message{
+			To:      offer.entry.Email,
+			Subject: fmt.Sprintf("Seats are available for %s", sessionTitle(&session)),
+			Body: fmt.Sprintf("Hello %s,\n\nGood news: %d seat(s) for %s on %s are now available for you.\n\n"+
+				"We hold them until %s. Open the link below to claim them; if you don't, "+
+				"they are offered to the next person on the waitlist.\n\n%s\n",
+				offer.entry.Name, offer.entry.Seats, sessionTitle(&session), formatSessionTime(session.StartsAt),
+				formatSessionTime(*offer.entry.OfferExpiresAt), waitlistOfferLink(offer.token)),
+		}); err != nil {
+			log.Printf("Waitlist offer email for entry %d failed: %v", offer.entry.ID, err)
+		}
+	}
+}
+
+// releaseWaitlistEntry moves an entry out of the waitlist and gives back the
+// seats of an open offer. It reports whether the entry changed and whether
+// seats were released.
+func releaseWaitlistEntry(entry *models.WaitlistEntry, to string) (changed, released bool, err error) {
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// The entry may have been offered seats or claimed since it was
+		// loaded, so the seats to give back depend on its current status
+		var current models.WaitlistEntry
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND status IN ?", entry.ID, activeWaitlistStatuses).
+			First(&current).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		if err := tx.Model(&models.WaitlistEntry{}).
+			Where("id = ? AND status = ?", current.ID, current.Status).
+			Updates(map[string]interface{}{"status": to, "claim_token_hash": nil}).Error; err != nil {
+			return err
+		}
+		changed = true
+
+		if current.Status == models.WaitlistOffered {
+			released = true
+			if err := releaseSeats(tx, current.SessionID, current.Seats); err != nil {
+				return err
+			}
+		}
+		entry.Status = to
+		entry.ClaimTokenHash = nil
+		return nil
+	})
+	return changed, released, err
+}
+
+// findWaitlistEntry loads the entry for a waitlist link
+func findWaitlistEntry(c *gin.Context) (*models.WaitlistEntry, bool) {
+	var entry models.WaitlistEntry
+	if err := config.DB.Preload("Session.Excursion").
+		Where("token_hash = ?", utils.HashToken(c.Param("token"))).First(&entry).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Waitlist entry not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching waitlist entry"})
+		return nil, false
+	}
+	return &entry, true
+}
+
+// findWaitlistOffer loads the offered entry for a claim link
+func findWaitlistOffer(c *gin.Context) (*models.WaitlistEntry, bool) {
+	var entry models.WaitlistEntry
+	err := config.DB.Preload("Session.Excursion").
+		Where("claim_token_hash = ? AND status = ? AND offer_expires_at > ?",
+			utils.HashToken(c.Param("token")), models.WaitlistOffered, time.Now()).
+		First(&entry).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "This offer has expired or was already used"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching offer"})
+		return nil, false
+	}
+	return &entry, true
+}
+
+// JoinWaitlist - Join the waitlist of a session that does not have enough seats left
+func JoinWaitlist(c *gin.Context) {
+	var requestBody struct {
+		Name  string `json:"name" binding:"required"`
+		Email string `json:"email" binding:"required,email"`
+		Phone string `json:"phone"`
+		Seats int    `json:"seats" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		return
+	}
+	if maxSeats := bookingMaxSeats(); requestBody.Seats > maxSeats {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A booking can have at most %d seats", maxSeats)})
+		return
+	}
+
+	wait, err := allowPublicRequest(models.ThrottleWaitlistIP, c.ClientIP(),
+		envInt("WAITLIST_JOINS_PER_IP", 5), publicFormWindow())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking waitlist"})
+		return
+	}
+	if wait > 0 {
+		respondThrottled(c, wait)
+		return
+	}
+
+	session, ok := findSession(c)
+	if !ok {
+		return
+	}
+	if session.Status != models.SessionScheduled || !session.StartsAt.After(time.Now()) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This session can no longer be booked"})
+		return
+	}
+	if requestBody.Seats > session.Capacity {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("This session has only %d seats", session.Capacity)})
+		return
+	}
+	if session.SeatsLeft >= requestBody.Seats {
+		c.JSON(http.StatusConflict, gin.H{"error": "Seats are still available, please book directly", "seats_left": session.SeatsLeft})
+		return
+	}
+
+	email := normalizeEmail(requestBody.Email)
+	var existing int64
+	if err := config.DB.Model(&models.WaitlistEntry{}).
+		Where("session_id = ? AND email = ? AND status IN ?", session.ID, email, activeWaitlistStatuses).
+		Count(&existing).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking waitlist"})
+		return
+	}
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "You are already on the waitlist for this session"})
+		return
+	}
+
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error joining waitlist"})
+		return
+	}
+
+	entry := models.WaitlistEntry{
+		SessionID: session.ID,
+		Name:      requestBody.Name,
+		Email:     email,
+		Phone:     requestBody.Phone,
+		Seats:     requestBody.Seats,
+		Status:    models.WaitlistWaiting,
+		TokenHash: utils.HashToken(token),
+	}
+	if err := config.DB.Create(&entry).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error joining waitlist"})
+		return
+	}
+
+	var ahead int64
+	config.DB.Model(&models.WaitlistEntry{}).
+		Where("session_id = ? AND status = ? AND id < ?", session.ID, models.WaitlistWaiting, entry.ID).
+		Count(&ahead)
+
+	if err := config.Mailer.Send(c.Request.Context(), mailer.Message{
+		To:      entry.Email,
+		Subject: fmt.Sprintf("You are on the waitlist for %s", sessionTitle(session)),
+		Body: fmt.Sprintf("Hello %s,\n\nYou are on the waitlist for %d seat(s) for %s on %s. "+
+			"If seats free up, we will email you a link to claim them.\n\n"+
+			"You can check your place or leave the waitlist here:\n\n%s\n",
+			entry.Name, entry.Seats, sessionTitle(session), formatSessionTime(session.StartsAt), waitlistLink(token)),
+	}); err != nil {
+		log.Printf("Waitlist email for entry %d failed: %v", entry.ID, err)
+	}
+
+	// Seats may have been freed between the check and the insert
+	offerFreedSeats(c.Request.Context(), session.ID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "You are on the waitlist; we will email you if seats free up",
+		"entry":    entry,
+		"position": ahead + 1,
+	})
+}
+
+// GetWaitlistEntry - Show a waitlist entry and its place in line to the holder of its link
+func GetWaitlistEntry(c *gin.Context) {
+	entry, ok := findWaitlistEntry(c)
+	if !ok {
+		return
+	}
+
+	response := gin.H{"entry": entry}
+	if entry.Status == models.WaitlistWaiting {
+		var ahead int64
+		if err := config.DB.Model(&models.WaitlistEntry{}).
+			Where("session_id = ? AND status = ? AND id < ?", entry.SessionID, models.WaitlistWaiting, entry.ID).
+			Count(&ahead).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching waitlist"})
+			return
+		}
+		response["position"] = ahead + 1
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// LeaveWaitlist - Remove a waitlist entry through its link, giving up any open offer
+func LeaveWaitlist(c *gin.Context) {
+	entry, ok := findWaitlistEntry(c)
+	if !ok {
+		return
+	}
+
+	changed, released, err := releaseWaitlistEntry(entry, models.WaitlistCancelled)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error leaving waitlist"})
+		return
+	}
+	if !changed {
+		c.JSON(http.StatusConflict, gin.H{"error": "This waitlist entry is no longer active"})
+		return
+	}
+	if released {
+		offerFreedSeats(c.Request.Context(), entry.SessionID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "You have left the waitlist"})
+}
+
+// GetWaitlistOffer - Show an open offer to the holder of its claim link
+func GetWaitlistOffer(c *gin.Context) {
+	entry, ok := findWaitlistOffer(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+// ClaimWaitlistOffer - Turn an open offer into a confirmed booking. The seats
+// were already held when the offer was made.
+func ClaimWaitlistOffer(c *gin.Context) {
+	entry, ok := findWaitlistOffer(c)
+	if !ok {
+		return
+	}
+
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error claiming offer"})
+		return
+	}
+
+	now := time.Now()
+	booking := models.Booking{
+		SessionID:   entry.SessionID,
+		Name:        entry.Name,
+		Email:       entry.Email,
+		Phone:       entry.Phone,
+		Seats:       entry.Seats,
+		Status:      models.BookingConfirmed,
+		TokenHash:   utils.HashToken(token),
+		ConfirmedAt: &now,
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&booking).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&models.WaitlistEntry{}).
+			Where("id = ? AND status = ? AND offer_expires_at > ?", entry.ID, models.WaitlistOffered, now).
+			Updates(map[string]interface{}{
+				"status":           models.WaitlistClaimed,
+				"claim_token_hash": nil,
+				"booking_id":       booking.ID,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			// The offer expired or was released meanwhile; undo the booking
+			return errOfferGone
+		}
+		return nil
+	})
+	if errors.Is(err, errOfferGone) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This offer has expired or was already used"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error claiming offer"})
+		return
+	}
+
+	if err := config.Mailer.Send(c.Request.Context(), mailer.Message{
+		To:      booking.Email,
+		Subject: fmt.Sprintf("Your booking for %s is confirmed", sessionTitle(entry.Session)),
+		Body: fmt.Sprintf("Hello %s,\n\nYour booking of %d seat(s) for %s on %s is confirmed.\n\n"+
+			"You can view or cancel it here:\n\n%s\n",
+			booking.Name, booking.Seats, sessionTitle(entry.Session), formatSessionTime(entry.Session.StartsAt),
+			bookingLink(token)),
+	}); err != nil {
+		log.Printf("Booking email for booking %d failed: %v", booking.ID, err)
+	}
+
+	c.JSON(http.StatusCreated, booking)
+}
+
+// DeclineWaitlistOffer - Give up an open offer so the seats go to the next person in line
+func DeclineWaitlistOffer(c *gin.Context) {
+	entry, ok := findWaitlistOffer(c)
+	if !ok {
+		return
+	}
+
+	changed, released, err := releaseWaitlistEntry(entry, models.WaitlistCancelled)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error declining offer"})
+		return
+	}
+	if !changed {
+		c.JSON(http.StatusConflict, gin.H{"error": "This offer has expired or was already used"})
+		return
+	}
+	if released {
+		offerFreedSeats(c.Request.Context(), entry.SessionID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Offer declined"})
+}
+
+// GetSessionWaitlist - List the waitlist of a session in order. By default
+// only waiting and offered entries are shown; ?status=all includes the rest.
+func GetSessionWaitlist(c *gin.Context) {
+	session, ok := findSession(c)
+	if !ok {
+		return
+	}
+
+	entries := []models.WaitlistEntry{}
+	query := config.DB.Where("session_id = ?", session.ID).Order("id")
+	switch status := c.Query("status"); status {
+	case "":
+		query = query.Where("status IN ?", activeWaitlistStatuses)
+	case "all":
+	default:
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching waitlist"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session": session,
+		"entries": entries,
+	})
+}
+
+// ExpireWaitlistOffers releases the seats of offers that were not claimed in
+// time and offers them to the next people in line. Entries of sessions that
+// have started are closed.
+func ExpireWaitlistOffers(ctx context.Context) (int, error) {
+	now := time.Now()
+
+	var entries []models.WaitlistEntry
+	if err := config.DB.Where("status = ? AND offer_expires_at <= ?", models.WaitlistOffered, now).
+		Find(&entries).Error; err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	sessions := map[uint]bool{}
+	for i := range entries {
+		changed, released, err := releaseWaitlistEntry(&entries[i], models.WaitlistExpired)
+		if err != nil {
+			return expired, err
+		}
+		if changed {
+			expired++
+		}
+		if released {
+			sessions[entries[i].SessionID] = true
+		}
+	}
+	for sessionID := range sessions {
+		offerFreedSeats(ctx, sessionID)
+	}
+
+	// Nobody can be offered seats once the session has started
+	if err := config.DB.Model(&models.WaitlistEntry{}).
+		Where("status = ? AND session_id IN (?)", models.WaitlistWaiting,
+			config.DB.Model(&models.ExcursionSession{}).Select("id").Where("starts_at <= ?", now)).
+		Update("status", models.WaitlistExpired).Error; err != nil {
+		return expired, err
+	}
+	return expired, nil
+}
@@ -0,0 +1,350 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kholodihor/cows-shelter-backend/config"
+	"github.com/kholodihor/cows-shelter-backend/models"
+	"github.com/kholodihor/cows-shelter-backend/payments"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxWebhookBody bounds the size of webhook payloads
+const maxWebhookBody = 1 << 20
+
+// campaignReferencePrefix marks a payment reference that names a campaign,
+// such as "campaign-12"
+const campaignReferencePrefix = "campaign-"
+
+// referencedCampaign returns the campaign a payment reference names, or nil
+func referencedCampaign(tx *gorm.DB, reference string) *models.Campaign {
+	id, err := strconv.ParseUint(strings.TrimPrefix(reference, campaignReferencePrefix), 10, 64)
+	if !strings.HasPrefix(reference, campaignReferencePrefix) || err != nil {
+		return nil
+	}
+	var campaign models.Campaign
+	if err := tx.Where("id = ?", id).First(&campaign).Error; err != nil {
+		log.Printf("Payment reference %q names an unknown campaign", reference)
+		return nil
+	}
+	return &campaign
+}
+
+// donationTransitionAllowed reports whether a provider event may move a
+// donation from one status to another. Events can arrive out of order, so a
+// settled payment never goes back to pending and a refund is final.
+func donationTransitionAllowed(from, to string) bool {
+	switch from {
+	case models.DonationPending:
+		return true
+	case models.DonationFailed:
+		return to == models.DonationCompleted
+	case models.DonationCompleted:
+		return to == models.DonationRefunded
+	}
+	return false
+}
+
+// applyPayment moves an existing donation to the state reported by a later
+// event of its payment. campaignCurrency is the currency of the donation's
+// campaign, if any. It reports whether the donation changed.
+func applyPayment(donation *models.Donation, payment *payments.Payment, receivedAt time.Time, campaignCurrency string) bool {
+	if payment.Status == models.DonationRefunded && donation.Status == models.DonationCompleted {
+		return applyRefund(donation, payment)
+	}
+	if donation.Status == payment.Status || !donationTransitionAllowed(donation.Status, payment.Status) {
+		return false
+	}
+	donation.Status = payment.Status
+	if payment.Status == models.DonationCompleted {
+		// Settled amounts are final, refunds keep the amount that was received
+		donation.Amount = payment.Amount
+		donation.ReceivedAt = receivedAt
+		// Amounts converted by an admin for a campaign in another currency stay
+		if donation.CampaignID != nil && campaignCurrency == payment.Currency {
+			donation.CampaignAmount = payment.Amount
+		}
+	}
+	return true
+}
+
+// applyRefund takes a refund of a completed donation off its amount. Only
+// when everything has been returned does the donation become refunded, keeping
+// the amount that was received. Refund totals only grow, so an event older
+// than one already applied changes nothing.
+func applyRefund(donation *models.Donation, payment *payments.Payment) bool {
+	received := donation.Amount + donation.RefundedAmount
+	refunded := payment.Refunded
+	if refunded <= 0 || refunded > received {
+		refunded = received
+	}
+	if refunded <= donation.RefundedAmount {
+		return false
+	}
+
+	donation.RefundedAmount = refunded
+	if refunded == received {
+		donation.Status = models.DonationRefunded
+		donation.Amount = received
+		return true
+	}
+
+	remaining := received - refunded
+	if donation.CampaignID != nil && donation.Amount > 0 {
+		// Converted campaign amounts shrink in proportion
+		donation.CampaignAmount = donation.CampaignAmount * remaining / donation.Amount
+	}
+	donation.Amount = remaining
+	return true
+}
+
+// recordPayment creates or updates the donation of a provider payment. It
+// returns the donation and its previous state, nil for a new donation.
+func recordPayment(tx *gorm.DB, source string, payment *payments.Payment) (*models.Donation, *models.Donation, error) {
+	var donation models.Donation
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("source = ? AND external_id = ?", source, payment.ExternalID).First(&donation).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, err
+	}
+
+	receivedAt := payment.OccurredAt
+	if receivedAt.IsZero() {
+		receivedAt = time.Now()
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		externalID := payment.ExternalID
+		donation = models.Donation{
+			Source:     source,
+			ExternalID: &externalID,
+			Amount:     payment.Amount,
+			Currency:   payment.Currency,
+			Status:     payment.Status,
+			DonorName:  payment.DonorName,
+			DonorEmail: normalizeEmail(payment.DonorEmail),
+			Anonymous:  payment.DonorName == "",
+			Message:    payment.Message,
+			ReceivedAt: receivedAt,
+		}
+		if err := validateDonation(&donation); err != nil {
+			return nil, nil, err
+		}
+		if err := applyDonationCampaign(&donation, referencedCampaign(tx, payment.Reference), nil); err != nil {
+			return nil, nil, err
+		}
+		if err := tx.Omit("Campaign").Create(&donation).Error; err != nil {
+			return nil, nil, err
+		}
+		return &donation, nil, nil
+	}
+
+	before := donation
+	var campaign models.Campaign
+	if donation.CampaignID != nil {
+		if err := tx.Select("currency").Where("id = ?", *donation.CampaignID).First(&campaign).Error; err != nil &&
+			!errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, err
+		}
+	}
+	if !applyPayment(&donation, payment, receivedAt, campaign.Currency) {
+		return &donation, &before, nil
+	}
+	if err := tx.Omit("Campaign").Save(&donation).Error; err != nil {
+		return nil, nil, err
+	}
+	return &donation, &before, nil
+}
+
+// processWebhookEvent records the payment of a stored event and marks the
+// event processed, ignored or failed
+func processWebhookEvent(c *gin.Context, provider payments.Provider, record *models.WebhookEvent, event *payments.Event) error {
+	var donation, before *models.Donation
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if event.Payment == nil {
+			record.Status = models.WebhookIgnored
+		} else {
+			var err error
+			if donation, before, err = recordPayment(tx, provider.Name(), event.Payment); err != nil {
+				return err
+			}
+			record.Status = models.WebhookProcessed
+			record.DonationID = &donation.ID
+		}
+		now := time.Now()
+		record.ProcessedAt = &now
+		record.Error = ""
+		return tx.Save(record).Error
+	})
+	if err != nil {
+		record.Status = models.WebhookFailed
+		record.Error = err.Error()
+		record.DonationID = nil
+		record.ProcessedAt = nil
+		if saveErr := config.DB.Save(record).Error; saveErr != nil {
+			log.Printf("Failed to mark %s webhook %s as failed: %v", record.Provider, record.EventID, saveErr)
+		}
+		return err
+	}
+
+	if donation != nil {
+		if before == nil {
+			recordAudit(c, models.AuditCreate, "donation", donation.ID, nil, *donation)
+		} else if before.Status != donation.Status || before.Amount != donation.Amount {
+			recordAudit(c, models.AuditUpdate, "donation", donation.ID, *before, *donation)
+		}
+	}
+	return nil
+}
+
+// HandlePaymentWebhook - Receive a webhook from a payment provider. The
+// signature is verified before anything is stored, each notification is
+// processed once however often it is delivered, and payments are recorded as
+// donations. Failures answer 5xx so the provider delivers the event again.
+func HandlePaymentWebhook(c *gin.Context) {
+	provider, ok := config.PaymentProviders[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown payment provider"})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBody))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read webhook body"})
+		return
+	}
+	if err := provider.Verify(c.Request.Context(), c.Request.Header, body); err != nil {
+		if errors.Is(err, payments.ErrInvalidSignature) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid webhook signature"})
+			return
+		}
+		log.Printf("Verifying %s webhook failed: %v", provider.Name(), err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Webhook verification is unavailable"})
+		return
+	}
+	event, err := provider.Parse(body)
+	if err != nil {
+		log.Printf("Parsing %s webhook failed: %v", provider.Name(), err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook payload"})
+		return
+	}
+
+	record := models.WebhookEvent{
+		Provider:  provider.Name(),
+		EventID:   event.ID,
+		EventType: event.Type,
+		Payload:   string(body),
+		Status:    models.WebhookReceived,
+	}
+	result := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store webhook event"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		if err := config.DB.Where("provider = ? AND event_id = ?", provider.Name(), event.ID).First(&record).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load webhook event"})
+			return
+		}
+		if record.Status != models.WebhookFailed {
+			c.JSON(http.StatusOK, gin.H{"status": "duplicate"})
+			return
+		}
+	}
+
+	if err := processWebhookEvent(c, provider, &record, event); err != nil {
+		log.Printf("Processing %s webhook %s failed: %v", record.Provider, record.EventID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process webhook event"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": record.Status})
+}
+
+// GetWebhookEvents - List stored webhook events with pagination; ?provider=
+// and ?status= filter
+func GetWebhookEvents(c *gin.Context) {
+	var events []models.WebhookEvent
+	var total int64
+
+	// Default values for pagination
+	limit := 20
+	page := 1
+
+	if l := c.Query("limit"); l != "" {
+		fmt.Sscanf(l, "%d", &limit)
+	}
+	if p := c.Query("page"); p != "" {
+		fmt.Sscanf(p, "%d", &page)
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	if page < 1 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	query := config.DB.Model(&models.WebhookEvent{})
+	if provider := c.Query("provider"); provider != "" {
+		query = query.Where("provider = ?", provider)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching webhook events"})
+		return
+	}
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching webhook events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       events,
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+		"totalPages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// RetryWebhookEvent - Process a failed webhook event again from its stored
+// payload, which was verified when it arrived
+func RetryWebhookEvent(c *gin.Context) {
+	var record models.WebhookEvent
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&record).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook event not found"})
+		return
+	}
+	if record.Status != models.WebhookFailed {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only failed webhook events can be retried"})
+		return
+	}
+	provider, ok := config.PaymentProviders[record.Provider]
+	if !ok {
+		c.JSON(http.StatusConflict, gin.H{"error": "Payment provider " + record.Provider + " is not configured"})
+		return
+	}
+
+	event, err := provider.Parse([]byte(record.Payload))
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Stored payload cannot be parsed: " + err.Error()})
+		return
+	}
+	if err := processWebhookEvent(c, provider, &record, event); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process webhook event: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, record)
+}
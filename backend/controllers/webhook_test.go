@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kholodihor/cows-shelter-backend/models"
+	"github.com/kholodihor/cows-shelter-backend/payments"
+)
+
+func TestDonationTransitionAllowed(t *testing.T) {
+	statuses := []string{models.DonationPending, models.DonationCompleted, models.DonationFailed, models.DonationRefunded}
+	allowed := map[[2]string]bool{
+		{models.DonationPending, models.DonationPending}:    true,
+		{models.DonationPending, models.DonationCompleted}:  true,
+		{models.DonationPending, models.DonationFailed}:     true,
+		{models.DonationPending, models.DonationRefunded}:   true,
+		{models.DonationFailed, models.DonationCompleted}:   true,
+		{models.DonationCompleted, models.DonationRefunded}: true,
+	}
+
+	for _, from := range statuses {
+		for _, to := range statuses {
+			if got := donationTransitionAllowed(from, to); got != allowed[[2]string{from, to}] {
+				t.Errorf("%s -> %s: got %v", from, to, got)
+			}
+		}
+	}
+}
+
+func TestApplyPayment(t *testing.T) {
+	received := time.Date(2025, 3, 8, 10, 15, 0, 0, time.UTC)
+	settled := time.Date(2025, 3, 8, 10, 16, 3, 0, time.UTC)
+	campaignID := uint(1)
+
+	tests := []struct {
+		name     string
+		from     models.Donation
+		campaign string // currency of the donation's campaign
+		payment  payments.Payment
+		changed  bool
+		want     models.Donation
+	}{
+		{
+			name:    "settling takes the final amount and time",
+			from:    models.Donation{Status: models.DonationPending, Amount: 50000, Currency: "UAH", ReceivedAt: received},
+			payment: payments.Payment{Status: payments.StatusCompleted, Amount: 49000, Currency: "UAH"},
+			changed: true,
+			want:    models.Donation{Status: models.DonationCompleted, Amount: 49000, Currency: "UAH", ReceivedAt: settled},
+		},
+		{
+			name: "settling credits a campaign in the same currency",
+			from: models.Donation{Status: models.DonationPending, Amount: 50000, Currency: "UAH",
+				CampaignID: &campaignID, CampaignAmount: 50000, ReceivedAt: received},
+			campaign: "UAH",
+			payment:  payments.Payment{Status: payments.StatusCompleted, Amount: 49000, Currency: "UAH"},
+			changed:  true,
+			want: models.Donation{Status: models.DonationCompleted, Amount: 49000, Currency: "UAH",
+				CampaignID: &campaignID, CampaignAmount: 49000, ReceivedAt: settled},
+		},
+		{
+			name: "campaigns in another currency keep their converted amount",
+			from: models.Donation{Status: models.DonationPending, Amount: 2500, Currency: "USD",
+				CampaignID: &campaignID, CampaignAmount: 100000, ReceivedAt: received},
+			campaign: "UAH",
+			payment:  payments.Payment{Status: payments.StatusCompleted, Amount: 2400, Currency: "USD"},
+			changed:  true,
+			want: models.Donation{Status: models.DonationCompleted, Amount: 2400, Currency: "USD",
+				CampaignID: &campaignID, CampaignAmount: 100000, ReceivedAt: settled},
+		},
+		{
+			name:    "a refund without a total refunds everything and keeps the amount that was received",
+			from:    models.Donation{Status: models.DonationCompleted, Amount: 50000, Currency: "UAH", ReceivedAt: received},
+			payment: payments.Payment{Status: payments.StatusRefunded, Amount: 0, Currency: "UAH"},
+			changed: true,
+			want:    models.Donation{Status: models.DonationRefunded, Amount: 50000, RefundedAmount: 50000, Currency: "UAH", ReceivedAt: received},
+		},
+		{
+			name: "a partial refund takes the refunded part off",
+			from: models.Donation{Status: models.DonationCompleted, Amount: 10000, Currency: "UAH",
+				CampaignID: &campaignID, CampaignAmount: 10000, ReceivedAt: received},
+			campaign: "UAH",
+			payment:  payments.Payment{Status: payments.StatusRefunded, Amount: 500, Refunded: 500, Currency: "UAH"},
+			changed:  true,
+			want: models.Donation{Status: models.DonationCompleted, Amount: 9500, RefundedAmount: 500, Currency: "UAH",
+				CampaignID: &campaignID, CampaignAmount: 9500, ReceivedAt: received},
+		},
+		{
+			name: "a partial refund shrinks a converted campaign amount in proportion",
+			from: models.Donation{Status: models.DonationCompleted, Amount: 2500, Currency: "USD",
+				CampaignID: &campaignID, CampaignAmount: 100000, ReceivedAt: received},
+			campaign: "UAH",
+			payment:  payments.Payment{Status: payments.StatusRefunded, Amount: 500, Refunded: 500, Currency: "USD"},
+			changed:  true,
+			want: models.Donation{Status: models.DonationCompleted, Amount: 2000, RefundedAmount: 500, Currency: "USD",
+				CampaignID: &campaignID, CampaignAmount: 80000, ReceivedAt: received},
+		},
+		{
+			name:    "a further partial refund applies the new total",
+			from:    models.Donation{Status: models.DonationCompleted, Amount: 9500, RefundedAmount: 500, Currency: "UAH", ReceivedAt: received},
+			payment: payments.Payment{Status: payments.StatusRefunded, Amount: 2000, Refunded: 2500, Currency: "UAH"},
+			changed: true,
+			want:    models.Donation{Status: models.DonationCompleted, Amount: 7500, RefundedAmount: 2500, Currency: "UAH", ReceivedAt: received},
+		},
+		{
+			name:    "an older refund total changes nothing",
+			from:    models.Donation{Status: models.DonationCompleted, Amount: 7500, RefundedAmount: 2500, Currency: "UAH", ReceivedAt: received},
+			payment: payments.Payment{Status: payments.StatusRefunded, Amount: 500, Refunded: 500, Currency: "UAH"},
+			want:    models.Donation{Status: models.DonationCompleted, Amount: 7500, RefundedAmount: 2500, Currency: "UAH", ReceivedAt: received},
+		},
+		{
+			name:    "refunding the rest refunds the donation with the amount received",
+			from:    models.Donation{Status: models.DonationCompleted, Amount: 7500, RefundedAmount: 2500, Currency: "UAH", ReceivedAt: received},
+			payment: payments.Payment{Status: payments.StatusRefunded, Amount: 7500, Refunded: 10000, Currency: "UAH"},
+			changed: true,
+			want:    models.Donation{Status: models.DonationRefunded, Amount: 10000, RefundedAmount: 10000, Currency: "UAH", ReceivedAt: received},
+		},
+		{
+			name:    "a late pending event does not undo a settled payment",
+			from:    models.Donation{Status: models.DonationCompleted, Amount: 50000, Currency: "UAH", ReceivedAt: received},
+			payment: payments.Payment{Status: payments.StatusPending, Amount: 50000, Currency: "UAH"},
+			want:    models.Donation{Status: models.DonationCompleted, Amount: 50000, Currency: "UAH", ReceivedAt: received},
+		},
+		{
+			name:    "a refund is final",
+			from:    models.Donation{Status: models.DonationRefunded, Amount: 50000, Currency: "UAH", ReceivedAt: received},
+			payment: payments.Payment{Status: payments.StatusCompleted, Amount: 50000, Currency: "UAH"},
+			want:    models.Donation{Status: models.DonationRefunded, Amount: 50000, Currency: "UAH", ReceivedAt: received},
+		},
+		{
+			name:    "a repeated event changes nothing",
+			from:    models.Donation{Status: models.DonationCompleted, Amount: 50000, Currency: "UAH", ReceivedAt: received},
+			payment: payments.Payment{Status: payments.StatusCompleted, Amount: 49000, Currency: "UAH"},
+			want:    models.Donation{Status: models.DonationCompleted, Amount: 50000, Currency: "UAH", ReceivedAt: received},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			donation := tt.from
+			if changed := applyPayment(&donation, &tt.payment, settled, tt.campaign); changed != tt.changed {
+				t.Errorf("changed = %v, want %v", changed, tt.changed)
+			}
+			if donation.Status != tt.want.Status || donation.Amount != tt.want.Amount ||
+				donation.RefundedAmount != tt.want.RefundedAmount ||
+				donation.CampaignAmount != tt.want.CampaignAmount || !donation.ReceivedAt.Equal(tt.want.ReceivedAt) {
+				t.Errorf("got %s %d, %d refunded (campaign %d) at %v, want %s %d, %d refunded (campaign %d) at %v",
+					donation.Status, donation.Amount, donation.RefundedAmount, donation.CampaignAmount, donation.ReceivedAt,
+					tt.want.Status, tt.want.Amount, tt.want.RefundedAmount, tt.want.CampaignAmount, tt.want.ReceivedAt)
+			}
+		})
+	}
+}
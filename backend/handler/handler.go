@@ -84,21 +84,89 @@ func RouterHandler(c *Config) {
 	// Health check endpoint
 	c.R.GET("/health", HealthCheck)
 
-	// API routes
+	// Public keys for verifying tokens issued by this service
+	c.R.GET("/.well-known/jwks.json", controllers.GetJWKS)
+
+	// Public API routes
 	c.R.POST("/api/user", controllers.CreateUser)
+	c.R.GET("/api/invitations/:token", controllers.GetInvitationByToken)
 	c.R.POST("/api/login", controllers.LoginUser)
+	c.R.POST("/api/auth/login", controllers.LoginUser)
+	c.R.POST("/api/auth/refresh", controllers.RefreshToken)
+	c.R.POST("/api/auth/logout", controllers.Logout)
+	c.R.POST("/api/auth/2fa/verify", controllers.VerifyMFA)
+
+	// Single sign-on through the configured OpenID Connect provider
+	c.R.GET("/api/auth/oidc/login", controllers.OIDCLogin)
+	c.R.GET("/api/auth/oidc/callback", controllers.OIDCCallback)
+	c.R.POST("/api/auth/oidc/exchange", controllers.OIDCExchange)
+
+	// Two-factor enrollment also accepts the challenge issued when it is required at login
+	enrollment := c.R.Group("/api/auth/2fa")
+	enrollment.Use(middleware.EnrollmentAuthMiddleware())
+	{
+		enrollment.POST("/setup", controllers.SetupMFA)
+		enrollment.POST("/enable", controllers.EnableMFA)
+	}
+	c.R.POST("/api/password/forgot", controllers.ForgotPassword)
+	c.R.GET("/api/password/reset/:token", controllers.GetPasswordByToken)
+	c.R.POST("/api/password/reset", controllers.ResetPassword)
 	c.R.GET("/api/contacts", controllers.GetContacts)
+	c.R.GET("/api/animals/pagination", controllers.GetAnimals)
+	c.R.GET("/api/animals/needs-sponsor", controllers.GetAnimalsNeedingSponsor)
+	c.R.GET("/api/animals", controllers.GetAllAnimals)
+	c.R.GET("/api/animals/:id", controllers.GetAnimalByID)
+	c.R.POST("/api/animals/:id/sponsorships", controllers.RequestSponsorship)
+
+	// Sponsor pages are reached through the secret link emailed to the sponsor
+	c.R.POST("/api/sponsorships/link", controllers.RequestSponsorLinks)
+	c.R.GET("/api/sponsorships/:token", controllers.GetSponsorPage)
+	c.R.POST("/api/sponsorships/:token/cancel", controllers.CancelSponsorship)
 	c.R.GET("/api/excursions/pagination", controllers.GetExcursions)
 	c.R.GET("/api/excursions", controllers.GetAllExcursions)
+	c.R.GET("/api/excursions/calendar.ics", controllers.GetExcursionsCalendar)
 	c.R.GET("/api/excursions/:id", controllers.GetExcursionByID)
+	c.R.GET("/api/excursions/:id/sessions", controllers.GetExcursionSessions)
+	c.R.GET("/api/excursions/:id/schedules", controllers.GetExcursionSchedules)
+	c.R.GET("/api/excursions/:id/calendar.ics", controllers.GetExcursionCalendar)
+	c.R.GET("/api/blackout-dates", controllers.GetBlackoutDates)
+	c.R.GET("/api/campaigns", controllers.GetCampaigns)
+	c.R.GET("/api/campaigns/:id", controllers.GetCampaignByID)
+	c.R.GET("/api/campaigns/:id/donations", controllers.GetCampaignDonations)
+	c.R.GET("/api/donations/qr", controllers.GetDonationQR)
+
+	// Donors download their receipt through the secret link emailed to them
+	c.R.GET("/api/receipts/:token", controllers.GetReceiptByToken)
+	c.R.GET("/api/reports/monthly", controllers.GetMonthlyFinance)
+	c.R.GET("/api/shifts", controllers.GetShifts)
+	c.R.POST("/api/volunteers", controllers.ApplyAsVolunteer)
+
+	// Volunteers manage their shifts through the secret link sent on approval
+	c.R.GET("/api/volunteers/:token", controllers.GetVolunteerPage)
+	c.R.PATCH("/api/volunteers/:token", controllers.UpdateVolunteerPage)
+	c.R.POST("/api/volunteers/:token/shifts/:id", controllers.SignUpForShift)
+	c.R.DELETE("/api/volunteers/:token/shifts/:id", controllers.CancelShiftSignup)
+
+	// Payment provider notifications, authenticated by their signatures
+	c.R.POST("/api/webhooks/:provider", controllers.HandlePaymentWebhook)
+	c.R.POST("/api/excursion-sessions/:id/bookings", controllers.CreateBooking)
+	c.R.POST("/api/excursion-sessions/:id/waitlist", controllers.JoinWaitlist)
+
+	// Visitors manage bookings through the secret link emailed to them
+	c.R.GET("/api/bookings/:token", controllers.GetBookingByToken)
+	c.R.POST("/api/bookings/:token/confirm", controllers.ConfirmBooking)
+	c.R.POST("/api/bookings/:token/cancel", controllers.CancelBookingByToken)
+	c.R.GET("/api/waitlist/:token", controllers.GetWaitlistEntry)
+	c.R.DELETE("/api/waitlist/:token", controllers.LeaveWaitlist)
+	c.R.GET("/api/waitlist-offers/:token", controllers.GetWaitlistOffer)
+	c.R.POST("/api/waitlist-offers/:token/claim", controllers.ClaimWaitlistOffer)
+	c.R.POST("/api/waitlist-offers/:token/decline", controllers.DeclineWaitlistOffer)
 	c.R.GET("/api/gallery/pagination", controllers.GetGalleries)
 	c.R.GET("/api/gallery", controllers.GetAllGalleries)
+	c.R.GET("/api/gallery/:id", controllers.GetGalleryByID)
 	c.R.GET("/api/reviews/pagination", controllers.GetReviews)
 	c.R.GET("/api/reviews", controllers.GetAllReviews)
 	c.R.GET("/api/reviews/:id", controllers.GetReviewByID)
-	c.R.POST("/api/reviews", controllers.CreateReview)
-	c.R.PATCH("/api/reviews/:id", controllers.UpdateReview)
-	c.R.DELETE("/api/reviews/:id", controllers.DeleteReview)
 	c.R.GET("/api/news/pagination", controllers.GetNews)
 	c.R.GET("/api/news", controllers.GetAllNews)
 	c.R.GET("/api/news/:id", controllers.GetNewsByID)
@@ -106,29 +174,158 @@ func RouterHandler(c *Config) {
 	c.R.GET("/api/partners", controllers.GetAllPartners)
 	c.R.GET("/api/partners/pagination", controllers.GetPartners)
 	c.R.GET("/api/partners/:id", controllers.GetPartnerByID)
-	c.R.POST("/api/excursions", controllers.CreateExcursion)
-	c.R.POST("/api/upload-image", controllers.UploadImage)
-	c.R.POST("/api/gallery", controllers.CreateGallery)
-	c.R.POST("/api/news", controllers.CreateNews)
-	c.R.POST("/api/partners", controllers.CreatePartner)
-	c.R.POST("/api/pdf", controllers.CreatePdf)
-	c.R.POST("/api/contacts", controllers.CreateContact)
-	c.R.PUT("/api/contacts/:id", controllers.UpdateContact)
-	c.R.PATCH("/api/contacts/:id", controllers.UpdateContact)
-	c.R.PUT("/api/news/:id", controllers.UpdateNews)
-	c.R.PUT("/api/excursions/:id", controllers.UpdateExcursion)
-	c.R.PATCH("/api/excursions/:id", controllers.UpdateExcursion) // Add PATCH support for frontend compatibility
-	c.R.PUT("/api/partners/:id", controllers.UpdatePartner)
-	c.R.PATCH("/api/partners/:id", controllers.UpdatePartner) // Add PATCH support for consistency
-	c.R.DELETE("/api/news/:id", controllers.DeleteNews)
-	c.R.GET("/api/gallery/:id", controllers.GetGalleryByID)
-	c.R.DELETE("/api/gallery/:id", controllers.DeleteGallery)
-	c.R.DELETE("/api/excursions/:id", controllers.DeleteExcursion)
-	c.R.DELETE("/api/partners/:id", controllers.DeletePartner)
 
+	// Authenticated API routes, each guarded by the permission it needs
 	api := c.R.Group("/api")
 	api.Use(middleware.AuthMiddleware())
 	{
-		api.GET("/user/:id", controllers.GetUserByID)
+		api.GET("/user/:id", middleware.RequirePermission("users:read"), controllers.GetUserByID)
+		api.GET("/me", middleware.RejectAPIKeys(), controllers.GetMe)
+		api.PATCH("/me", middleware.RejectAPIKeys(), controllers.UpdateMe)
+		api.GET("/me/sessions", middleware.RejectAPIKeys(), controllers.GetMySessions)
+		api.DELETE("/me/sessions", middleware.RejectAPIKeys(), controllers.DeleteMySessions)
+		api.DELETE("/me/sessions/:id", middleware.RejectAPIKeys(), controllers.DeleteMySession)
+		api.POST("/password/change", middleware.RejectAPIKeys(), controllers.ChangePassword)
+		api.POST("/auth/2fa/disable", middleware.RejectAPIKeys(), controllers.DisableMFA)
+		api.POST("/auth/2fa/recovery-codes", middleware.RejectAPIKeys(), controllers.RegenerateRecoveryCodes)
+
+		api.GET("/admin/users", middleware.RequirePermission("users:read"), controllers.GetUsers)
+		api.GET("/admin/users/:id", middleware.RequirePermission("users:read"), controllers.GetUserByID)
+		api.POST("/admin/users/invite", middleware.RequirePermission("users:write"), controllers.CreateInvitation)
+		api.PATCH("/admin/users/:id/role", middleware.RequirePermission("users:write"), controllers.UpdateUserRole)
+		api.POST("/admin/users/:id/deactivate", middleware.RequirePermission("users:write"), controllers.DeactivateUser)
+		api.POST("/admin/users/:id/reactivate", middleware.RequirePermission("users:write"), controllers.ReactivateUser)
+		api.POST("/admin/users/:id/password-reset", middleware.RequirePermission("users:write"), controllers.ForcePasswordReset)
+		api.GET("/admin/users/:id/sessions", middleware.RequirePermission("users:read"), controllers.GetUserSessions)
+		api.DELETE("/admin/users/:id/sessions", middleware.RequirePermission("users:write"), controllers.DeleteUserSessions)
+		api.DELETE("/admin/users/:id/sessions/:session_id", middleware.RequirePermission("users:write"), controllers.DeleteUserSession)
+
+		api.GET("/admin/invitations", middleware.RequirePermission("users:read"), controllers.GetInvitations)
+		api.POST("/admin/invitations", middleware.RequirePermission("users:write"), controllers.CreateInvitation)
+		api.DELETE("/admin/invitations/:id", middleware.RequirePermission("users:write"), controllers.RevokeInvitation)
+
+		api.GET("/admin/api-keys", middleware.RejectAPIKeys(), middleware.RequirePermission("apikeys:read"), controllers.GetAPIKeys)
+		api.POST("/admin/api-keys", middleware.RejectAPIKeys(), middleware.RequirePermission("apikeys:write"), controllers.CreateAPIKey)
+		api.DELETE("/admin/api-keys/:id", middleware.RejectAPIKeys(), middleware.RequirePermission("apikeys:write"), controllers.RevokeAPIKey)
+
+		api.GET("/admin/audit", middleware.RequirePermission("audit:read"), controllers.GetAuditLog)
+
+		api.GET("/admin/lockouts", middleware.RequirePermission("security:read"), controllers.GetLockouts)
+		api.DELETE("/admin/lockouts/:id", middleware.RequirePermission("security:write"), controllers.DeleteLockout)
+
+		api.POST("/animals", middleware.RequirePermission("animals:write"), controllers.CreateAnimal)
+		api.PUT("/animals/:id", middleware.RequirePermission("animals:write"), controllers.UpdateAnimal)
+		api.PATCH("/animals/:id", middleware.RequirePermission("animals:write"), controllers.UpdateAnimal)
+		api.DELETE("/animals/:id", middleware.RequirePermission("animals:write"), controllers.DeleteAnimal)
+		api.POST("/animals/:id/photos", middleware.RequirePermission("animals:write"), controllers.AddAnimalPhoto)
+		api.PUT("/animals/:id/photos/order", middleware.RequirePermission("animals:write"), controllers.ReorderAnimalPhotos)
+		api.DELETE("/animals/:id/photos/:photo_id", middleware.RequirePermission("animals:write"), controllers.DeleteAnimalPhoto)
+
+		// Medical data is admin-only and never exposed on public animal endpoints
+		api.GET("/admin/animals/:id/medical", middleware.RequirePermission("medical:read"), controllers.GetAnimalMedicalRecords)
+		api.POST("/admin/animals/:id/medical", middleware.RequirePermission("medical:write"), controllers.CreateMedicalRecord)
+		api.GET("/admin/medical/reminders", middleware.RequirePermission("medical:read"), controllers.GetMedicalReminders)
+		api.GET("/admin/medical/:id", middleware.RequirePermission("medical:read"), controllers.GetMedicalRecordByID)
+		api.PATCH("/admin/medical/:id", middleware.RequirePermission("medical:write"), controllers.UpdateMedicalRecord)
+		api.DELETE("/admin/medical/:id", middleware.RequirePermission("medical:write"), controllers.DeleteMedicalRecord)
+		api.POST("/admin/medical/:id/attachments", middleware.RequirePermission("medical:write"), controllers.AddMedicalAttachment)
+		api.GET("/admin/medical/:id/attachments/:attachment_id", middleware.RequirePermission("medical:read"), controllers.DownloadMedicalAttachment)
+		api.DELETE("/admin/medical/:id/attachments/:attachment_id", middleware.RequirePermission("medical:write"), controllers.DeleteMedicalAttachment)
+
+		api.GET("/admin/sponsorships", middleware.RequirePermission("sponsorships:read"), controllers.GetSponsorships)
+		api.GET("/admin/sponsorships/coverage", middleware.RequirePermission("sponsorships:read"), controllers.GetSponsorshipCoverage)
+		api.POST("/admin/sponsorships", middleware.RequirePermission("sponsorships:write"), controllers.CreateSponsorship)
+		api.PATCH("/admin/sponsorships/:id", middleware.RequirePermission("sponsorships:write"), controllers.UpdateSponsorship)
+		api.POST("/admin/sponsorships/:id/link", middleware.RequirePermission("sponsorships:write"), controllers.ResendSponsorLink)
+
+		api.GET("/admin/campaigns", middleware.RequirePermission("donations:read"), controllers.GetAdminCampaigns)
+		api.POST("/admin/campaigns", middleware.RequirePermission("donations:write"), controllers.CreateCampaign)
+		api.PATCH("/admin/campaigns/:id", middleware.RequirePermission("donations:write"), controllers.UpdateCampaign)
+		api.DELETE("/admin/campaigns/:id", middleware.RequirePermission("donations:write"), controllers.DeleteCampaign)
+		api.GET("/admin/donations", middleware.RequirePermission("donations:read"), controllers.GetDonations)
+		api.GET("/admin/donations/report", middleware.RequirePermission("donations:read"), controllers.GetDonationReport)
+		api.POST("/admin/donations", middleware.RequirePermission("donations:write"), controllers.CreateDonation)
+		api.PATCH("/admin/donations/:id", middleware.RequirePermission("donations:write"), controllers.UpdateDonation)
+		api.DELETE("/admin/donations/:id", middleware.RequirePermission("donations:write"), controllers.DeleteDonation)
+		api.POST("/admin/donations/:id/receipt", middleware.RequirePermission("donations:write"), controllers.IssueReceipt)
+		api.GET("/admin/receipts", middleware.RequirePermission("donations:read"), controllers.GetReceipts)
+		api.GET("/admin/receipts/:id", middleware.RequirePermission("donations:read"), controllers.GetReceiptByID)
+		api.GET("/admin/receipts/:id/document", middleware.RequirePermission("donations:read"), controllers.DownloadReceipt)
+		api.POST("/admin/receipts/:id/regenerate", middleware.RequirePermission("donations:write"), controllers.RegenerateReceipt)
+		api.POST("/admin/receipts/:id/void", middleware.RequirePermission("donations:write"), controllers.VoidReceipt)
+		api.GET("/admin/webhook-events", middleware.RequirePermission("donations:read"), controllers.GetWebhookEvents)
+		api.POST("/admin/webhook-events/:id/retry", middleware.RequirePermission("donations:write"), controllers.RetryWebhookEvent)
+		api.GET("/admin/expenses", middleware.RequirePermission("expenses:read"), controllers.GetExpenses)
+		api.GET("/admin/expenses/:id", middleware.RequirePermission("expenses:read"), controllers.GetExpenseByID)
+		api.GET("/admin/expenses/:id/receipt", middleware.RequirePermission("expenses:read"), controllers.DownloadExpenseReceipt)
+		api.POST("/admin/expenses", middleware.RequirePermission("expenses:write"), controllers.CreateExpense)
+		api.PATCH("/admin/expenses/:id", middleware.RequirePermission("expenses:write"), controllers.UpdateExpense)
+		api.DELETE("/admin/expenses/:id", middleware.RequirePermission("expenses:write"), controllers.DeleteExpense)
+		api.POST("/admin/reports/monthly/:month/publish", middleware.RequirePermission("expenses:write"), controllers.PublishMonthlyFinance)
+
+		api.GET("/admin/volunteers", middleware.RequirePermission("volunteers:read"), controllers.GetVolunteers)
+		api.GET("/admin/volunteers/hours", middleware.RequirePermission("volunteers:read"), controllers.GetVolunteerHours)
+		api.GET("/admin/volunteers/:id", middleware.RequirePermission("volunteers:read"), controllers.GetVolunteerByID)
+		api.PATCH("/admin/volunteers/:id", middleware.RequirePermission("volunteers:write"), controllers.UpdateVolunteer)
+		api.DELETE("/admin/volunteers/:id", middleware.RequirePermission("volunteers:write"), controllers.DeleteVolunteer)
+		api.POST("/admin/volunteers/:id/approve", middleware.RequirePermission("volunteers:write"), controllers.ApproveVolunteer)
+		api.POST("/admin/volunteers/:id/reject", middleware.RequirePermission("volunteers:write"), controllers.RejectVolunteer)
+		api.POST("/admin/volunteers/:id/deactivate", middleware.RequirePermission("volunteers:write"), controllers.DeactivateVolunteer)
+		api.POST("/admin/volunteers/:id/link", middleware.RequirePermission("volunteers:write"), controllers.ResendVolunteerLink)
+		api.GET("/admin/shifts", middleware.RequirePermission("volunteers:read"), controllers.GetAdminShifts)
+		api.POST("/admin/shifts", middleware.RequirePermission("volunteers:write"), controllers.CreateShift)
+		api.PATCH("/admin/shifts/:id", middleware.RequirePermission("volunteers:write"), controllers.UpdateShift)
+		api.DELETE("/admin/shifts/:id", middleware.RequirePermission("volunteers:write"), controllers.DeleteShift)
+		api.POST("/admin/shifts/:id/cancel", middleware.RequirePermission("volunteers:write"), controllers.CancelShift)
+		api.GET("/admin/shifts/:id/signups", middleware.RequirePermission("volunteers:read"), controllers.GetShiftSignups)
+		api.POST("/admin/shifts/:id/signups", middleware.RequirePermission("volunteers:write"), controllers.AddShiftSignup)
+		api.PATCH("/admin/shift-signups/:id", middleware.RequirePermission("volunteers:write"), controllers.UpdateShiftSignup)
+
+		api.GET("/admin/animals/:id/updates", middleware.RequirePermission("animals:read"), controllers.GetAnimalUpdates)
+		api.POST("/admin/animals/:id/updates", middleware.RequirePermission("animals:write"), controllers.CreateAnimalUpdate)
+		api.PATCH("/admin/animal-updates/:id", middleware.RequirePermission("animals:write"), controllers.UpdateAnimalUpdate)
+		api.DELETE("/admin/animal-updates/:id", middleware.RequirePermission("animals:write"), controllers.DeleteAnimalUpdate)
+
+		api.POST("/news", middleware.RequirePermission("news:write"), controllers.CreateNews)
+		api.PUT("/news/:id", middleware.RequirePermission("news:write"), controllers.UpdateNews)
+		api.DELETE("/news/:id", middleware.RequirePermission("news:write"), controllers.DeleteNews)
+
+		api.POST("/excursions", middleware.RequirePermission("excursions:write"), controllers.CreateExcursion)
+		api.PUT("/excursions/:id", middleware.RequirePermission("excursions:write"), controllers.UpdateExcursion)
+		api.PATCH("/excursions/:id", middleware.RequirePermission("excursions:write"), controllers.UpdateExcursion) // Add PATCH support for frontend compatibility
+		api.DELETE("/excursions/:id", middleware.RequirePermission("excursions:write"), controllers.DeleteExcursion)
+
+		api.POST("/excursions/:id/sessions", middleware.RequirePermission("excursions:write"), controllers.CreateExcursionSession)
+		api.POST("/excursions/:id/schedules", middleware.RequirePermission("excursions:write"), controllers.CreateExcursionSchedule)
+		api.PATCH("/excursion-schedules/:id", middleware.RequirePermission("excursions:write"), controllers.UpdateExcursionSchedule)
+		api.DELETE("/excursion-schedules/:id", middleware.RequirePermission("excursions:write"), controllers.DeleteExcursionSchedule)
+		api.POST("/blackout-dates", middleware.RequirePermission("excursions:write"), controllers.CreateBlackoutDate)
+		api.DELETE("/blackout-dates/:id", middleware.RequirePermission("excursions:write"), controllers.DeleteBlackoutDate)
+		api.PATCH("/excursion-sessions/:id", middleware.RequirePermission("excursions:write"), controllers.UpdateExcursionSession)
+		api.POST("/excursion-sessions/:id/cancel", middleware.RequirePermission("excursions:write"), controllers.CancelExcursionSession)
+		api.DELETE("/excursion-sessions/:id", middleware.RequirePermission("excursions:write"), controllers.DeleteExcursionSession)
+		api.GET("/admin/excursion-sessions", middleware.RequirePermission("bookings:read"), controllers.GetSessions)
+		api.GET("/admin/excursion-sessions/:id/attendees", middleware.RequirePermission("bookings:read"), controllers.GetSessionAttendees)
+		api.GET("/admin/excursion-sessions/:id/waitlist", middleware.RequirePermission("bookings:read"), controllers.GetSessionWaitlist)
+		api.POST("/admin/bookings/:id/cancel", middleware.RequirePermission("bookings:write"), controllers.AdminCancelBooking)
+
+		api.POST("/upload-image", middleware.RequirePermission("gallery:write"), controllers.UploadImage)
+		api.POST("/gallery", middleware.RequirePermission("gallery:write"), controllers.CreateGallery)
+		api.DELETE("/gallery/:id", middleware.RequirePermission("gallery:write"), controllers.DeleteGallery)
+
+		api.POST("/partners", middleware.RequirePermission("partners:write"), controllers.CreatePartner)
+		api.PUT("/partners/:id", middleware.RequirePermission("partners:write"), controllers.UpdatePartner)
+		api.PATCH("/partners/:id", middleware.RequirePermission("partners:write"), controllers.UpdatePartner) // Add PATCH support for consistency
+		api.DELETE("/partners/:id", middleware.RequirePermission("partners:write"), controllers.DeletePartner)
+
+		api.POST("/pdf", middleware.RequirePermission("pdf:write"), controllers.CreatePdf)
+
+		api.POST("/contacts", middleware.RequirePermission("contacts:write"), controllers.CreateContact)
+		api.PUT("/contacts/:id", middleware.RequirePermission("contacts:write"), controllers.UpdateContact)
+		api.PATCH("/contacts/:id", middleware.RequirePermission("contacts:write"), controllers.UpdateContact)
+
+		api.POST("/reviews", middleware.RequirePermission("reviews:write"), controllers.CreateReview)
+		api.PATCH("/reviews/:id", middleware.RequirePermission("reviews:write"), controllers.UpdateReview)
+		api.DELETE("/reviews/:id", middleware.RequirePermission("reviews:write"), controllers.DeleteReview)
 	}
 }
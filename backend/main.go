@@ -13,9 +13,11 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/kholodihor/cows-shelter-backend/config"
+	"github.com/kholodihor/cows-shelter-backend/controllers"
 	"github.com/kholodihor/cows-shelter-backend/handler"
 	"github.com/kholodihor/cows-shelter-backend/middleware"
 	"github.com/kholodihor/cows-shelter-backend/models"
+	"github.com/kholodihor/cows-shelter-backend/utils"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -33,6 +35,36 @@ func runMigrations() error {
 		&models.Gallery{},
 		&models.Review{},
 		&models.Pdf{},
+		&models.Animal{},
+		&models.AnimalPhoto{},
+		&models.MedicalRecord{},
+		&models.MedicalAttachment{},
+		&models.Sponsorship{},
+		&models.AnimalUpdate{},
+		&models.ExcursionSession{},
+		&models.Booking{},
+		&models.ExcursionSchedule{},
+		&models.BlackoutDate{},
+		&models.WaitlistEntry{},
+		&models.Campaign{},
+		&models.Donation{},
+		&models.WebhookEvent{},
+		&models.Receipt{},
+		&models.Expense{},
+		&models.MonthlyReport{},
+		&models.Volunteer{},
+		&models.Shift{},
+		&models.ShiftSignup{},
+		&models.RefreshToken{},
+		&models.Session{},
+		&models.LoginThrottle{},
+		&models.RecoveryCode{},
+		&models.Invitation{},
+		&models.AuditLog{},
+		&models.APIKey{},
+		&models.UserIdentity{},
+		&models.OIDCLogin{},
+		&models.Password{},
 	); err != nil {
 		return fmt.Errorf("failed to run migrations: %v", err)
 	}
@@ -59,7 +91,7 @@ func createAdminUser(email, password string) error {
 	userData := models.User{
 		Email:    email,
 		Password: string(hashedPassword),
-		Role:     "admin",
+		Role:     models.RoleAdmin,
 	}
 
 	if result.Error != nil {
@@ -70,7 +102,7 @@ func createAdminUser(email, password string) error {
 		log.Printf("Created new admin user: %s", email)
 	} else {
 		// Update existing user to admin
-		user.Role = "admin"
+		user.Role = models.RoleAdmin
 		user.Password = string(hashedPassword)
 		if err := config.DB.Save(&user).Error; err != nil {
 			return fmt.Errorf("failed to update user to admin: %v", err)
@@ -117,9 +149,25 @@ func main() {
 
 	log.Println("Starting server...")
 
+	// Fail fast on invalid token signing configuration
+	if err := utils.LoadKeys(); err != nil {
+		log.Fatalf("Failed to load JWT signing keys: %v\n", err)
+	}
+
 	router := gin.Default()
 
 	config.Connect()
+	config.Mailer = config.NewMailer()
+	config.PaymentProviders = config.NewPaymentProviders()
+	config.Notifier = config.NewNotifier()
+
+	// Background jobs stop when the server shuts down
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	controllers.StartAuditRetention(jobsCtx)
+	controllers.StartMedicalReminders(jobsCtx)
+	controllers.StartBookingExpiry(jobsCtx)
+	controllers.StartShiftReminders(jobsCtx)
 
 	// Initialize storage service based on configuration
 	log.Println("Using S3 storage service")
@@ -132,6 +180,8 @@ func main() {
 		log.Printf("Storage service initialized successfully")
 		// Add storage middleware only if storage service was initialized successfully
 		router.Use(middleware.GinStorageMiddleware(storageService))
+		// Monthly financial reports are published to storage
+		controllers.StartMonthlyReports(middleware.WithStorage(jobsCtx, storageService))
 	}
 
 	// Add CORS middleware
@@ -3,35 +3,176 @@ package middleware
 import (
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kholodihor/cows-shelter-backend/config"
+	"github.com/kholodihor/cows-shelter-backend/models"
 	"github.com/kholodihor/cows-shelter-backend/utils"
 )
 
+// authenticate validates the bearer token and loads its user into the context.
+// Tokens with a purpose other than the allowed ones are rejected.
+func authenticate(c *gin.Context, allowedPurposes ...string) (*utils.Claims, bool) {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+		return nil, false
+	}
+
+	bearerToken := strings.Split(authHeader, "Bearer ")
+	if len(bearerToken) != 2 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token format"})
+		return nil, false
+	}
+
+	tokenString := bearerToken[1]
+	claims, err := utils.ValidateToken(tokenString)
+
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		return nil, false
+	}
+
+	purposeAllowed := false
+	for _, purpose := range allowedPurposes {
+		if claims.Purpose == purpose {
+			purposeAllowed = true
+			break
+		}
+	}
+	if !purposeAllowed {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		return nil, false
+	}
+
+	// Load the user so that role changes take effect without re-login
+	var user models.User
+	if err := config.DB.Where("email = ?", claims.Email).First(&user).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "User no longer exists"})
+		return nil, false
+	}
+
+	if user.DeactivatedAt != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Account is deactivated"})
+		return nil, false
+	}
+
+	// Tokens issued before the last password change are revoked
+	if user.PasswordChangedAt != nil && claims.IssuedBefore(*user.PasswordChangedAt) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+		return nil, false
+	}
+
+	// Access tokens stop working as soon as their session is signed out
+	if claims.SessionID != "" {
+		var session models.Session
+		if err := config.DB.Where("id = ? AND user_id = ?", claims.SessionID, user.ID).First(&session).Error; err != nil || session.RevokedAt != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+			return nil, false
+		}
+
+		// Record activity at most once a minute per session to keep writes cheap
+		if now := time.Now(); now.Sub(session.LastSeenAt) > time.Minute {
+			config.DB.Model(&session).Updates(map[string]interface{}{"last_seen_at": now, "ip": c.ClientIP()})
+		}
+		c.Set("session_id", session.ID)
+	}
+
+	// Save user details in context for further use
+	c.Set("user", user)
+	c.Set("email", user.Email)
+	c.Set("role", user.Role)
+	c.Set("auth_purpose", claims.Purpose)
+	return claims, true
+}
+
+// apiKeyFromRequest returns the API key sent in X-API-Key or as a bearer token
+func apiKeyFromRequest(c *gin.Context) string {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+	if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok && strings.HasPrefix(token, utils.APIKeyPrefix) {
+		return token
+	}
+	return ""
+}
+
+// authenticateAPIKey validates an API key and loads the key and its owner into
+// the context. Permission checks then also require a matching key scope.
+func authenticateAPIKey(c *gin.Context, key string) bool {
+	var apiKey models.APIKey
+	now := time.Now()
+	err := config.DB.Preload("User").
+		Where("key_hash = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", utils.HashToken(key), now).
+		First(&apiKey).Error
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired API key"})
+		return false
+	}
+
+	user := apiKey.User
+	if user.ID == 0 || user.DeactivatedAt != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired API key"})
+		return false
+	}
+
+	// Record usage at most once a minute per key to keep writes cheap
+	config.DB.Model(&models.APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", apiKey.ID, now.Add(-time.Minute)).
+		Updates(map[string]interface{}{"last_used_at": now, "last_used_ip": c.ClientIP()})
+
+	c.Set("user", user)
+	c.Set("email", user.Email)
+	c.Set("role", user.Role)
+	c.Set("auth_purpose", "")
+	c.Set("api_key", apiKey)
+	return true
+}
+
+// AuthMiddleware accepts a JWT access token or an API key
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+		if key := apiKeyFromRequest(c); key != "" {
+			if !authenticateAPIKey(c, key) {
+				return
+			}
+		} else if _, ok := authenticate(c, ""); !ok {
 			return
 		}
 
-		bearerToken := strings.Split(authHeader, "Bearer ")
-		if len(bearerToken) != 2 {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token format"})
+		// Admins must finish 2FA enrollment before using the API when required.
+		// This covers their API keys too, including keys minted before the
+		// policy was turned on.
+		user := c.MustGet("user").(models.User)
+		if config.RequireAdminMFA() && user.Role == models.RoleAdmin && !user.TOTPEnabled {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication must be enabled for admin accounts"})
 			return
 		}
 
-		tokenString := bearerToken[1]
-		claims, err := utils.ValidateToken(tokenString)
+		c.Next()
+	}
+}
 
-		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+// RejectAPIKeys restricts a route to users logged in with a token, for
+// account and security settings that integrations must not touch
+func RejectAPIKeys() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, isAPIKey := c.Get("api_key"); isAPIKey {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "This endpoint is not available to API keys"})
 			return
 		}
+		c.Next()
+	}
+}
 
-		// Save email in context for further use
-		c.Set("email", claims.Email)
+// EnrollmentAuthMiddleware accepts a regular access token or the enrollment
+// challenge issued at login when 2FA is required but not yet set up
+func EnrollmentAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := authenticate(c, "", utils.PurposeMFASetup); !ok {
+			return
+		}
 		c.Next()
 	}
-}
\ No newline at end of file
+}
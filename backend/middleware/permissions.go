@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kholodihor/cows-shelter-backend/models"
+)
+
+// rolePermissions maps each role to the permissions it grants. Permissions
+// have the form "<resource>:<action>" and either part may be a "*" wildcard.
+var rolePermissions = map[string][]string{
+	models.RoleAdmin: {"*:*"},
+	models.RoleEditor: {
+		"animals:*",
+		"bookings:*",
+		"news:*",
+		"excursions:*",
+		"gallery:*",
+		"partners:*",
+		"pdf:*",
+		"contacts:*",
+		"reviews:*",
+		"volunteers:*",
+	},
+	models.RoleViewer: {
+		"animals:read",
+		"news:read",
+		"excursions:read",
+		"gallery:read",
+		"partners:read",
+		"pdf:read",
+		"contacts:read",
+		"reviews:read",
+	},
+}
+
+// matchPermission reports whether a granted permission covers the required one
+func matchPermission(granted, required string) bool {
+	grantedResource, grantedAction, _ := strings.Cut(granted, ":")
+	requiredResource, requiredAction, _ := strings.Cut(required, ":")
+
+	if grantedResource != "*" && grantedResource != requiredResource {
+		return false
+	}
+	return grantedAction == "*" || grantedAction == requiredAction
+}
+
+// RoleHasPermission reports whether the given role grants the permission
+func RoleHasPermission(role, permission string) bool {
+	for _, granted := range rolePermissions[role] {
+		if matchPermission(granted, permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// ScopesHavePermission reports whether any of the API key scopes grants the permission
+func ScopesHavePermission(scopes []string, permission string) bool {
+	for _, granted := range scopes {
+		if matchPermission(granted, permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidPermission reports whether a permission is well formed, such as
+// "news:write" or "gallery:*"
+func ValidPermission(permission string) bool {
+	resource, action, ok := strings.Cut(permission, ":")
+	if !ok || resource == "" {
+		return false
+	}
+	return action == "read" || action == "write" || action == "*"
+}
+
+// RequirePermission aborts with 403 unless the authenticated user's role grants
+// the permission and, for API keys, one of the key scopes does as well. It must
+// run after AuthMiddleware.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, exists := c.Get("role")
+		if !exists {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			return
+		}
+
+		if !RoleHasPermission(role.(string), permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			return
+		}
+
+		if apiKey, isAPIKey := c.Get("api_key"); isAPIKey && !ScopesHavePermission(apiKey.(models.APIKey).Scopes, permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API key scope does not allow this action"})
+			return
+		}
+
+		c.Next()
+	}
+}
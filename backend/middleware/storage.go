@@ -23,6 +23,12 @@ func StorageMiddleware(store storage.Service) func(http.Handler) http.Handler {
 	}
 }
 
+// WithStorage returns a copy of ctx carrying the storage service, for work
+// that runs outside a request such as background jobs
+func WithStorage(ctx context.Context, store storage.Service) context.Context {
+	return context.WithValue(ctx, storageKey, store)
+}
+
 // GetStorage retrieves the storage service from the request context
 func GetStorage(ctx context.Context) storage.Service {
 	if store, ok := ctx.Value(storageKey).(storage.Service); ok {
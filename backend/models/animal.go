@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Animal statuses
+const (
+	AnimalResident = "resident"
+	AnimalAdopted  = "adopted"
+	AnimalDeceased = "deceased"
+)
+
+// ValidAnimalStatus reports whether status is one of the known statuses
+func ValidAnimalStatus(status string) bool {
+	return status == AnimalResident || status == AnimalAdopted || status == AnimalDeceased
+}
+
+// Animal is a cow (or any other resident) of the shelter
+type Animal struct {
+	gorm.Model
+	NameEn        string        `json:"name_en"`
+	NameUa        string        `json:"name_ua"`
+	StoryEn       string        `json:"story_en"`
+	StoryUa       string        `json:"story_ua"`
+	Breed         string        `json:"breed"`
+	BirthDate     *time.Time    `json:"birth_date" gorm:"type:date"`
+	ArrivalDate   *time.Time    `json:"arrival_date" gorm:"type:date"`
+	Status        string        `json:"status" gorm:"index;default:'resident'"`
+	CoverImageUrl string        `json:"cover_image_url"`
+	MonthlyCost   int64         `json:"monthly_cost"` // upkeep in minor currency units, the sponsorship target
+	Photos        []AnimalPhoto `json:"photos,omitempty"`
+}
+
+// AnimalPhoto is one photo of an animal's profile, shown in Position order
+type AnimalPhoto struct {
+	gorm.Model
+	AnimalID  uint   `json:"animal_id" gorm:"index"`
+	ImageUrl  string `json:"image_url"`
+	CaptionEn string `json:"caption_en"`
+	CaptionUa string `json:"caption_ua"`
+	Position  int    `json:"position"`
+}
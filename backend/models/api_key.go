@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKey lets scripts and integrations call the API without a login. Only the
+// SHA-256 hash of the key is stored; the key itself is shown once on creation.
+// A key acts on behalf of the user who created it, limited to its scopes.
+type APIKey struct {
+	gorm.Model
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix" gorm:"index"`
+	KeyHash    string     `json:"-" gorm:"uniqueIndex"`
+	Scopes     []string   `json:"scopes" gorm:"serializer:json;type:text"`
+	UserID     uint       `json:"user_id" gorm:"index"`
+	User       User       `json:"-"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
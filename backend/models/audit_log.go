@@ -0,0 +1,29 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Audit actions recorded for every create, update and delete
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+)
+
+// AuditLog records one administrative change. Entries are append-only and
+// removed only by the retention cleanup, so there is no soft delete.
+type AuditLog struct {
+	ID         uint            `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time       `gorm:"index" json:"created_at"`
+	ActorID    *uint           `json:"actor_id"`
+	ActorEmail string          `gorm:"index" json:"actor_email"`
+	APIKeyID   *uint           `json:"api_key_id"` // set when the change was made with an API key
+	Action     string          `gorm:"index;not null" json:"action"`
+	EntityType string          `gorm:"index:idx_audit_entity;not null" json:"entity_type"`
+	EntityID   uint            `gorm:"index:idx_audit_entity" json:"entity_id"`
+	EntityKey  string          `gorm:"index" json:"entity_key,omitempty"` // ID of entities without a numeric one, such as sessions
+	Changes    json.RawMessage `gorm:"type:jsonb" json:"changes"`
+	IP         string          `json:"ip"`
+}
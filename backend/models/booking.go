@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Excursion session statuses
+const (
+	SessionScheduled = "scheduled"
+	SessionCancelled = "cancelled"
+)
+
+// ExcursionSession is one dated occurrence of an excursion that visitors can
+// book. BookedSeats is only changed by conditional updates so it never
+// exceeds Capacity, even with concurrent bookings.
+type ExcursionSession struct {
+	gorm.Model
+	ExcursionID uint       `json:"excursion_id" gorm:"index"`
+	Excursion   *Excursion `json:"excursion,omitempty"`
+	ScheduleID  *uint      `json:"schedule_id" gorm:"uniqueIndex:idx_session_schedule_start"` // set for generated sessions
+	StartsAt    time.Time  `json:"starts_at" gorm:"index;uniqueIndex:idx_session_schedule_start"`
+	EndsAt      time.Time  `json:"ends_at"`
+	Capacity    int        `json:"capacity"`
+	BookedSeats int        `json:"booked_seats" gorm:"not null;default:0"`
+	Status      string     `json:"status" gorm:"index;default:'scheduled'"`
+	SeatsLeft   int        `json:"seats_left" gorm:"-"`
+}
+
+// AfterFind computes how many seats can still be booked
+func (s *ExcursionSession) AfterFind(tx *gorm.DB) error {
+	s.SeatsLeft = 0
+	if s.Status == SessionScheduled && s.BookedSeats < s.Capacity {
+		s.SeatsLeft = s.Capacity - s.BookedSeats
+	}
+	return nil
+}
+
+// Booking statuses. Pending and confirmed bookings hold seats.
+const (
+	BookingPending   = "pending"   // waiting for the visitor to confirm by email
+	BookingConfirmed = "confirmed" // confirmed by the visitor
+	BookingCancelled = "cancelled" // cancelled by the visitor or the shelter
+	BookingExpired   = "expired"   // not confirmed in time
+)
+
+// Booking reserves seats in an excursion session. The visitor manages it
+// through a secret link whose SHA-256 hash is stored in TokenHash.
+type Booking struct {
+	gorm.Model
+	SessionID   uint              `json:"session_id" gorm:"index"`
+	Session     *ExcursionSession `json:"session,omitempty"`
+	Name        string            `json:"name"`
+	Email       string            `json:"email" gorm:"index"`
+	Phone       string            `json:"phone"`
+	Seats       int               `json:"seats"`
+	Status      string            `json:"status" gorm:"index"`
+	TokenHash   string            `json:"-" gorm:"uniqueIndex"`
+	ExpiresAt   *time.Time        `json:"expires_at"` // confirmation deadline of pending bookings
+	ConfirmedAt *time.Time        `json:"confirmed_at"`
+	CancelledAt *time.Time        `json:"cancelled_at"`
+}
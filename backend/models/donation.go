@@ -0,0 +1,97 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Campaign statuses
+const (
+	CampaignDraft  = "draft"  // being prepared, not shown publicly
+	CampaignActive = "active" // open for donations
+	CampaignClosed = "closed" // finished; still shown with its final total
+)
+
+// ValidCampaignStatus reports whether status is one of the known statuses
+func ValidCampaignStatus(status string) bool {
+	switch status {
+	case CampaignDraft, CampaignActive, CampaignClosed:
+		return true
+	}
+	return false
+}
+
+// Campaign is a fundraising goal donations can be assigned to
+type Campaign struct {
+	gorm.Model
+	TitleEn       string     `json:"title_en"`
+	TitleUa       string     `json:"title_ua"`
+	DescriptionEn string     `json:"description_en"`
+	DescriptionUa string     `json:"description_ua"`
+	GoalAmount    int64      `json:"goal_amount"` // in minor currency units
+	Currency      string     `json:"currency"`
+	Deadline      *time.Time `json:"deadline" gorm:"type:date"`
+	Status        string     `json:"status" gorm:"index"`
+}
+
+// Donation sources, matching the payment options of the donate page
+const (
+	SourcePrivatbank   = "privatbank"
+	SourceMonobank     = "monobank"
+	SourcePayPal       = "paypal"
+	SourceWesternUnion = "western_union"
+	SourceSwift        = "swift"
+	SourceCash         = "cash"
+	SourceOther        = "other"
+)
+
+// ValidDonationSource reports whether source is one of the known sources
+func ValidDonationSource(source string) bool {
+	switch source {
+	case SourcePrivatbank, SourceMonobank, SourcePayPal, SourceWesternUnion, SourceSwift, SourceCash, SourceOther:
+		return true
+	}
+	return false
+}
+
+// Donation statuses. Only completed donations count towards totals.
+const (
+	DonationPending   = "pending"   // payment started but not settled
+	DonationCompleted = "completed" // money received
+	DonationFailed    = "failed"    // payment did not go through
+	DonationRefunded  = "refunded"  // money returned to the donor
+)
+
+// ValidDonationStatus reports whether status is one of the known statuses
+func ValidDonationStatus(status string) bool {
+	switch status {
+	case DonationPending, DonationCompleted, DonationFailed, DonationRefunded:
+		return true
+	}
+	return false
+}
+
+// Donation records money received through one of the donation sources.
+// CampaignAmount is the amount credited to the campaign in the campaign's
+// currency; it equals Amount when the currencies match and is entered by an
+// admin after conversion otherwise.
+type Donation struct {
+	gorm.Model
+	CampaignID     *uint     `json:"campaign_id" gorm:"index"`
+	Campaign       *Campaign `json:"campaign,omitempty"`
+	Source         string    `json:"source" gorm:"index;uniqueIndex:idx_donation_external"`
+	ExternalID     *string   `json:"external_id" gorm:"uniqueIndex:idx_donation_external"` // payment provider reference
+	Amount         int64     `json:"amount"`                                               // in minor currency units
+	Currency       string    `json:"currency"`
+	CampaignAmount int64     `json:"campaign_amount"`
+	RefundedAmount int64     `json:"refunded_amount"` // returned to the donor by the provider, already taken off Amount while completed
+	Status         string    `json:"status" gorm:"index"`
+	DonorName      string    `json:"donor_name"`
+	DonorEmail     string    `json:"donor_email" gorm:"index"`
+	Anonymous      bool      `json:"anonymous"` // hide the donor on public pages
+	Message        string    `json:"message"`
+	Note           string    `json:"note"` // internal note, admins only
+	ReceivedAt     time.Time `json:"received_at" gorm:"index"`
+	CreatedByID    *uint     `json:"created_by_id"`
+}
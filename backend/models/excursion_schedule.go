@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Weekday codes used by schedules, as in iCalendar RRULE BYDAY
+var WeekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// ExcursionSchedule is a weekly recurrence rule of an excursion, comparable to
+// FREQ=WEEKLY;INTERVAL=IntervalWeeks;BYDAY=Weekdays in RRULE terms. It is
+// expanded into ExcursionSession rows on demand. Times are local to the
+// shelter time zone.
+type ExcursionSchedule struct {
+	gorm.Model
+	ExcursionID   uint       `json:"excursion_id" gorm:"index"`
+	Weekdays      []string   `json:"weekdays" gorm:"serializer:json;type:text"` // MO..SU
+	IntervalWeeks int        `json:"interval_weeks" gorm:"not null;default:1"`  // every n weeks
+	StartTime     string     `json:"start_time"`                                // HH:MM
+	EndTime       string     `json:"end_time"`                                  // HH:MM
+	Capacity      int        `json:"capacity"`
+	ValidFrom     time.Time  `json:"valid_from" gorm:"type:date"`
+	ValidUntil    *time.Time `json:"valid_until" gorm:"type:date"`
+	ExcludedDates []string   `json:"excluded_dates" gorm:"serializer:json;type:text"` // YYYY-MM-DD
+	Active        bool       `json:"active"`
+}
+
+// BlackoutDate is a day without any excursions, such as a public holiday
+type BlackoutDate struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at"`
+	Date      time.Time `json:"date" gorm:"type:date;uniqueIndex"`
+	Reason    string    `json:"reason"`
+}
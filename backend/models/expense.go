@@ -0,0 +1,76 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Expense categories, as shown in the financial reports
+const (
+	ExpenseFeed       = "feed"
+	ExpenseVeterinary = "veterinary"
+	ExpenseHousing    = "housing" // barns, fencing and repairs
+	ExpenseStaff      = "staff"
+	ExpenseTransport  = "transport"
+	ExpenseUtilities  = "utilities"
+	ExpenseEquipment  = "equipment"
+	ExpenseOther      = "other"
+)
+
+// ExpenseCategories lists the categories in report order
+var ExpenseCategories = []string{
+	ExpenseFeed, ExpenseVeterinary, ExpenseHousing, ExpenseStaff,
+	ExpenseTransport, ExpenseUtilities, ExpenseEquipment, ExpenseOther,
+}
+
+// ValidExpenseCategory reports whether category is one of the known categories
+func ValidExpenseCategory(category string) bool {
+	for _, known := range ExpenseCategories {
+		if category == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Expense is money the shelter spent, entered into the ledger by an admin.
+// The scan of its invoice or receipt, if any, lives under the private
+// storage prefix and is only downloaded through the API, from ReceiptScanUrl.
+type Expense struct {
+	gorm.Model
+	Category       string    `json:"category" gorm:"index"`
+	Description    string    `json:"description"`
+	Vendor         string    `json:"vendor"`
+	Amount         int64     `json:"amount"` // in minor currency units
+	Currency       string    `json:"currency"`
+	SpentOn        time.Time `json:"spent_on" gorm:"type:date;index"`
+	ReceiptScanKey string    `json:"-"`
+	ReceiptScanUrl string    `json:"receipt_scan_url,omitempty" gorm:"-"`
+	CreatedByID    *uint     `json:"created_by_id"`
+}
+
+// AfterFind sets the API path the receipt scan is downloaded from
+func (e *Expense) AfterFind(tx *gorm.DB) error {
+	e.ReceiptScanUrl = ""
+	if e.ReceiptScanKey != "" {
+		e.ReceiptScanUrl = fmt.Sprintf("/api/admin/expenses/%d/receipt", e.ID)
+	}
+	return nil
+}
+
+// AfterSave keeps the download path in step with a new or removed scan
+func (e *Expense) AfterSave(tx *gorm.DB) error {
+	return e.AfterFind(tx)
+}
+
+// MonthlyReport records that the financial report of a month was published
+// as a document
+type MonthlyReport struct {
+	gorm.Model
+	Month       string    `json:"month" gorm:"uniqueIndex;size:7;not null"` // YYYY-MM
+	PdfID       uint      `json:"pdf_id"`
+	Pdf         *Pdf      `json:"pdf,omitempty"`
+	PublishedAt time.Time `json:"published_at"`
+}
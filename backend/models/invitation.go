@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Invitation lets the holder of the token register one account with a role
+// chosen by an admin. Only the SHA-256 hash of the token is stored.
+type Invitation struct {
+	gorm.Model
+	Email          string     `json:"email" gorm:"index"`
+	Name           string     `json:"name"`
+	Role           string     `json:"role"`
+	TokenHash      string     `json:"-" gorm:"uniqueIndex"`
+	InvitedByID    uint       `json:"invited_by_id"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	AcceptedAt     *time.Time `json:"accepted_at"`
+	AcceptedUserID *uint      `json:"accepted_user_id"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+}
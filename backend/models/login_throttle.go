@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Kinds of login throttles
+const (
+	ThrottleAccount = "account"
+	ThrottleIP      = "ip"
+)
+
+// Kinds of throttles for public forms, by client IP or email
+const (
+	ThrottleSponsorRequestIP       = "sponsor_request_ip"
+	ThrottleSponsorLinksIP         = "sponsor_links_ip"
+	ThrottleSponsorLinksEmail      = "sponsor_links_email"
+	ThrottleBookingIP              = "booking_ip"
+	ThrottleWaitlistIP             = "waitlist_ip"
+	ThrottleVolunteerApplicationIP = "volunteer_application_ip"
+	ThrottlePasswordResetIP        = "password_reset_ip"
+	ThrottlePasswordResetEmail     = "password_reset_email"
+)
+
+// LoginThrottle tracks failed login attempts for one account (by email) or one
+// client IP. Public forms use it the same way to count their requests, with
+// Failures holding the number of requests. Rows are deleted outright when
+// cleared, so there is no soft delete.
+type LoginThrottle struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	Kind          string     `gorm:"not null;uniqueIndex:idx_login_throttle_subject" json:"kind"`
+	Subject       string     `gorm:"not null;uniqueIndex:idx_login_throttle_subject" json:"subject"`
+	Failures      int        `json:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
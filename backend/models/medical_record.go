@@ -0,0 +1,64 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Medical record types
+const (
+	MedicalVaccination = "vaccination"
+	MedicalTreatment   = "treatment"
+	MedicalCheckup     = "checkup"
+	MedicalWeight      = "weight"
+)
+
+// ValidMedicalType reports whether kind is one of the known record types
+func ValidMedicalType(kind string) bool {
+	switch kind {
+	case MedicalVaccination, MedicalTreatment, MedicalCheckup, MedicalWeight:
+		return true
+	}
+	return false
+}
+
+// MedicalRecord is a veterinary event in an animal's history. Records are
+// only served to admins and never embedded in public animal responses.
+type MedicalRecord struct {
+	gorm.Model
+	AnimalID       uint                `json:"animal_id" gorm:"index"`
+	Type           string              `json:"type" gorm:"index"`
+	Title          string              `json:"title"` // e.g. the vaccine or treatment name
+	Notes          string              `json:"notes"`
+	Veterinarian   string              `json:"veterinarian"`
+	PerformedAt    time.Time           `json:"performed_at" gorm:"type:date"`
+	WeightKg       *float64            `json:"weight_kg"`
+	NextDueAt      *time.Time          `json:"next_due_at" gorm:"type:date;index"`
+	ReminderSentAt *time.Time          `json:"reminder_sent_at"`
+	CreatedByID    uint                `json:"created_by_id"`
+	Attachments    []MedicalAttachment `json:"attachments,omitempty"`
+}
+
+// MedicalAttachment is a file (lab result, prescription, x-ray) kept with a
+// medical record. Files live under the private storage prefix and are only
+// downloaded through the API, from DownloadUrl.
+type MedicalAttachment struct {
+	gorm.Model
+	MedicalRecordID uint   `json:"medical_record_id" gorm:"index"`
+	FileName        string `json:"file_name"`
+	ObjectKey       string `json:"-"`
+	DownloadUrl     string `json:"download_url" gorm:"-"`
+}
+
+// AfterFind sets the API path the file is downloaded from
+func (a *MedicalAttachment) AfterFind(tx *gorm.DB) error {
+	a.DownloadUrl = fmt.Sprintf("/api/admin/medical/%d/attachments/%d", a.MedicalRecordID, a.ID)
+	return nil
+}
+
+// AfterCreate sets the download path of a new attachment
+func (a *MedicalAttachment) AfterCreate(tx *gorm.DB) error {
+	return a.AfterFind(tx)
+}
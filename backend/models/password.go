@@ -1,10 +1,18 @@
 package models
 
-import "gorm.io/gorm"
+import (
+    "time"
 
+    "gorm.io/gorm"
+)
 
+// Password is a single-use password reset token. Only the SHA-256 hash of the
+// token is stored; the plain token is sent to the user by email.
 type Password struct {
     gorm.Model
-    Email string `json:"email"`
-    Token string `json:"token" gorm:"unique"`
-}
\ No newline at end of file
+    UserID    uint       `json:"user_id" gorm:"index"`
+    Email     string     `json:"email"`
+    TokenHash string     `json:"-" gorm:"uniqueIndex"`
+    ExpiresAt time.Time  `json:"expires_at"`
+    UsedAt    *time.Time `json:"used_at"`
+}
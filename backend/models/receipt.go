@@ -0,0 +1,51 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Receipt statuses
+const (
+	ReceiptIssued = "issued" // valid receipt
+	ReceiptVoided = "voided" // cancelled; the serial number is never reused
+)
+
+// Receipt is a PDF acknowledgement of a completed donation. Serial numbers
+// run per calendar year (CS-2026-000001) and the donor details are kept as
+// printed, so a regenerated receipt shows what the donor asked for. The PDF
+// shows the donor's tax code and address, so it lives under the private
+// storage prefix: admins download it from DownloadUrl and the donor through
+// a secret link whose SHA-256 hash is stored in AccessTokenHash.
+type Receipt struct {
+	gorm.Model
+	DonationID      uint       `json:"donation_id" gorm:"index;not null"`
+	Donation        *Donation  `json:"donation,omitempty"`
+	Serial          string     `json:"serial" gorm:"uniqueIndex;not null"`
+	Year            int        `json:"year" gorm:"uniqueIndex:idx_receipt_number;not null"`
+	Number          int        `json:"number" gorm:"uniqueIndex:idx_receipt_number;not null"`
+	Status          string     `json:"status" gorm:"index"`
+	DonorName       string     `json:"donor_name"`
+	DonorTaxCode    string     `json:"donor_tax_code"`
+	DonorAddress    string     `json:"donor_address"`
+	ObjectKey       string     `json:"-"`
+	DownloadUrl     string     `json:"download_url" gorm:"-"`
+	AccessTokenHash *string    `json:"-" gorm:"uniqueIndex"`
+	IssuedAt        time.Time  `json:"issued_at"`
+	IssuedByID      *uint      `json:"issued_by_id"`
+	VoidedAt        *time.Time `json:"voided_at"`
+	VoidReason      string     `json:"void_reason"`
+}
+
+// AfterFind sets the API path admins download the document from
+func (r *Receipt) AfterFind(tx *gorm.DB) error {
+	r.DownloadUrl = fmt.Sprintf("/api/admin/receipts/%d/document", r.ID)
+	return nil
+}
+
+// AfterCreate sets the download path of a new receipt
+func (r *Receipt) AfterCreate(tx *gorm.DB) error {
+	return r.AfterFind(tx)
+}
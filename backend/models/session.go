@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Session is one login on one device. Its ID is the refresh token family ID
+// and is carried in access tokens as the "sid" claim, so revoking the session
+// ends both the refresh chain and the access tokens issued for it.
+type Session struct {
+	ID         string     `gorm:"primaryKey;size:36" json:"id"`
+	UserID     uint       `gorm:"index;not null" json:"user_id"`
+	Device     string     `json:"device"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
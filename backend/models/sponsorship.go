@@ -0,0 +1,86 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Sponsorship statuses
+const (
+	SponsorshipPending   = "pending"   // requested by a supporter, awaiting confirmation
+	SponsorshipActive    = "active"    // counts towards the animal's coverage
+	SponsorshipPaused    = "paused"    // temporarily not paying
+	SponsorshipCancelled = "cancelled" // ended by the sponsor or an admin
+)
+
+// ValidSponsorshipStatus reports whether status is one of the known statuses
+func ValidSponsorshipStatus(status string) bool {
+	switch status {
+	case SponsorshipPending, SponsorshipActive, SponsorshipPaused, SponsorshipCancelled:
+		return true
+	}
+	return false
+}
+
+// Sponsorship periods
+const (
+	PeriodMonthly   = "monthly"
+	PeriodQuarterly = "quarterly"
+	PeriodYearly    = "yearly"
+)
+
+// PeriodMonths returns the length of a sponsorship period in months, or 0 for
+// an unknown period
+func PeriodMonths(period string) int64 {
+	switch period {
+	case PeriodMonthly:
+		return 1
+	case PeriodQuarterly:
+		return 3
+	case PeriodYearly:
+		return 12
+	}
+	return 0
+}
+
+// Sponsorship links a supporter to one animal they pay for regularly.
+// Sponsors are not users; they reach their sponsor page through a secret link
+// whose SHA-256 hash is stored in TokenHash.
+type Sponsorship struct {
+	gorm.Model
+	AnimalID     uint       `json:"animal_id" gorm:"index"`
+	Animal       *Animal    `json:"animal,omitempty"`
+	SponsorName  string     `json:"sponsor_name"`
+	SponsorEmail string     `json:"sponsor_email" gorm:"index"`
+	ShowName     bool       `json:"show_name"` // name may be acknowledged publicly
+	Amount       int64      `json:"amount"`    // per period, in minor currency units
+	Currency     string     `json:"currency"`
+	Period       string     `json:"period"`
+	Status       string     `json:"status" gorm:"index"`
+	StartedAt    *time.Time `json:"started_at"`
+	EndsAt       *time.Time `json:"ends_at" gorm:"type:date"`
+	CancelledAt  *time.Time `json:"cancelled_at"`
+	TokenHash    string     `json:"-" gorm:"index"`
+}
+
+// MonthlyAmount is the sponsorship amount spread over one month
+func (s Sponsorship) MonthlyAmount() int64 {
+	months := PeriodMonths(s.Period)
+	if months == 0 {
+		return 0
+	}
+	return s.Amount / months
+}
+
+// AnimalUpdate is a news post about one animal that only its sponsors can read
+type AnimalUpdate struct {
+	gorm.Model
+	AnimalID uint   `json:"animal_id" gorm:"index"`
+	TitleEn  string `json:"title_en"`
+	TitleUa  string `json:"title_ua"`
+	BodyEn   string `json:"body_en"`
+	BodyUa   string `json:"body_ua"`
+	ImageUrl string `json:"image_url"`
+	AuthorID uint   `json:"author_id"`
+}
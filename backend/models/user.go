@@ -1,10 +1,35 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Roles understood by the permission matrix in middleware.
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+// ValidRole reports whether role is one of the known roles
+func ValidRole(role string) bool {
+	return role == RoleAdmin || role == RoleEditor || role == RoleViewer
+}
 
 type User struct {
 	gorm.Model
 	Email    string `json:"email" gorm:"unique"`
 	Password string `json:"-"`
-	Role     string `json:"role" gorm:"default:'user'"`
-}
\ No newline at end of file
+	Role     string `json:"role" gorm:"default:'viewer'"`
+	Name     string `json:"name"`
+	// DeactivatedAt blocks login and API access while set
+	DeactivatedAt *time.Time `json:"deactivated_at"`
+	// PasswordChangedAt invalidates every token issued before it
+	PasswordChangedAt *time.Time `json:"password_changed_at"`
+	// TOTPSecret is set during enrollment and only used once TOTPEnabled is true
+	TOTPSecret      string `json:"-"`
+	TOTPEnabled     bool   `json:"totp_enabled"`
+	TOTPLastCounter int64  `json:"-"`
+}
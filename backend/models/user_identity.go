@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserIdentity links a local user to an account at an external identity
+// provider, identified by the issuer and its stable subject ID
+type UserIdentity struct {
+	gorm.Model
+	UserID      uint      `json:"user_id" gorm:"index"`
+	Issuer      string    `json:"issuer" gorm:"uniqueIndex:idx_user_identity_subject"`
+	Subject     string    `json:"subject" gorm:"uniqueIndex:idx_user_identity_subject"`
+	Email       string    `json:"email"`
+	LastLoginAt time.Time `json:"last_login_at"`
+}
+
+// OIDCLogin tracks one single sign-on attempt. It starts with the state, nonce
+// and PKCE verifier sent to the provider; after the callback it holds a
+// short-lived login code the frontend exchanges for tokens. Rows are deleted
+// once used, so there is no soft delete.
+type OIDCLogin struct {
+	ID            uint   `gorm:"primaryKey"`
+	StateHash     string `gorm:"uniqueIndex"`
+	Nonce         string
+	CodeVerifier  string
+	UserID        *uint
+	LoginCodeHash *string   `gorm:"uniqueIndex"`
+	ExpiresAt     time.Time `gorm:"index"`
+	CreatedAt     time.Time
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Volunteer statuses. Only approved volunteers can sign up for shifts.
+const (
+	VolunteerPending  = "pending"  // applied, waiting for an admin
+	VolunteerApproved = "approved" // can sign up for shifts
+	VolunteerRejected = "rejected" // application declined
+	VolunteerInactive = "inactive" // no longer volunteering
+)
+
+// ValidVolunteerStatus reports whether status is one of the known statuses
+func ValidVolunteerStatus(status string) bool {
+	switch status {
+	case VolunteerPending, VolunteerApproved, VolunteerRejected, VolunteerInactive:
+		return true
+	}
+	return false
+}
+
+// Volunteer is the profile of someone helping at the shelter. Approved
+// volunteers manage their shifts through a secret link whose SHA-256 hash is
+// stored in TokenHash.
+type Volunteer struct {
+	gorm.Model
+	Name         string     `json:"name"`
+	Email        string     `json:"email" gorm:"index"`
+	Phone        string     `json:"phone"`
+	Skills       string     `json:"skills"`
+	Availability string     `json:"availability"` // when they can usually help
+	About        string     `json:"about"`
+	Note         string     `json:"note"` // internal note, admins only
+	Status       string     `json:"status" gorm:"index"`
+	TokenHash    *string    `json:"-" gorm:"uniqueIndex"`
+	ReviewedAt   *time.Time `json:"reviewed_at"`
+	ReviewedByID *uint      `json:"reviewed_by_id"`
+}
+
+// Shift statuses
+const (
+	ShiftScheduled = "scheduled"
+	ShiftCancelled = "cancelled"
+)
+
+// Shift is a time window in which volunteers do a task at the shelter.
+// SignedUp is only changed by conditional updates so it never exceeds
+// Capacity, even with concurrent sign-ups.
+type Shift struct {
+	gorm.Model
+	Task        string    `json:"task"`
+	Description string    `json:"description"`
+	StartsAt    time.Time `json:"starts_at" gorm:"index"`
+	EndsAt      time.Time `json:"ends_at"`
+	Capacity    int       `json:"capacity"`
+	SignedUp    int       `json:"signed_up" gorm:"not null;default:0"`
+	Status      string    `json:"status" gorm:"index;default:'scheduled'"`
+	PlacesLeft  int       `json:"places_left" gorm:"-"`
+}
+
+// AfterFind computes how many volunteers can still sign up
+func (s *Shift) AfterFind(tx *gorm.DB) error {
+	s.PlacesLeft = 0
+	if s.Status == ShiftScheduled && s.SignedUp < s.Capacity {
+		s.PlacesLeft = s.Capacity - s.SignedUp
+	}
+	return nil
+}
+
+// Shift sign-up statuses. Confirmed sign-ups hold a place, and those of past
+// shifts count as hours worked.
+const (
+	SignupConfirmed = "confirmed" // signed up; worked the shift once it is over
+	SignupCancelled = "cancelled" // withdrawn by the volunteer or an admin
+	SignupNoShow    = "no_show"   // did not come, marked by an admin
+)
+
+// ValidSignupStatus reports whether status is one of the known statuses
+func ValidSignupStatus(status string) bool {
+	switch status {
+	case SignupConfirmed, SignupCancelled, SignupNoShow:
+		return true
+	}
+	return false
+}
+
+// ShiftSignup puts a volunteer on a shift. Minutes overrides the length of
+// the shift in the hours report when the volunteer worked more or less.
+type ShiftSignup struct {
+	gorm.Model
+	ShiftID        uint       `json:"shift_id" gorm:"uniqueIndex:idx_shift_volunteer;not null"`
+	Shift          *Shift     `json:"shift,omitempty"`
+	VolunteerID    uint       `json:"volunteer_id" gorm:"uniqueIndex:idx_shift_volunteer;index;not null"`
+	Volunteer      *Volunteer `json:"volunteer,omitempty"`
+	Status         string     `json:"status" gorm:"index"`
+	Minutes        *int       `json:"minutes"`
+	ReminderSentAt *time.Time `json:"reminder_sent_at"`
+}
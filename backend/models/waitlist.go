@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Waitlist entry statuses
+const (
+	WaitlistWaiting   = "waiting"   // in line for seats
+	WaitlistOffered   = "offered"   // seats are held for a limited time
+	WaitlistClaimed   = "claimed"   // the offer was turned into a booking
+	WaitlistExpired   = "expired"   // the offer ran out or the session started
+	WaitlistCancelled = "cancelled" // left the list, declined or the session was cancelled
+)
+
+// WaitlistEntry keeps a visitor in line for a fully booked session. When
+// seats free up they are held for the next entry that fits and offered
+// through a claim link. TokenHash is the visitor's link to manage the entry,
+// ClaimTokenHash the link of the current offer.
+type WaitlistEntry struct {
+	gorm.Model
+	SessionID      uint              `json:"session_id" gorm:"index"`
+	Session        *ExcursionSession `json:"session,omitempty"`
+	Name           string            `json:"name"`
+	Email          string            `json:"email" gorm:"index"`
+	Phone          string            `json:"phone"`
+	Seats          int               `json:"seats"`
+	Status         string            `json:"status" gorm:"index"`
+	TokenHash      string            `json:"-" gorm:"uniqueIndex"`
+	ClaimTokenHash *string           `json:"-" gorm:"uniqueIndex"`
+	OfferedAt      *time.Time        `json:"offered_at"`
+	OfferExpiresAt *time.Time        `json:"offer_expires_at"`
+	BookingID      *uint             `json:"booking_id"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Webhook event processing states
+const (
+	WebhookReceived  = "received"  // stored, being processed
+	WebhookProcessed = "processed" // payment recorded
+	WebhookIgnored   = "ignored"   // verified but not about a payment
+	WebhookFailed    = "failed"    // processing failed; a redelivery retries it
+)
+
+// WebhookEvent is a verified notification from a payment provider. The
+// unique provider and event ID make repeated deliveries of the same
+// notification a no-op, and the raw payload is kept for reprocessing.
+type WebhookEvent struct {
+	ID          uint       `json:"id" gorm:"primarykey"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	Provider    string     `json:"provider" gorm:"uniqueIndex:idx_webhook_event;not null"`
+	EventID     string     `json:"event_id" gorm:"uniqueIndex:idx_webhook_event;not null"`
+	EventType   string     `json:"event_type"`
+	Payload     string     `json:"payload" gorm:"type:text"`
+	Status      string     `json:"status" gorm:"index"`
+	Error       string     `json:"error"`
+	DonationID  *uint      `json:"donation_id"`
+	ProcessedAt *time.Time `json:"processed_at"`
+}
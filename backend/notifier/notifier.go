@@ -0,0 +1,137 @@
+// Package notifier sends short notifications, such as shift reminders, to
+// people over whichever channels the shelter has configured: email, an HTTP
+// webhook for chat bots and SMS gateways, or the log. Channels are combined
+// with Multi, and new ones only need to implement Notifier.
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/kholodihor/cows-shelter-backend/mailer"
+)
+
+// ErrNoAddress is returned when a recipient cannot be reached on a channel
+var ErrNoAddress = errors.New("notifier: recipient has no address for this channel")
+
+// Recipient is a person and the addresses they can be reached at
+type Recipient struct {
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+	Phone string `json:"phone,omitempty"`
+}
+
+// Message is a plain-text notification to one recipient
+type Message struct {
+	To      Recipient `json:"to"`
+	Subject string    `json:"subject"`
+	Body    string    `json:"body"`
+}
+
+// Notifier delivers notifications. Implementations must be safe for concurrent use.
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// Log writes notifications to the application log instead of sending them.
+// It is used in development and whenever no channel is configured.
+type Log struct{}
+
+// Notify logs the message
+func (Log) Notify(ctx context.Context, msg Message) error {
+	log.Printf("notifier: to=%s <%s> subject=%q\n%s", msg.To.Name, msg.To.Email, msg.Subject, msg.Body)
+	return nil
+}
+
+// Email delivers notifications as email
+type Email struct {
+	Mailer mailer.Mailer
+}
+
+// Notify emails the message to the recipient's address
+func (n Email) Notify(ctx context.Context, msg Message) error {
+	if msg.To.Email == "" {
+		return ErrNoAddress
+	}
+	return n.Mailer.Send(ctx, mailer.Message{To: msg.To.Email, Subject: msg.Subject, Body: msg.Body})
+}
+
+// Webhook posts notifications as JSON to a URL, where a chat bot or an SMS
+// gateway delivers them. With a Secret, the X-Signature header carries the
+// hex HMAC-SHA256 of the body so the receiver can check the sender.
+type Webhook struct {
+	URL    string
+	Secret string
+	Client *http.Client
+}
+
+// Notify posts the message and expects a 2xx response
+func (n *Webhook) Notify(ctx context.Context, msg Message) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(n.Secret))
+		mac.Write(payload)
+		req.Header.Set("X-Signature", hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	client := n.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("notifier: webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("notifier: webhook: status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Multi delivers each notification through all of its notifiers. It succeeds
+// when at least one of them delivered the message, so a retry never repeats
+// it on the channels that worked; failures of the others are logged.
+type Multi []Notifier
+
+// Notify sends the message through every notifier
+func (m Multi) Notify(ctx context.Context, msg Message) error {
+	var errs []error
+	delivered := false
+	for _, n := range m {
+		err := n.Notify(ctx, msg)
+		switch {
+		case err == nil:
+			delivered = true
+		case !errors.Is(err, ErrNoAddress):
+			errs = append(errs, err)
+		}
+	}
+	if delivered {
+		for _, err := range errs {
+			log.Printf("notifier: partial delivery to %s: %v", msg.To.Name, err)
+		}
+		return nil
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return ErrNoAddress
+}
@@ -0,0 +1,258 @@
+package payments
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMonobankAPI is the Monobank acquiring API
+const DefaultMonobankAPI = "https://api.monobank.ua"
+
+// DefaultMonobankKeyRefetch is the default minimum time between fetches of
+// the public key after a signature did not match
+const DefaultMonobankKeyRefetch = 5 * time.Minute
+
+// monobankCurrencies maps the ISO 4217 numeric codes Monobank sends
+var monobankCurrencies = map[int]string{980: "UAH", 840: "USD", 978: "EUR", 826: "GBP", 985: "PLN"}
+
+// Monobank handles acquiring invoice webhooks. Monobank signs each body with
+// ECDSA and sends the signature in the X-Sign header. The public key to check
+// it is either configured or fetched with the merchant token, and fetched
+// again when a signature does not match, since Monobank rotates it. Forged
+// webhooks must not turn into a flood of requests to Monobank, so the key is
+// fetched again at most once per KeyRefetch.
+type Monobank struct {
+	PublicKey  string // base64-encoded PEM, as returned by /api/merchant/pubkey
+	Token      string // X-Token used to fetch the public key when it is not configured
+	APIBase    string
+	Client     *http.Client
+	KeyRefetch time.Duration // DefaultMonobankKeyRefetch when zero
+
+	mu        sync.Mutex
+	key       *ecdsa.PublicKey
+	fetchedAt time.Time
+}
+
+// Name returns the donation source of Monobank payments
+func (m *Monobank) Name() string { return "monobank" }
+
+// Verify checks the X-Sign signature of the body
+func (m *Monobank) Verify(ctx context.Context, header http.Header, body []byte) error {
+	signature, err := base64.StdEncoding.DecodeString(header.Get("X-Sign"))
+	if err != nil || len(signature) == 0 {
+		return ErrInvalidSignature
+	}
+	digest := sha256.Sum256(body)
+
+	key, err := m.publicKey(ctx, false)
+	if err != nil {
+		return err
+	}
+	if ecdsa.VerifyASN1(key, digest[:], signature) {
+		return nil
+	}
+	if m.PublicKey != "" || m.Token == "" {
+		return ErrInvalidSignature
+	}
+	// The key may have been rotated since it was cached
+	refreshed, err := m.publicKey(ctx, true)
+	if err != nil {
+		return err
+	}
+	if refreshed == key || !ecdsa.VerifyASN1(refreshed, digest[:], signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// publicKey returns the verification key, fetching it when needed. A refresh
+// returns the cached key if it was fetched less than KeyRefetch ago.
+func (m *Monobank) publicKey(ctx context.Context, refresh bool) (*ecdsa.PublicKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.key != nil && (!refresh || time.Since(m.fetchedAt) < m.keyRefetch()) {
+		return m.key, nil
+	}
+
+	encoded := m.PublicKey
+	if encoded == "" {
+		if m.Token == "" {
+			return nil, errors.New("monobank: neither a public key nor a token is configured")
+		}
+		// Failed fetches count too, so an unreachable API is not retried on every webhook
+		if time.Since(m.fetchedAt) < m.keyRefetch() {
+			return nil, errors.New("monobank: public key unavailable, fetching it failed recently")
+		}
+		m.fetchedAt = time.Now()
+		fetched, err := m.fetchPublicKey(ctx)
+		if err != nil {
+			return nil, err
+		}
+		encoded = fetched
+	}
+	key, err := ParseMonobankKey(encoded)
+	if err != nil {
+		return nil, err
+	}
+	m.key = key
+	return key, nil
+}
+
+func (m *Monobank) keyRefetch() time.Duration {
+	if m.KeyRefetch <= 0 {
+		return DefaultMonobankKeyRefetch
+	}
+	return m.KeyRefetch
+}
+
+// fetchPublicKey asks the acquiring API for the current merchant public key
+func (m *Monobank) fetchPublicKey(ctx context.Context) (string, error) {
+	base := m.APIBase
+	if base == "" {
+		base = DefaultMonobankAPI
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(base, "/")+"/api/merchant/pubkey", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Token", m.Token)
+
+	client := m.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("monobank: fetching public key: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("monobank: fetching public key: status %d", resp.StatusCode)
+	}
+
+	var payload struct {
+		Key string `json:"key"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return "", fmt.Errorf("monobank: decoding public key: %w", err)
+	}
+	return payload.Key, nil
+}
+
+// ParseMonobankKey decodes a Monobank public key given as PEM or as
+// base64-encoded PEM
+func ParseMonobankKey(encoded string) (*ecdsa.PublicKey, error) {
+	data := []byte(strings.TrimSpace(encoded))
+	if !strings.HasPrefix(string(data), "-----BEGIN") {
+		decoded, err := base64.StdEncoding.DecodeString(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("monobank: public key is not base64: %w", err)
+		}
+		data = decoded
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("monobank: public key is not PEM")
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("monobank: parsing public key: %w", err)
+	}
+	key, ok := parsed.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.New("monobank: public key is not an ECDSA key")
+	}
+	return key, nil
+}
+
+// monobankInvoice is the body of an invoice status webhook
+type monobankInvoice struct {
+	InvoiceID    string    `json:"invoiceId"`
+	Status       string    `json:"status"`
+	Amount       int64     `json:"amount"`
+	FinalAmount  int64     `json:"finalAmount"`
+	Ccy          int       `json:"ccy"`
+	Reference    string    `json:"reference"`
+	ModifiedDate time.Time `json:"modifiedDate"`
+	CancelList   []struct {
+		Status string `json:"status"`
+		Amount int64  `json:"amount"`
+	} `json:"cancelList"` // refunds of the invoice
+}
+
+// Parse decodes an invoice webhook. Monobank sends one per status change of
+// an invoice, so the event ID combines the invoice, status and change time.
+func (m *Monobank) Parse(body []byte) (*Event, error) {
+	var invoice monobankInvoice
+	if err := json.Unmarshal(body, &invoice); err != nil {
+		return nil, fmt.Errorf("monobank: decoding webhook: %w", err)
+	}
+	if invoice.InvoiceID == "" || invoice.Status == "" {
+		return nil, errors.New("monobank: webhook has no invoiceId or status")
+	}
+
+	event := &Event{
+		ID:   fmt.Sprintf("%s/%s/%s", invoice.InvoiceID, invoice.Status, invoice.ModifiedDate.UTC().Format(time.RFC3339Nano)),
+		Type: "invoice." + invoice.Status,
+	}
+
+	var status string
+	switch invoice.Status {
+	case "created", "processing", "hold":
+		status = StatusPending
+	case "success":
+		status = StatusCompleted
+	case "failure", "expired":
+		status = StatusFailed
+	case "reversed":
+		status = StatusRefunded
+	default:
+		return event, nil
+	}
+
+	currency, ok := monobankCurrencies[invoice.Ccy]
+	if !ok {
+		if invoice.Ccy != 0 {
+			return nil, fmt.Errorf("monobank: unknown currency code %d", invoice.Ccy)
+		}
+		currency = "UAH" // invoices default to hryvnia
+	}
+	amount := invoice.Amount
+	if status == StatusCompleted && invoice.FinalAmount > 0 {
+		amount = invoice.FinalAmount
+	}
+
+	// A partial refund leaves the invoice successful with a lower final
+	// amount, so it is told apart by its cancellations
+	var refunded int64
+	for _, cancel := range invoice.CancelList {
+		if cancel.Status == "success" {
+			refunded += cancel.Amount
+		}
+	}
+	if status == StatusCompleted && refunded > 0 {
+		status, amount = StatusRefunded, invoice.Amount
+	}
+
+	event.Payment = &Payment{
+		ExternalID: invoice.InvoiceID,
+		Status:     status,
+		Amount:     amount,
+		Refunded:   refunded,
+		Currency:   currency,
+		Reference:  invoice.Reference,
+		OccurredAt: invoice.ModifiedDate,
+	}
+	return event, nil
+}
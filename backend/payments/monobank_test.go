@@ -0,0 +1,297 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// readFixture returns a file from testdata without surrounding whitespace
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.TrimSpace(data)
+}
+
+// signedMonobankWebhook returns a fixture body with its X-Sign header
+func signedMonobankWebhook(t *testing.T, name string) ([]byte, http.Header) {
+	t.Helper()
+	header := http.Header{}
+	header.Set("X-Sign", string(readFixture(t, name+".sig")))
+	return readFixture(t, name+".json"), header
+}
+
+// monobankKeyServer serves a merchant public key the way /api/merchant/pubkey does
+type monobankKeyServer struct {
+	server *httptest.Server
+
+	mu      sync.Mutex
+	key     string
+	fetches int
+}
+
+func newMonobankKeyServer(t *testing.T, key string) *monobankKeyServer {
+	t.Helper()
+	s := &monobankKeyServer{key: key}
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.fetches++
+		key := s.key
+		s.mu.Unlock()
+		if r.URL.Path != "/api/merchant/pubkey" || r.Header.Get("X-Token") != "merchant-token" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"key": key})
+	}))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *monobankKeyServer) setKey(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.key = key
+}
+
+func (s *monobankKeyServer) fetchCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fetches
+}
+
+// otherMonobankKey returns a base64-encoded PEM key that did not sign the fixtures
+func otherMonobankKey(t *testing.T) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func TestMonobankVerify(t *testing.T) {
+	m := &Monobank{PublicKey: string(readFixture(t, "monobank_pubkey.txt"))}
+	ctx := context.Background()
+
+	for _, name := range []string{"monobank_invoice_success", "monobank_invoice_reversed"} {
+		body, header := signedMonobankWebhook(t, name)
+		if err := m.Verify(ctx, header, body); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	body, header := signedMonobankWebhook(t, "monobank_invoice_success")
+	_, otherHeader := signedMonobankWebhook(t, "monobank_invoice_reversed")
+	tests := []struct {
+		name   string
+		header http.Header
+		body   []byte
+	}{
+		{"tampered amount", header, bytes.Replace(body, []byte(`"finalAmount":50000`), []byte(`"finalAmount":500000`), 1)},
+		{"tampered reference", header, bytes.Replace(body, []byte("campaign-1"), []byte("campaign-2"), 1)},
+		{"signature of another body", otherHeader, body},
+		{"missing signature", http.Header{}, body},
+		{"signature not base64", http.Header{"X-Sign": {"not base64!"}}, body},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := m.Verify(ctx, tt.header, tt.body); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("got %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+}
+
+func TestMonobankVerifyFetchesKey(t *testing.T) {
+	server := newMonobankKeyServer(t, string(readFixture(t, "monobank_pubkey.txt")))
+	m := &Monobank{Token: "merchant-token", APIBase: server.server.URL}
+	ctx := context.Background()
+
+	body, header := signedMonobankWebhook(t, "monobank_invoice_success")
+	if err := m.Verify(ctx, header, body); err != nil {
+		t.Fatalf("valid webhook: %v", err)
+	}
+	if err := m.Verify(ctx, header, body); err != nil {
+		t.Fatalf("valid webhook with cached key: %v", err)
+	}
+	if n := server.fetchCount(); n != 1 {
+		t.Errorf("key fetched %d times, want 1", n)
+	}
+
+	// Forged webhooks do not each trigger a fetch
+	tampered := bytes.Replace(body, []byte("campaign-1"), []byte("campaign-2"), 1)
+	for i := 0; i < 3; i++ {
+		if err := m.Verify(ctx, header, tampered); !errors.Is(err, ErrInvalidSignature) {
+			t.Fatalf("tampered webhook: got %v, want ErrInvalidSignature", err)
+		}
+	}
+	if n := server.fetchCount(); n != 1 {
+		t.Errorf("key fetched %d times after forged webhooks, want 1", n)
+	}
+}
+
+func TestMonobankVerifyRotatedKey(t *testing.T) {
+	server := newMonobankKeyServer(t, otherMonobankKey(t))
+	m := &Monobank{Token: "merchant-token", APIBase: server.server.URL, KeyRefetch: time.Minute}
+	ctx := context.Background()
+	body, header := signedMonobankWebhook(t, "monobank_invoice_success")
+
+	// The cached key is outdated, but it was fetched too recently to fetch again
+	if err := m.Verify(ctx, header, body); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("got %v, want ErrInvalidSignature", err)
+	}
+	if n := server.fetchCount(); n != 1 {
+		t.Fatalf("key fetched %d times, want 1", n)
+	}
+
+	server.setKey(string(readFixture(t, "monobank_pubkey.txt")))
+	m.fetchedAt = time.Now().Add(-2 * time.Minute)
+	if err := m.Verify(ctx, header, body); err != nil {
+		t.Fatalf("webhook signed with the rotated key: %v", err)
+	}
+	if n := server.fetchCount(); n != 2 {
+		t.Errorf("key fetched %d times, want 2", n)
+	}
+}
+
+func TestMonobankVerifyUnreachableAPI(t *testing.T) {
+	server := newMonobankKeyServer(t, "")
+	m := &Monobank{Token: "wrong-token", APIBase: server.server.URL}
+	ctx := context.Background()
+	body, header := signedMonobankWebhook(t, "monobank_invoice_success")
+
+	for i := 0; i < 3; i++ {
+		if err := m.Verify(ctx, header, body); err == nil || errors.Is(err, ErrInvalidSignature) {
+			t.Fatalf("got %v, want a key error", err)
+		}
+	}
+	if n := server.fetchCount(); n != 1 {
+		t.Errorf("key fetched %d times, want 1", n)
+	}
+}
+
+func TestMonobankParse(t *testing.T) {
+	m := &Monobank{}
+
+	event, err := m.Parse(readFixture(t, "monobank_invoice_success.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.ID != "p2_9ZgpZVsl3/success/2025-03-08T10:16:03Z" || event.Type != "invoice.success" {
+		t.Errorf("got event %q of type %q", event.ID, event.Type)
+	}
+	want := Payment{
+		ExternalID: "p2_9ZgpZVsl3",
+		Status:     StatusCompleted,
+		Amount:     50000,
+		Currency:   "UAH",
+		Reference:  "campaign-1",
+		OccurredAt: time.Date(2025, 3, 8, 10, 16, 3, 0, time.UTC),
+	}
+	if event.Payment == nil || *event.Payment != want {
+		t.Errorf("got payment %+v, want %+v", event.Payment, want)
+	}
+
+	// A refund keeps the amount of the payment it undoes
+	event, err = m.Parse(readFixture(t, "monobank_invoice_reversed.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Payment == nil || event.Payment.ExternalID != "p2_9ZgpZVsl3" ||
+		event.Payment.Status != StatusRefunded || event.Payment.Amount != 50000 || event.Payment.Refunded != 50000 {
+		t.Errorf("got payment %+v for the reversed invoice", event.Payment)
+	}
+}
+
+func TestMonobankParsePartialRefund(t *testing.T) {
+	// A partially refunded invoice stays successful with a lower final amount
+	body := `{"invoiceId":"inv-1","status":"success","amount":50000,"finalAmount":45000,"ccy":980,` +
+		`"modifiedDate":"2025-03-09T08:02:11Z","cancelList":[` +
+		`{"status":"success","amount":5000,"ccy":980},{"status":"failure","amount":10000,"ccy":980}]}`
+	event, err := (&Monobank{}).Parse([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Payment == nil || event.Payment.Status != StatusRefunded ||
+		event.Payment.Amount != 50000 || event.Payment.Refunded != 5000 {
+		t.Errorf("got payment %+v for the partial refund", event.Payment)
+	}
+}
+
+func TestMonobankParseStatuses(t *testing.T) {
+	tests := []struct {
+		status string
+		want   string // empty when the event does not concern a payment
+	}{
+		{"created", StatusPending},
+		{"processing", StatusPending},
+		{"hold", StatusPending},
+		{"success", StatusCompleted},
+		{"failure", StatusFailed},
+		{"expired", StatusFailed},
+		{"reversed", StatusRefunded},
+		{"something_new", ""},
+	}
+	m := &Monobank{}
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			body := `{"invoiceId":"inv-1","status":"` + tt.status + `","amount":1000,"ccy":840,"modifiedDate":"2025-03-08T10:16:03Z"}`
+			event, err := m.Parse([]byte(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == "" {
+				if event.Payment != nil {
+					t.Errorf("got payment %+v, want none", event.Payment)
+				}
+				return
+			}
+			if event.Payment == nil || event.Payment.Status != tt.want || event.Payment.Currency != "USD" {
+				t.Errorf("got payment %+v, want status %s in USD", event.Payment, tt.want)
+			}
+		})
+	}
+}
+
+func TestMonobankParseRejects(t *testing.T) {
+	m := &Monobank{}
+	for _, body := range []string{
+		`not json`,
+		`{"status":"success","amount":1000}`,
+		`{"invoiceId":"inv-1","amount":1000}`,
+		`{"invoiceId":"inv-1","status":"success","amount":1000,"ccy":999}`,
+	} {
+		if _, err := m.Parse([]byte(body)); err == nil {
+			t.Errorf("%s: parsed without error", body)
+		}
+	}
+
+	// Invoices without a currency are in hryvnia
+	event, err := m.Parse([]byte(`{"invoiceId":"inv-1","status":"success","amount":1000}`))
+	if err != nil || event.Payment == nil || !strings.EqualFold(event.Payment.Currency, "UAH") {
+		t.Errorf("got %+v, %v", event, err)
+	}
+}
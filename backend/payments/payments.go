@@ -0,0 +1,88 @@
+// Package payments verifies and decodes webhook notifications from the
+// payment providers donors use, turning them into provider-neutral payments.
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSignature is returned when a webhook cannot be proven to come
+// from the provider
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Payment statuses, matching the donation statuses they are stored as
+const (
+	StatusPending   = "pending"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusRefunded  = "refunded"
+)
+
+// Payment is the state of one payment as reported by a provider
+type Payment struct {
+	ExternalID string // the provider's payment reference, stable across events
+	Status     string
+	Amount     int64 // in minor currency units
+	Refunded   int64 // for refunds, the total returned so far; zero when the provider does not say, which counts as everything
+	Currency   string
+	DonorName  string
+	DonorEmail string
+	Message    string
+	Reference  string // merchant reference passed when the payment was created
+	OccurredAt time.Time
+}
+
+// Event is a verified webhook notification
+type Event struct {
+	ID      string   // unique per notification; repeated deliveries share it
+	Type    string   // provider event type
+	Payment *Payment // nil for events that do not concern a payment
+}
+
+// Provider verifies and parses the webhooks of one payment provider.
+// Implementations must be safe for concurrent use.
+type Provider interface {
+	// Name is the donation source the provider's payments are recorded under
+	Name() string
+	// Verify checks that the request was sent by the provider
+	Verify(ctx context.Context, header http.Header, body []byte) error
+	// Parse decodes a verified webhook body
+	Parse(body []byte) (*Event, error)
+}
+
+// zeroDecimalCurrencies have no minor unit
+var zeroDecimalCurrencies = map[string]bool{"JPY": true, "HUF": true, "TWD": true}
+
+// ParseAmount converts a decimal amount such as "10.50" to minor units of the currency
+func ParseAmount(value, currency string) (int64, error) {
+	rat, ok := new(big.Rat).SetString(strings.TrimSpace(value))
+	if !ok || rat.Sign() < 0 {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	if !zeroDecimalCurrencies[strings.ToUpper(currency)] {
+		rat.Mul(rat, big.NewRat(100, 1))
+	}
+	if !rat.IsInt() || !rat.Num().IsInt64() {
+		return 0, fmt.Errorf("invalid amount %q for %s", value, currency)
+	}
+	return rat.Num().Int64(), nil
+}
+
+// FormatAmount formats an amount in minor units of the currency as a decimal
+// such as "10.50", the reverse of ParseAmount
+func FormatAmount(amount int64, currency string) string {
+	if zeroDecimalCurrencies[strings.ToUpper(currency)] {
+		return fmt.Sprintf("%d", amount)
+	}
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/100, amount%100)
+}
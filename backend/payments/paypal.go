@@ -0,0 +1,294 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPayPalAPI is the live PayPal REST API; use
+// https://api-m.sandbox.paypal.com for the sandbox
+const DefaultPayPalAPI = "https://api-m.paypal.com"
+
+// PayPal handles REST webhooks. PayPal signs them with its own certificate,
+// and each one is checked with the verify-webhook-signature API against the
+// ID of the webhook registered in the PayPal dashboard.
+type PayPal struct {
+	ClientID     string
+	ClientSecret string
+	WebhookID    string
+	APIBase      string
+	Client       *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	tokenExpiry time.Time
+}
+
+// Name returns the donation source of PayPal payments
+func (p *PayPal) Name() string { return "paypal" }
+
+// paypalVerifyHeaders maps the verification request fields to the webhook headers
+var paypalVerifyHeaders = map[string]string{
+	"auth_algo":         "PAYPAL-AUTH-ALGO",
+	"cert_url":          "PAYPAL-CERT-URL",
+	"transmission_id":   "PAYPAL-TRANSMISSION-ID",
+	"transmission_sig":  "PAYPAL-TRANSMISSION-SIG",
+	"transmission_time": "PAYPAL-TRANSMISSION-TIME",
+}
+
+// Verify asks PayPal whether the transmission headers sign the body
+func (p *PayPal) Verify(ctx context.Context, header http.Header, body []byte) error {
+	if p.WebhookID == "" {
+		return errors.New("paypal: PAYPAL_WEBHOOK_ID is not configured")
+	}
+	request := map[string]interface{}{
+		"webhook_id":    p.WebhookID,
+		"webhook_event": json.RawMessage(body),
+	}
+	for field, name := range paypalVerifyHeaders {
+		value := header.Get(name)
+		if value == "" {
+			return ErrInvalidSignature
+		}
+		request[field] = value
+	}
+	payload, err := json.Marshal(request)
+	if err != nil {
+		// The body is not valid JSON, so it cannot be a PayPal event
+		return ErrInvalidSignature
+	}
+
+	var result struct {
+		VerificationStatus string `json:"verification_status"`
+	}
+	if err := p.call(ctx, "/v1/notifications/verify-webhook-signature", payload, &result); err != nil {
+		return err
+	}
+	if result.VerificationStatus != "SUCCESS" {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// call posts JSON to the REST API with an OAuth access token, getting a new
+// token once if the cached one was rejected
+func (p *PayPal) call(ctx context.Context, path string, payload []byte, result interface{}) error {
+	for attempt := 0; ; attempt++ {
+		token, err := p.token(ctx, attempt > 0)
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.base()+path, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := p.client().Do(req)
+		if err != nil {
+			return fmt.Errorf("paypal: %s: %w", path, err)
+		}
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			resp.Body.Close()
+			continue
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("paypal: %s: status %d", path, resp.StatusCode)
+		}
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			return fmt.Errorf("paypal: decoding %s response: %w", path, err)
+		}
+		return nil
+	}
+}
+
+// token returns a client-credentials access token, reusing it until shortly
+// before it expires
+func (p *PayPal) token(ctx context.Context, refresh bool) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.accessToken != "" && !refresh && time.Now().Before(p.tokenExpiry) {
+		return p.accessToken, nil
+	}
+	if p.ClientID == "" || p.ClientSecret == "" {
+		return "", errors.New("paypal: client credentials are not configured")
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.base()+"/v1/oauth2/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(p.ClientID, p.ClientSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.client().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("paypal: requesting access token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("paypal: requesting access token: status %d", resp.StatusCode)
+	}
+
+	var payload struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return "", fmt.Errorf("paypal: decoding access token: %w", err)
+	}
+	p.accessToken = payload.AccessToken
+	p.tokenExpiry = time.Now().Add(time.Duration(payload.ExpiresIn)*time.Second - time.Minute)
+	return p.accessToken, nil
+}
+
+func (p *PayPal) base() string {
+	if p.APIBase == "" {
+		return DefaultPayPalAPI
+	}
+	return strings.TrimRight(p.APIBase, "/")
+}
+
+func (p *PayPal) client() *http.Client {
+	if p.Client == nil {
+		return &http.Client{Timeout: 10 * time.Second}
+	}
+	return p.Client
+}
+
+// paypalEvent is the envelope of a REST webhook
+type paypalEvent struct {
+	ID         string          `json:"id"`
+	EventType  string          `json:"event_type"`
+	CreateTime time.Time       `json:"create_time"`
+	Resource   json.RawMessage `json:"resource"`
+}
+
+// paypalResource holds the fields used from capture, refund and sale resources
+type paypalResource struct {
+	ID       string `json:"id"`
+	CustomID string `json:"custom_id"` // captures and refunds
+	Custom   string `json:"custom"`    // sales
+	SaleID   string `json:"sale_id"`   // sale refunds
+	Amount   struct {
+		Value        string `json:"value"`
+		CurrencyCode string `json:"currency_code"`
+		Total        string `json:"total"`    // sales
+		Currency     string `json:"currency"` // sales
+	} `json:"amount"`
+	// Refunds also report the total refunded on their payment so far
+	SellerPayableBreakdown struct {
+		TotalRefundedAmount struct {
+			Value string `json:"value"`
+		} `json:"total_refunded_amount"`
+	} `json:"seller_payable_breakdown"` // capture refunds
+	TotalRefundedAmount struct {
+		Value string `json:"value"`
+	} `json:"total_refunded_amount"` // sale refunds
+	Links []struct {
+		Href string `json:"href"`
+		Rel  string `json:"rel"`
+	} `json:"links"`
+}
+
+// paypalStatuses maps the payment event types to payment statuses
+var paypalStatuses = map[string]string{
+	"PAYMENT.CAPTURE.COMPLETED": StatusCompleted,
+	"PAYMENT.CAPTURE.PENDING":   StatusPending,
+	"PAYMENT.CAPTURE.DENIED":    StatusFailed,
+	"PAYMENT.CAPTURE.DECLINED":  StatusFailed,
+	"PAYMENT.CAPTURE.REFUNDED":  StatusRefunded,
+	"PAYMENT.CAPTURE.REVERSED":  StatusRefunded,
+	"PAYMENT.SALE.COMPLETED":    StatusCompleted,
+	"PAYMENT.SALE.PENDING":      StatusPending,
+	"PAYMENT.SALE.DENIED":       StatusFailed,
+	"PAYMENT.SALE.REFUNDED":     StatusRefunded,
+	"PAYMENT.SALE.REVERSED":     StatusRefunded,
+}
+
+// Parse decodes a webhook. Capture and sale events become payments; refunds
+// and reversals refer back to the captured payment they undo.
+func (p *PayPal) Parse(body []byte) (*Event, error) {
+	var envelope paypalEvent
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, fmt.Errorf("paypal: decoding webhook: %w", err)
+	}
+	if envelope.ID == "" || envelope.EventType == "" {
+		return nil, errors.New("paypal: webhook has no id or event_type")
+	}
+	event := &Event{ID: envelope.ID, Type: envelope.EventType}
+
+	status, ok := paypalStatuses[envelope.EventType]
+	if !ok {
+		return event, nil
+	}
+	var resource paypalResource
+	if err := json.Unmarshal(envelope.Resource, &resource); err != nil {
+		return nil, fmt.Errorf("paypal: decoding %s resource: %w", envelope.EventType, err)
+	}
+
+	value, currency := resource.Amount.Value, resource.Amount.CurrencyCode
+	if value == "" {
+		value, currency = resource.Amount.Total, resource.Amount.Currency
+	}
+	currency = strings.ToUpper(currency)
+	amount, err := ParseAmount(value, currency)
+	if err != nil {
+		return nil, fmt.Errorf("paypal: %w", err)
+	}
+
+	externalID := resource.ID
+	var refunded int64
+	if status == StatusRefunded {
+		total := resource.SellerPayableBreakdown.TotalRefundedAmount.Value
+		if total == "" {
+			total = resource.TotalRefundedAmount.Value
+		}
+		refunded = amount // without a total, this is taken to be the only refund
+		if total != "" {
+			if refunded, err = ParseAmount(total, currency); err != nil {
+				return nil, fmt.Errorf("paypal: refunded total: %w", err)
+			}
+		}
+
+		// Refund resources point at the payment they undo; reversals of
+		// sales carry the sale itself
+		if resource.SaleID != "" {
+			externalID = resource.SaleID
+		}
+		for _, link := range resource.Links {
+			if link.Rel == "up" && strings.Contains(link.Href, "/captures/") {
+				externalID = link.Href[strings.LastIndex(link.Href, "/")+1:]
+			}
+		}
+	}
+	if externalID == "" {
+		return nil, fmt.Errorf("paypal: %s event %s does not name its payment", envelope.EventType, envelope.ID)
+	}
+
+	reference := resource.CustomID
+	if reference == "" {
+		reference = resource.Custom
+	}
+	event.Payment = &Payment{
+		ExternalID: externalID,
+		Status:     status,
+		Amount:     amount,
+		Refunded:   refunded,
+		Currency:   currency,
+		Reference:  reference,
+		OccurredAt: envelope.CreateTime,
+	}
+	return event, nil
+}
@@ -0,0 +1,244 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+const stubPayPalWebhookID = "3WN01883JV181864H"
+
+// paypalStub is the part of the PayPal REST API used to verify webhooks. It
+// only accepts the transmission it was given, like PayPal only accepts
+// bodies signed with its certificate.
+type paypalStub struct {
+	server *httptest.Server
+	body   []byte
+	sig    string
+
+	mu     sync.Mutex
+	tokens int
+}
+
+func newPayPalStub(t *testing.T, body []byte, sig string) *paypalStub {
+	t.Helper()
+	s := &paypalStub{body: body, sig: sig}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, ok := r.BasicAuth(); !ok || id != "client" || secret != "secret" ||
+			r.FormValue("grant_type") != "client_credentials" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		s.mu.Lock()
+		s.tokens++
+		s.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "stub-token", "expires_in": 3600})
+	})
+	mux.HandleFunc("/v1/notifications/verify-webhook-signature", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer stub-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var request struct {
+			WebhookID       string          `json:"webhook_id"`
+			TransmissionSig string          `json:"transmission_sig"`
+			WebhookEvent    json.RawMessage `json:"webhook_event"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		status := "FAILURE"
+		if request.WebhookID == stubPayPalWebhookID && request.TransmissionSig == s.sig && sameJSON(request.WebhookEvent, s.body) {
+			status = "SUCCESS"
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"verification_status": status})
+	})
+
+	s.server = httptest.NewServer(mux)
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *paypalStub) tokenCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tokens
+}
+
+func sameJSON(a, b []byte) bool {
+	var x, y interface{}
+	return json.Unmarshal(a, &x) == nil && json.Unmarshal(b, &y) == nil && reflect.DeepEqual(x, y)
+}
+
+// paypalHeaders returns the transmission headers PayPal sends with a webhook
+func paypalHeaders(sig string) http.Header {
+	header := http.Header{}
+	header.Set("PAYPAL-AUTH-ALGO", "SHA256withRSA")
+	header.Set("PAYPAL-CERT-URL", "https://api.paypal.com/v1/notifications/certs/CERT-360caa42-fca2a594-a5cafa77")
+	header.Set("PAYPAL-TRANSMISSION-ID", "69cd13f0-d67a-11e5-baa3-778b53f4ae55")
+	header.Set("PAYPAL-TRANSMISSION-SIG", sig)
+	header.Set("PAYPAL-TRANSMISSION-TIME", "2025-03-08T12:03:52Z")
+	return header
+}
+
+func TestPayPalVerify(t *testing.T) {
+	body := readFixture(t, "paypal_capture_completed.json")
+	stub := newPayPalStub(t, body, "stub-signature")
+	p := &PayPal{ClientID: "client", ClientSecret: "secret", WebhookID: stubPayPalWebhookID, APIBase: stub.server.URL}
+	ctx := context.Background()
+
+	if err := p.Verify(ctx, paypalHeaders("stub-signature"), body); err != nil {
+		t.Fatalf("valid webhook: %v", err)
+	}
+	if err := p.Verify(ctx, paypalHeaders("stub-signature"), body); err != nil {
+		t.Fatalf("valid webhook with cached token: %v", err)
+	}
+	if n := stub.tokenCount(); n != 1 {
+		t.Errorf("requested %d access tokens, want 1", n)
+	}
+
+	missing := paypalHeaders("stub-signature")
+	missing.Del("PAYPAL-CERT-URL")
+	tests := []struct {
+		name   string
+		header http.Header
+		body   []byte
+	}{
+		{"tampered amount", paypalHeaders("stub-signature"), bytes.Replace(body, []byte(`"value":"25.00"`), []byte(`"value":"2500.00"`), 1)},
+		{"another event", paypalHeaders("stub-signature"), readFixture(t, "paypal_capture_refunded.json")},
+		{"wrong signature", paypalHeaders("forged"), body},
+		{"missing header", missing, body},
+		{"body not JSON", paypalHeaders("stub-signature"), []byte("not json")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := p.Verify(ctx, tt.header, tt.body); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("got %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+
+	unconfigured := &PayPal{ClientID: "client", ClientSecret: "secret", APIBase: stub.server.URL}
+	if err := unconfigured.Verify(ctx, paypalHeaders("stub-signature"), body); err == nil {
+		t.Error("verified without a webhook ID")
+	}
+}
+
+func TestPayPalParse(t *testing.T) {
+	p := &PayPal{}
+
+	event, err := p.Parse(readFixture(t, "paypal_capture_completed.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.ID != "WH-58D329510W468432D-8HN650336L201105X" || event.Type != "PAYMENT.CAPTURE.COMPLETED" {
+		t.Errorf("got event %q of type %q", event.ID, event.Type)
+	}
+	// The "up" link of a capture points at its order, not at a payment
+	want := Payment{
+		ExternalID: "42311647XV020574X",
+		Status:     StatusCompleted,
+		Amount:     2500,
+		Currency:   "USD",
+		Reference:  "campaign-1",
+	}
+	if event.Payment == nil {
+		t.Fatal("no payment")
+	}
+	got := *event.Payment
+	got.OccurredAt = time.Time{}
+	if got != want {
+		t.Errorf("got payment %+v, want %+v", got, want)
+	}
+	if !event.Payment.OccurredAt.Equal(time.Date(2025, 3, 8, 12, 3, 51, 232000000, time.UTC)) {
+		t.Errorf("got time %v", event.Payment.OccurredAt)
+	}
+
+	// The refund names the capture it undoes, not itself
+	event, err = p.Parse(readFixture(t, "paypal_capture_refunded.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Payment == nil || event.Payment.ExternalID != "42311647XV020574X" ||
+		event.Payment.Status != StatusRefunded || event.Payment.Amount != 2500 || event.Payment.Refunded != 2500 {
+		t.Errorf("got payment %+v for the refund", event.Payment)
+	}
+
+	// A partial refund reports its own amount and the total refunded so far
+	event, err = p.Parse(readFixture(t, "paypal_capture_partially_refunded.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Payment == nil || event.Payment.ExternalID != "42311647XV020574X" ||
+		event.Payment.Status != StatusRefunded || event.Payment.Amount != 500 || event.Payment.Refunded != 500 {
+		t.Errorf("got payment %+v for the partial refund", event.Payment)
+	}
+}
+
+func TestPayPalParseStatuses(t *testing.T) {
+	for eventType, want := range paypalStatuses {
+		t.Run(eventType, func(t *testing.T) {
+			body := `{"id":"WH-1","event_type":"` + eventType + `","resource":{"id":"CAP-1","amount":{"value":"10.50","currency_code":"eur"}}}`
+			event, err := (&PayPal{}).Parse([]byte(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if event.Payment == nil || event.Payment.Status != want ||
+				event.Payment.Amount != 1050 || event.Payment.Currency != "EUR" {
+				t.Errorf("got payment %+v, want status %s", event.Payment, want)
+			}
+		})
+	}
+
+	event, err := (&PayPal{}).Parse([]byte(`{"id":"WH-2","event_type":"CHECKOUT.ORDER.APPROVED","resource":{}}`))
+	if err != nil || event.Payment != nil {
+		t.Errorf("unrelated event: got %+v, %v", event, err)
+	}
+}
+
+func TestPayPalParseSaleRefund(t *testing.T) {
+	body := `{"id":"WH-3","event_type":"PAYMENT.SALE.REFUNDED","create_time":"2025-03-10T09:21:14Z",` +
+		`"resource":{"id":"REF-1","sale_id":"SALE-1","custom":"campaign-3","amount":{"total":"7.00","currency":"USD"}}}`
+	event, err := (&PayPal{}).Parse([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Payment == nil || event.Payment.ExternalID != "SALE-1" || event.Payment.Amount != 700 ||
+		event.Payment.Reference != "campaign-3" {
+		t.Errorf("got payment %+v", event.Payment)
+	}
+
+	// Later refunds of a sale carry the running total
+	body = `{"id":"WH-4","event_type":"PAYMENT.SALE.REFUNDED","resource":{"id":"REF-2","sale_id":"SALE-1",` +
+		`"amount":{"total":"2.00","currency":"USD"},"total_refunded_amount":{"value":"9.00","currency":"USD"}}}`
+	event, err = (&PayPal{}).Parse([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Payment == nil || event.Payment.Amount != 200 || event.Payment.Refunded != 900 {
+		t.Errorf("got payment %+v", event.Payment)
+	}
+}
+
+func TestPayPalParseRejects(t *testing.T) {
+	for _, body := range []string{
+		`not json`,
+		`{"event_type":"PAYMENT.CAPTURE.COMPLETED"}`,
+		`{"id":"WH-1","event_type":"PAYMENT.CAPTURE.COMPLETED","resource":{"id":"CAP-1","amount":{"value":"ten","currency_code":"USD"}}}`,
+		`{"id":"WH-1","event_type":"PAYMENT.CAPTURE.COMPLETED","resource":{"amount":{"value":"10.00","currency_code":"USD"}}}`,
+	} {
+		if _, err := (&PayPal{}).Parse([]byte(body)); err == nil {
+			t.Errorf("%s: parsed without error", body)
+		}
+	}
+}
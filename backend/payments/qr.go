@@ -0,0 +1,149 @@
+package payments
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode/utf8"
+)
+
+// NBUQRBase is the link prefix of Ukrainian payment QR codes; banking apps
+// open the link and read the transfer from its base64url-encoded data
+const NBUQRBase = "https://bank.gov.ua/qr/"
+
+// BankTransfer describes a credit transfer to encode in a payment QR code
+type BankTransfer struct {
+	Name          string // recipient, up to 70 characters
+	IBAN          string
+	BIC           string // EPC only, optional within the EEA
+	RecipientCode string // NBU only: EDRPOU or individual tax number
+	Amount        int64  // in minor units, 0 lets the payer choose
+	Purpose       string // up to 140 characters
+}
+
+// NBUPayload builds a hryvnia transfer in the National Bank of Ukraine QR
+// format (version 002, UTF-8, function UCT)
+func NBUPayload(t BankTransfer) (string, error) {
+	iban := normalizeIBAN(t.IBAN)
+	if !strings.HasPrefix(iban, "UA") || !ValidIBAN(iban) {
+		return "", errors.New("a valid Ukrainian IBAN is required")
+	}
+	code := strings.TrimSpace(t.RecipientCode)
+	if len(code) < 8 || len(code) > 10 || strings.Trim(code, "0123456789") != "" {
+		return "", errors.New("the recipient code must be an EDRPOU or tax number of 8 to 10 digits")
+	}
+	if err := checkTransferText(t); err != nil {
+		return "", err
+	}
+
+	amount := ""
+	if t.Amount > 0 {
+		amount = "UAH" + formatMinorUnits(t.Amount)
+	}
+	fields := []string{
+		"BCD",
+		"002",
+		"1",
+		"UCT",
+		"",
+		strings.TrimSpace(t.Name),
+		iban,
+		amount,
+		code,
+		"",
+		"",
+		strings.TrimSpace(t.Purpose),
+		"",
+	}
+	data := strings.Join(fields, "\n")
+	return NBUQRBase + base64.RawURLEncoding.EncodeToString([]byte(data)), nil
+}
+
+// EPCPayload builds a euro SEPA credit transfer in the European Payments
+// Council QR format (EPC069-12, version 002, UTF-8)
+func EPCPayload(t BankTransfer) (string, error) {
+	iban := normalizeIBAN(t.IBAN)
+	if !ValidIBAN(iban) {
+		return "", errors.New("a valid IBAN is required")
+	}
+	if err := checkTransferText(t); err != nil {
+		return "", err
+	}
+	if t.Amount > 99999999999 {
+		return "", errors.New("the amount must not exceed 999999999.99 EUR")
+	}
+
+	amount := ""
+	if t.Amount > 0 {
+		amount = "EUR" + formatMinorUnits(t.Amount)
+	}
+	fields := []string{
+		"BCD",
+		"002",
+		"1",
+		"SCT",
+		strings.ToUpper(strings.TrimSpace(t.BIC)),
+		strings.TrimSpace(t.Name),
+		iban,
+		amount,
+		"",
+		"",
+		strings.TrimSpace(t.Purpose),
+	}
+	payload := strings.Join(fields, "\n")
+	if len(payload) > 331 {
+		return "", errors.New("the transfer details are too long for an EPC QR code")
+	}
+	return payload, nil
+}
+
+// checkTransferText enforces the length limits both formats share
+func checkTransferText(t BankTransfer) error {
+	name := strings.TrimSpace(t.Name)
+	if name == "" || utf8.RuneCountInString(name) > 70 {
+		return errors.New("the recipient name must be 1 to 70 characters")
+	}
+	if utf8.RuneCountInString(strings.TrimSpace(t.Purpose)) > 140 {
+		return errors.New("the purpose must not exceed 140 characters")
+	}
+	if strings.ContainsAny(name+t.Purpose, "\r\n") {
+		return errors.New("the recipient name and purpose must be single lines")
+	}
+	if t.Amount < 0 {
+		return errors.New("the amount cannot be negative")
+	}
+	return nil
+}
+
+// formatMinorUnits formats an amount in minor units as 123.45
+func formatMinorUnits(amount int64) string {
+	return fmt.Sprintf("%d.%02d", amount/100, amount%100)
+}
+
+// normalizeIBAN removes spaces and upper-cases an IBAN
+func normalizeIBAN(iban string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(iban), " ", ""))
+}
+
+// ValidIBAN checks the structure and the ISO 13616 check digits of an IBAN
+func ValidIBAN(iban string) bool {
+	iban = normalizeIBAN(iban)
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+	var digits strings.Builder
+	for _, r := range iban[4:] + iban[:4] {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r >= 'A' && r <= 'Z':
+			fmt.Fprintf(&digits, "%d", r-'A'+10)
+		default:
+			return false
+		}
+	}
+	n, ok := new(big.Int).SetString(digits.String(), 10)
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
@@ -0,0 +1,143 @@
+package payments
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestNBUPayload(t *testing.T) {
+	transfer := BankTransfer{
+		Name:          "Благодійний фонд «Корівник»",
+		IBAN:          "ua21 3223 1300 0002 6007 2335 6600 1",
+		RecipientCode: "12345678",
+		Amount:        25000,
+		Purpose:       "Благодійний внесок",
+	}
+
+	// NBU QR version 002: thirteen lines, with the transfer in lines 6 to 9
+	// and 12, encoded as unpadded base64url after the bank.gov.ua link
+	want := NBUQRBase + "QkNECjAwMgoxClVDVAoK0JHQu9Cw0LPQvtC00ZbQudC90LjQuSDRhNC-0L3QtCDCq9Ca0L7RgNGW0LLQvdC40LrCuwpVQTIxMzIyMzEzMDAwMDAyNjAwNzIzMzU2NjAwMQpVQUgyNTAuMDAKMTIzNDU2NzgKCgrQkdC70LDQs9C-0LTRltC50L3QuNC5INCy0L3QtdGB0L7Qugo"
+	got, err := NBUPayload(transfer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	// Without an amount the payer chooses it
+	transfer.Amount = 0
+	got, err = NBUPayload(transfer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(got, NBUQRBase))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantData := "BCD\n002\n1\nUCT\n\nБлагодійний фонд «Корівник»\nUA213223130000026007233566001\n\n12345678\n\n\nБлагодійний внесок\n"
+	if string(data) != wantData {
+		t.Errorf("got %q, want %q", data, wantData)
+	}
+}
+
+func TestNBUPayloadRejects(t *testing.T) {
+	valid := BankTransfer{Name: "Cows Shelter", IBAN: "UA213223130000026007233566001", RecipientCode: "12345678"}
+	tests := []struct {
+		name   string
+		change func(*BankTransfer)
+	}{
+		{"foreign IBAN", func(transfer *BankTransfer) { transfer.IBAN = "DE89370400440532013000" }},
+		{"wrong check digits", func(transfer *BankTransfer) { transfer.IBAN = "UA213223130000026007233566002" }},
+		{"short recipient code", func(transfer *BankTransfer) { transfer.RecipientCode = "1234567" }},
+		{"recipient code with letters", func(transfer *BankTransfer) { transfer.RecipientCode = "1234567A" }},
+		{"no name", func(transfer *BankTransfer) { transfer.Name = " " }},
+		{"name too long", func(transfer *BankTransfer) { transfer.Name = strings.Repeat("к", 71) }},
+		{"purpose too long", func(transfer *BankTransfer) { transfer.Purpose = strings.Repeat("п", 141) }},
+		{"purpose on two lines", func(transfer *BankTransfer) { transfer.Purpose = "line\nline" }},
+		{"negative amount", func(transfer *BankTransfer) { transfer.Amount = -1 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transfer := valid
+			tt.change(&transfer)
+			if got, err := NBUPayload(transfer); err == nil {
+				t.Errorf("built %s", got)
+			}
+		})
+	}
+}
+
+func TestEPCPayload(t *testing.T) {
+	tests := []struct {
+		name     string
+		transfer BankTransfer
+		want     string
+	}{
+		{
+			// The example of EPC069-12, without a purpose code
+			name: "with BIC and amount",
+			transfer: BankTransfer{Name: "Red Cross of Belgium", IBAN: "BE72000000001616", BIC: "bpotbeb1",
+				Amount: 100, Purpose: "Urgency fund"},
+			want: "BCD\n002\n1\nSCT\nBPOTBEB1\nRed Cross of Belgium\nBE72000000001616\nEUR1.00\n\n\nUrgency fund",
+		},
+		{
+			name:     "without BIC and amount",
+			transfer: BankTransfer{Name: "Franz Mustermänner", IBAN: "DE89 3704 0044 0532 0130 00", Purpose: "Spende"},
+			want:     "BCD\n002\n1\nSCT\n\nFranz Mustermänner\nDE89370400440532013000\n\n\n\nSpende",
+		},
+		{
+			name:     "largest amount",
+			transfer: BankTransfer{Name: "Cows Shelter", IBAN: "NL91ABNA0417164300", Amount: 99999999999},
+			want:     "BCD\n002\n1\nSCT\n\nCows Shelter\nNL91ABNA0417164300\nEUR999999999.99\n\n\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EPCPayload(tt.transfer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	for _, transfer := range []BankTransfer{
+		{Name: "Cows Shelter", IBAN: "NL91ABNA0417164301"},
+		{Name: "Cows Shelter", IBAN: "NL91ABNA0417164300", Amount: 100000000000},
+		{Name: "", IBAN: "NL91ABNA0417164300"},
+	} {
+		if got, err := EPCPayload(transfer); err == nil {
+			t.Errorf("%+v: built %q", transfer, got)
+		}
+	}
+}
+
+func TestValidIBAN(t *testing.T) {
+	tests := []struct {
+		iban string
+		want bool
+	}{
+		{"UA213223130000026007233566001", true},
+		{"UA903052992990004149123456789", true},
+		{"DE89370400440532013000", true},
+		{"GB82WEST12345698765432", true},
+		{"gb82 west 1234 5698 7654 32", true},
+		{"BE72000000001616", true},
+		{"UA213223130000026007233566002", false}, // one digit off
+		{"GB82WEST12345698765433", false},
+		{"GB28WEST12345698765432", false},              // check digits swapped
+		{"DE8937040044", false},                        // too short
+		{"UA213223130000026007233566001234567", false}, // too long
+		{"DE89-3704-0044-0532-0130-00", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := ValidIBAN(tt.iban); got != tt.want {
+			t.Errorf("ValidIBAN(%q) = %v, want %v", tt.iban, got, tt.want)
+		}
+	}
+}
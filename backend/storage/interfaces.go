@@ -2,10 +2,16 @@ package storage
 
 import (
 	"context"
+	"io"
 	"mime/multipart"
 	"time"
 )
 
+// PrivatePrefix holds objects that must never be reachable through a public
+// URL, such as medical files. The bucket policy denies public reads under it,
+// and the API serves these objects only to authorized users via GetObject.
+const PrivatePrefix = "private/"
+
 // ObjectInfo contains information about a stored object
 type ObjectInfo struct {
 	Key          string
@@ -22,6 +28,20 @@ type Service interface {
 	// UploadBase64 uploads a base64-encoded image and returns the URL
 	UploadBase64(ctx context.Context, base64Data, folder string) (string, error)
 
+	// UploadPrivateBase64 uploads a base64 data URL under PrivatePrefix and
+	// returns the object key; it has no public URL
+	UploadPrivateBase64(ctx context.Context, base64Data, folder string) (string, error)
+
+	// GetObject opens an object for reading and returns its content type
+	GetObject(ctx context.Context, objectKey string) (io.ReadCloser, string, error)
+
+	// UploadBytes stores data under the given object key, replacing any
+	// existing object, and returns the URL
+	UploadBytes(ctx context.Context, objectKey string, data []byte, contentType string) (string, error)
+
+	// ObjectExists reports whether an object with the given key exists
+	ObjectExists(ctx context.Context, objectKey string) (bool, error)
+
 	// DeleteFile deletes a file from storage
 	DeleteFile(ctx context.Context, objectName string) error
 
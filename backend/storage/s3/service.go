@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"io"
 	"mime/multipart"
 	"os"
 	"path/filepath"
@@ -14,6 +16,7 @@ import (
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/google/uuid"
 
 	"github.com/kholodihor/cows-shelter-backend/storage"
@@ -112,6 +115,20 @@ func (s *Service) UploadFile(ctx context.Context, fileHeader *multipart.FileHead
 
 // UploadBase64 uploads a base64-encoded image
 func (s *Service) UploadBase64(ctx context.Context, base64Data, folder string) (string, error) {
+	objectName, err := s.putBase64(ctx, base64Data, folder)
+	if err != nil {
+		return "", err
+	}
+	return s.GetObjectURL(objectName), nil
+}
+
+// UploadPrivateBase64 uploads a base64 data URL under storage.PrivatePrefix
+func (s *Service) UploadPrivateBase64(ctx context.Context, base64Data, folder string) (string, error) {
+	return s.putBase64(ctx, base64Data, storage.PrivatePrefix+strings.TrimPrefix(folder, "/"))
+}
+
+// putBase64 decodes a data URL and stores it under a new name in folder
+func (s *Service) putBase64(ctx context.Context, base64Data, folder string) (string, error) {
 	// Extract content type and base64 data from the data URL
 	contentType, base64Image, err := parseBase64Data(base64Data)
 	if err != nil {
@@ -125,7 +142,10 @@ func (s *Service) UploadBase64(ctx context.Context, base64Data, folder string) (
 	}
 
 	// Generate a unique filename
-	ext := "." + strings.Split(contentType, "/")[1] // e.g., "image/png" -> ".png"
+	ext := ""
+	if _, subtype, ok := strings.Cut(contentType, "/"); ok {
+		ext = "." + subtype // e.g., "image/png" -> ".png"
+	}
 	objectName := generateObjectName(folder, ext)
 
 	// Upload the binary data to S3
@@ -139,7 +159,51 @@ func (s *Service) UploadBase64(ctx context.Context, base64Data, folder string) (
 		return "", fmt.Errorf("failed to upload base64 data to S3: %w", err)
 	}
 
-	return s.GetObjectURL(objectName), nil
+	return objectName, nil
+}
+
+// GetObject opens an object for reading; the caller closes the body
+func (s *Service) GetObject(ctx context.Context, objectKey string) (io.ReadCloser, string, error) {
+	output, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(objectKey),
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get object from S3: %w", err)
+	}
+	return output.Body, aws.ToString(output.ContentType), nil
+}
+
+// UploadBytes uploads data under a fixed object key
+func (s *Service) UploadBytes(ctx context.Context, objectKey string, data []byte, contentType string) (string, error) {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucketName),
+		Key:         aws.String(objectKey),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload object to S3: %w", err)
+	}
+
+	return s.GetObjectURL(objectKey), nil
+}
+
+// ObjectExists checks for an object with HeadObject
+func (s *Service) ObjectExists(ctx context.Context, objectKey string) (bool, error) {
+	_, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(objectKey),
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check object in S3: %w", err)
+	}
+
+	return true, nil
 }
 
 // DeleteFile deletes a file from S3
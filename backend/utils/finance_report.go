@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+)
+
+// FinanceRow is one line of a financial report table
+type FinanceRow struct {
+	Label    string
+	Currency string
+	Count    int64
+	Amount   string // formatted decimal, such as "500.00"
+}
+
+// FinanceBalance compares income and expenses in one currency
+type FinanceBalance struct {
+	Currency string
+	Income   string
+	Expenses string
+	Balance  string
+}
+
+// FinanceReport holds the income and expenses of a period
+type FinanceReport struct {
+	Organization string
+	PeriodEn     string // such as "September 2026"
+	PeriodUa     string
+	GeneratedAt  time.Time
+	Income       []FinanceRow
+	Expenses     []FinanceRow
+	Balances     []FinanceBalance
+}
+
+// Column widths of the report tables in millimetres
+const (
+	financeLabelWidth  = 95.0
+	financeCountWidth  = 25.0
+	financeAmountWidth = receiptWidth - financeLabelWidth - financeCountWidth
+)
+
+// RenderFinanceReport typesets a bilingual financial report as an A4 PDF:
+// income, expenses and the balance of each currency
+func RenderFinanceReport(r FinanceReport, fonts PDFFonts) ([]byte, error) {
+	pdf, err := newPDFDocument("Financial report "+r.PeriodEn, r.Organization, r.GeneratedAt, fonts)
+	if err != nil {
+		return nil, err
+	}
+
+	pdf.SetFont("receipt", "B", 15)
+	pdf.MultiCell(receiptWidth, 7, r.Organization, "", "L", false)
+	pdf.Ln(4)
+	pdf.SetFont("receipt", "B", 18)
+	pdf.CellFormat(receiptWidth, 9, "Financial report: "+r.PeriodEn, "", 1, "L", false, 0, "")
+	pdf.SetFont("receipt", "", 14)
+	pdf.CellFormat(receiptWidth, 7, "Фінансовий звіт: "+r.PeriodUa, "", 1, "L", false, 0, "")
+	pdf.Ln(2)
+	pdf.SetFont("receipt", "", 9)
+	pdf.SetTextColor(90, 90, 90)
+	pdf.CellFormat(receiptWidth, 5, "Generated / Сформовано: "+r.GeneratedAt.Format(receiptDateFormat), "", 1, "L", false, 0, "")
+	pdf.SetTextColor(0, 0, 0)
+
+	table := func(title string, rows []FinanceRow, empty string) {
+		pdf.Ln(6)
+		pdf.SetFont("receipt", "B", 12)
+		pdf.CellFormat(receiptWidth, 7, title, "", 1, "L", false, 0, "")
+		pdf.SetFont("receipt", "B", 9)
+		pdf.SetFillColor(235, 235, 235)
+		pdf.CellFormat(financeLabelWidth, 7, "Category / Категорія", "1", 0, "L", true, 0, "")
+		pdf.CellFormat(financeCountWidth, 7, "Count / К-сть", "1", 0, "R", true, 0, "")
+		pdf.CellFormat(financeAmountWidth, 7, "Amount / Сума", "1", 1, "R", true, 0, "")
+		pdf.SetFont("receipt", "", 10)
+		if len(rows) == 0 {
+			pdf.CellFormat(receiptWidth, 7, empty, "1", 1, "L", false, 0, "")
+			return
+		}
+		for _, row := range rows {
+			pdf.CellFormat(financeLabelWidth, 7, row.Label, "1", 0, "L", false, 0, "")
+			pdf.CellFormat(financeCountWidth, 7, fmt.Sprintf("%d", row.Count), "1", 0, "R", false, 0, "")
+			pdf.CellFormat(financeAmountWidth, 7, row.Amount+" "+row.Currency, "1", 1, "R", false, 0, "")
+		}
+	}
+	table("Income / Надходження", r.Income, "No donations received / Надходжень не було")
+	table("Expenses / Витрати", r.Expenses, "No expenses / Витрат не було")
+
+	pdf.Ln(6)
+	pdf.SetFont("receipt", "B", 12)
+	pdf.CellFormat(receiptWidth, 7, "Balance / Підсумок", "", 1, "L", false, 0, "")
+	columnWidth := (receiptWidth - 25) / 3
+	pdf.SetFont("receipt", "B", 9)
+	pdf.CellFormat(25, 7, "Currency / Валюта", "1", 0, "L", true, 0, "")
+	pdf.CellFormat(columnWidth, 7, "Income / Надходження", "1", 0, "R", true, 0, "")
+	pdf.CellFormat(columnWidth, 7, "Expenses / Витрати", "1", 0, "R", true, 0, "")
+	pdf.CellFormat(columnWidth, 7, "Balance / Різниця", "1", 1, "R", true, 0, "")
+	pdf.SetFont("receipt", "", 10)
+	for _, balance := range r.Balances {
+		pdf.CellFormat(25, 7, balance.Currency, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(columnWidth, 7, balance.Income, "1", 0, "R", false, 0, "")
+		pdf.CellFormat(columnWidth, 7, balance.Expenses, "1", 0, "R", false, 0, "")
+		pdf.CellFormat(columnWidth, 7, balance.Balance, "1", 1, "R", false, 0, "")
+	}
+
+	pdf.Ln(8)
+	pdf.SetFont("receipt", "", 8)
+	pdf.SetTextColor(120, 120, 120)
+	pdf.MultiCell(receiptWidth, 4, joinLines(
+		"Income counts completed donations. Amounts in different currencies are not converted.",
+		"Надходження враховують завершені благодійні внески. Суми в різних валютах не конвертуються.",
+	), "", "L", false)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to render financial report: %w", err)
+	}
+	return buf.Bytes(), nil
+}
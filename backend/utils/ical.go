@@ -0,0 +1,227 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// iCalendar event statuses
+const (
+	ICalConfirmed = "CONFIRMED"
+	ICalCancelled = "CANCELLED"
+)
+
+const (
+	icalDateTime      = "20060102T150405"
+	icalLineLimit     = 75 // octets per content line before folding (RFC 5545 §3.1)
+	icalTransitionGap = 24 * time.Hour
+)
+
+// ICalEvent is a single VEVENT of a calendar
+type ICalEvent struct {
+	UID          string
+	Start        time.Time
+	End          time.Time
+	Summary      string
+	Description  string
+	Location     string
+	URL          string
+	Status       string // ICalConfirmed or ICalCancelled
+	LastModified time.Time
+}
+
+// Calendar is an RFC 5545 calendar whose events are written in the local
+// time of Location, described by a VTIMEZONE built from the Go time zone
+// database so clients show them correctly across DST changes.
+type Calendar struct {
+	Name        string
+	Description string
+	Location    *time.Location
+	RefreshTTL  time.Duration // how often subscribers should poll, 0 to omit
+	Events      []ICalEvent
+}
+
+// Encode renders the calendar as a text/calendar document
+func (cal *Calendar) Encode() []byte {
+	loc := cal.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	w := &icalWriter{}
+	w.line("BEGIN:VCALENDAR")
+	w.line("VERSION:2.0")
+	w.line("PRODID:-//Cows Shelter//Excursions//EN")
+	w.line("CALSCALE:GREGORIAN")
+	w.line("METHOD:PUBLISH")
+	if cal.Name != "" {
+		w.line("X-WR-CALNAME:" + icalText(cal.Name))
+	}
+	if cal.Description != "" {
+		w.line("X-WR-CALDESC:" + icalText(cal.Description))
+	}
+	if loc != time.UTC {
+		w.line("X-WR-TIMEZONE:" + loc.String())
+	}
+	if cal.RefreshTTL > 0 {
+		ttl := icalDuration(cal.RefreshTTL)
+		w.line("REFRESH-INTERVAL;VALUE=DURATION:" + ttl)
+		w.line("X-PUBLISHED-TTL:" + ttl)
+	}
+	if loc != time.UTC {
+		cal.writeTimezone(w, loc)
+	}
+
+	stamp := time.Now().UTC().Format(icalDateTime) + "Z"
+	for i := range cal.Events {
+		event := &cal.Events[i]
+		w.line("BEGIN:VEVENT")
+		w.line("UID:" + event.UID)
+		w.line("DTSTAMP:" + stamp)
+		w.line(icalTime("DTSTART", event.Start, loc))
+		w.line(icalTime("DTEND", event.End, loc))
+		w.line("SUMMARY:" + icalText(event.Summary))
+		if event.Description != "" {
+			w.line("DESCRIPTION:" + icalText(event.Description))
+		}
+		if event.Location != "" {
+			w.line("LOCATION:" + icalText(event.Location))
+		}
+		if event.URL != "" {
+			w.line("URL:" + event.URL)
+		}
+		if event.Status != "" {
+			w.line("STATUS:" + event.Status)
+		}
+		if !event.LastModified.IsZero() {
+			w.line("LAST-MODIFIED:" + event.LastModified.UTC().Format(icalDateTime) + "Z")
+		}
+		w.line("END:VEVENT")
+	}
+	w.line("END:VCALENDAR")
+	return w.buf.Bytes()
+}
+
+// writeTimezone emits the VTIMEZONE of loc covering the years of the events.
+// Go does not expose zone rules, so offset changes are found by scanning
+// and each one becomes its own STANDARD or DAYLIGHT observance.
+func (cal *Calendar) writeTimezone(w *icalWriter, loc *time.Location) {
+	from, to := time.Now(), time.Now()
+	for _, event := range cal.Events {
+		if event.Start.Before(from) {
+			from = event.Start
+		}
+		if event.End.After(to) {
+			to = event.End
+		}
+	}
+	from = time.Date(from.In(loc).Year()-1, time.January, 1, 0, 0, 0, 0, loc)
+	to = time.Date(to.In(loc).Year()+1, time.December, 31, 0, 0, 0, 0, loc)
+
+	w.line("BEGIN:VTIMEZONE")
+	w.line("TZID:" + loc.String())
+
+	// The observance in effect before the range anchors the rest
+	name, offset := from.Zone()
+	observance(w, from.IsDST(), "19700101T000000", offset, offset, name)
+
+	for t := from; t.Before(to); t = t.Add(icalTransitionGap) {
+		next := t.Add(icalTransitionGap)
+		_, before := t.Zone()
+		_, after := next.Zone()
+		if before == after {
+			continue
+		}
+		// Narrow the change down to the second it happens
+		lo, hi := t, next
+		for hi.Sub(lo) > time.Second {
+			mid := lo.Add(hi.Sub(lo) / 2)
+			if _, o := mid.Zone(); o == before {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		name, _ := hi.Zone()
+		local := hi.UTC().Add(time.Duration(before) * time.Second).Format(icalDateTime)
+		observance(w, hi.IsDST(), local, before, after, name)
+	}
+	w.line("END:VTIMEZONE")
+}
+
+// observance writes one STANDARD or DAYLIGHT block starting at local wall
+// time start, given in the offset before the change
+func observance(w *icalWriter, dst bool, start string, from, to int, name string) {
+	kind := "STANDARD"
+	if dst {
+		kind = "DAYLIGHT"
+	}
+	w.line("BEGIN:" + kind)
+	w.line("DTSTART:" + start)
+	w.line("TZOFFSETFROM:" + icalOffset(from))
+	w.line("TZOFFSETTO:" + icalOffset(to))
+	if name != "" {
+		w.line("TZNAME:" + icalText(name))
+	}
+	w.line("END:" + kind)
+}
+
+// icalTime formats a DTSTART/DTEND property in the calendar zone
+func icalTime(property string, t time.Time, loc *time.Location) string {
+	if loc == time.UTC {
+		return property + ":" + t.UTC().Format(icalDateTime) + "Z"
+	}
+	return property + ";TZID=" + loc.String() + ":" + t.In(loc).Format(icalDateTime)
+}
+
+// icalOffset formats a UTC offset in seconds as +HHMM
+func icalOffset(seconds int) string {
+	sign := '+'
+	if seconds < 0 {
+		sign = '-'
+		seconds = -seconds
+	}
+	if seconds%60 != 0 {
+		return fmt.Sprintf("%c%02d%02d%02d", sign, seconds/3600, seconds%3600/60, seconds%60)
+	}
+	return fmt.Sprintf("%c%02d%02d", sign, seconds/3600, seconds%3600/60)
+}
+
+// icalDuration formats d as an RFC 5545 duration such as PT1H
+func icalDuration(d time.Duration) string {
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("PT%dH", d/time.Hour)
+	}
+	return fmt.Sprintf("PT%dM", d/time.Minute)
+}
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// icalText escapes a TEXT value
+func icalText(s string) string {
+	return icalEscaper.Replace(s)
+}
+
+// icalWriter writes CRLF-terminated content lines folded at 75 octets
+type icalWriter struct {
+	buf bytes.Buffer
+}
+
+func (w *icalWriter) line(s string) {
+	limit := icalLineLimit
+	for len(s) > limit {
+		// Never split a multi-byte UTF-8 sequence
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.buf.WriteString(s[:cut])
+		w.buf.WriteString("\r\n ")
+		s = s[cut:]
+		limit = icalLineLimit - 1 // the leading space counts
+	}
+	w.buf.WriteString(s)
+	w.buf.WriteString("\r\n")
+}
@@ -8,10 +8,17 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-var jwtKey = []byte("qwerty98765") // Replace with your secret key
+// Purposes of restricted tokens that are not valid as access tokens
+const (
+	PurposeMFA      = "mfa"       // password checked, TOTP code still required
+	PurposeMFASetup = "mfa_setup" // password checked, 2FA enrollment required by policy
+)
 
 type Claims struct {
-	Email string `json:"email"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	Purpose   string `json:"purpose,omitempty"`
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -27,27 +34,88 @@ func CheckPasswordHash(password, hash string) bool {
     return err == nil
 }
 
-// GenerateJWT generates a new token for a user
-func GenerateJWT(email string) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour) // Token valid for 24 hours
+// AccessTokenTTL returns the lifetime of access tokens. They are short-lived
+// and renewed through refresh tokens.
+func AccessTokenTTL() time.Duration {
+	ttl, err := time.ParseDuration(GetEnv("ACCESS_TOKEN_TTL", "15m"))
+	if err != nil || ttl <= 0 {
+		return 15 * time.Minute
+	}
+	return ttl
+}
+
+// RefreshTokenTTL returns the lifetime of refresh tokens
+func RefreshTokenTTL() time.Duration {
+	ttl, err := time.ParseDuration(GetEnv("REFRESH_TOKEN_TTL", "720h"))
+	if err != nil || ttl <= 0 {
+		return 30 * 24 * time.Hour
+	}
+	return ttl
+}
+
+// GenerateJWT generates a new access token for a user with the given role,
+// bound to the login session it was issued for
+func GenerateJWT(email, role, sessionID string) (string, error) {
+	now := time.Now()
+	expirationTime := now.Add(AccessTokenTTL())
 	claims := &Claims{
-		Email: email,
+		Email:     email,
+		Role:      role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtKey)
+	keys, err := currentKeySet()
+	if err != nil {
+		return "", err
+	}
+	return keys.sign(claims)
+}
+
+// GenerateChallengeToken issues a short-lived token that only proves the
+// password step of a login for the given purpose
+func GenerateChallengeToken(email, purpose string) (string, error) {
+	now := time.Now()
+	claims := &Claims{
+		Email:   email,
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(5 * time.Minute)),
+		},
+	}
+
+	keys, err := currentKeySet()
+	if err != nil {
+		return "", err
+	}
+	return keys.sign(claims)
+}
+
+// ValidateChallengeToken validates a challenge token issued for the purpose
+func ValidateChallengeToken(tokenString, purpose string) (*Claims, error) {
+	claims, err := ValidateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != purpose {
+		return nil, errors.New("invalid token purpose")
+	}
+	return claims, nil
 }
 
 // ValidateToken validates the JWT token and returns the claims
 func ValidateToken(tokenString string) (*Claims, error) {
-	claims := &Claims{}
+	keys, err := currentKeySet()
+	if err != nil {
+		return nil, err
+	}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return jwtKey, nil
-	})
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, keys.keyFunc)
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrSignatureInvalid) {
@@ -61,4 +129,14 @@ func ValidateToken(tokenString string) (*Claims, error) {
 	}
 
 	return claims, nil
-}
\ No newline at end of file
+}
+
+// IssuedBefore reports whether the token was issued before t. Tokens without
+// an issued-at claim are treated as older than any timestamp.
+func (c *Claims) IssuedBefore(t time.Time) bool {
+	if c.IssuedAt == nil {
+		return true
+	}
+	// JWT timestamps have second precision
+	return c.IssuedAt.Time.Before(t.Truncate(time.Second))
+}
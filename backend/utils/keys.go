@@ -0,0 +1,312 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKey is a key that verifies tokens and, when it holds a private part,
+// signs them
+type SigningKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	SignKey   interface{} // []byte, ed25519.PrivateKey or *rsa.PrivateKey
+	VerifyKey interface{} // []byte, ed25519.PublicKey or *rsa.PublicKey
+}
+
+// KeySet holds the active signing key and every key accepted for verification.
+// Keeping retired keys in the set lets tokens they signed expire naturally.
+type KeySet struct {
+	Active *SigningKey
+	Keys   map[string]*SigningKey
+}
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+const legacyKeyID = "default"
+
+var (
+	keySetOnce sync.Once
+	keySet     *KeySet
+	keySetErr  error
+)
+
+// LoadKeys loads the signing keys from the environment. It is called at
+// startup so misconfiguration fails fast; later calls are no-ops.
+func LoadKeys() error {
+	_, err := currentKeySet()
+	return err
+}
+
+func currentKeySet() (*KeySet, error) {
+	keySetOnce.Do(func() {
+		keySet, keySetErr = LoadKeySetFromEnv()
+	})
+	return keySet, keySetErr
+}
+
+// LoadKeySetFromEnv builds a key set from configuration:
+//
+//	JWT_ALG               HS256 (default), EdDSA or RS256; algorithm of the active key
+//	JWT_ACTIVE_KID        kid of the key used for signing
+//	JWT_KEYS              comma-separated kid:secret HMAC keys
+//	JWT_SECRET            single HMAC key with kid "default"
+//	JWT_PRIVATE_KEY(_FILE) PEM private key for EdDSA/RS256
+//	JWT_PUBLIC_KEYS       comma-separated kid:path PEM public keys still accepted
+func LoadKeySetFromEnv() (*KeySet, error) {
+	set := &KeySet{Keys: map[string]*SigningKey{}}
+
+	// HMAC secrets are always loaded so tokens survive a switch to asymmetric keys
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		set.add(&SigningKey{ID: legacyKeyID, Method: jwt.SigningMethodHS256, SignKey: []byte(secret), VerifyKey: []byte(secret)})
+	}
+	for _, entry := range splitList(os.Getenv("JWT_KEYS")) {
+		kid, secret, ok := strings.Cut(entry, ":")
+		if !ok || kid == "" || secret == "" {
+			return nil, fmt.Errorf("invalid JWT_KEYS entry %q, expected kid:secret", entry)
+		}
+		set.add(&SigningKey{ID: kid, Method: jwt.SigningMethodHS256, SignKey: []byte(secret), VerifyKey: []byte(secret)})
+	}
+
+	for _, entry := range splitList(os.Getenv("JWT_PUBLIC_KEYS")) {
+		kid, path, ok := strings.Cut(entry, ":")
+		if !ok || kid == "" || path == "" {
+			return nil, fmt.Errorf("invalid JWT_PUBLIC_KEYS entry %q, expected kid:path", entry)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read public key %s: %w", kid, err)
+		}
+		key, err := parsePublicKey(kid, data)
+		if err != nil {
+			return nil, err
+		}
+		set.add(key)
+	}
+
+	activeKid := os.Getenv("JWT_ACTIVE_KID")
+	alg := GetEnv("JWT_ALG", "HS256")
+
+	switch alg {
+	case "HS256":
+		if len(set.Keys) == 0 {
+			// A random key would quietly log everyone out on every restart
+			// and differ between replicas, so production must configure one
+			if os.Getenv("ENV") == "production" || os.Getenv("GIN_MODE") == "release" {
+				return nil, errors.New("no JWT_SECRET or JWT_KEYS configured; signing keys are required in production")
+			}
+			// Keep local development working without configuration
+			secret := make([]byte, 32)
+			if _, err := rand.Read(secret); err != nil {
+				return nil, err
+			}
+			log.Println("Warning: no JWT_SECRET or JWT_KEYS configured, using an ephemeral key; tokens will not survive a restart")
+			set.add(&SigningKey{ID: "ephemeral", Method: jwt.SigningMethodHS256, SignKey: secret, VerifyKey: secret})
+			activeKid = "ephemeral"
+		}
+		if activeKid == "" {
+			if _, ok := set.Keys[legacyKeyID]; ok {
+				activeKid = legacyKeyID
+			} else if entries := splitList(os.Getenv("JWT_KEYS")); len(entries) > 0 {
+				activeKid, _, _ = strings.Cut(entries[0], ":")
+			}
+		}
+	case "EdDSA", "RS256":
+		data, err := readPEMSetting("JWT_PRIVATE_KEY")
+		if err != nil {
+			return nil, err
+		}
+		if activeKid == "" {
+			return nil, errors.New("JWT_ACTIVE_KID is required for asymmetric signing keys")
+		}
+		key, err := parsePrivateKey(activeKid, alg, data)
+		if err != nil {
+			return nil, err
+		}
+		set.add(key)
+	default:
+		return nil, fmt.Errorf("unsupported JWT_ALG %q", alg)
+	}
+
+	active, ok := set.Keys[activeKid]
+	if !ok || active.SignKey == nil || active.Method.Alg() != alg {
+		return nil, fmt.Errorf("active signing key %q is not a %s key with a private part", activeKid, alg)
+	}
+	set.Active = active
+
+	return set, nil
+}
+
+func (s *KeySet) add(key *SigningKey) {
+	s.Keys[key.ID] = key
+}
+
+// keyFunc resolves the verification key from the token's kid header and
+// refuses tokens whose algorithm does not match the key
+func (s *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		// Tokens issued before key ids were introduced
+		kid = legacyKeyID
+	}
+
+	key, ok := s.Keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+	return key.VerifyKey, nil
+}
+
+// sign signs the claims with the active key and sets the kid header
+func (s *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.Active.Method, claims)
+	token.Header["kid"] = s.Active.ID
+	return token.SignedString(s.Active.SignKey)
+}
+
+// JWKS returns the public keys that other services can use to verify tokens.
+// HMAC secrets are never published.
+func JWKS() (*JWKSet, error) {
+	set, err := currentKeySet()
+	if err != nil {
+		return nil, err
+	}
+
+	jwks := &JWKSet{Keys: []JWK{}}
+	for _, key := range set.Keys {
+		switch pub := key.VerifyKey.(type) {
+		case ed25519.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				Kty: "OKP",
+				Kid: key.ID,
+				Use: "sig",
+				Alg: "EdDSA",
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		case *rsa.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				Kty: "RSA",
+				Kid: key.ID,
+				Use: "sig",
+				Alg: "RS256",
+				N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		}
+	}
+
+	sort.Slice(jwks.Keys, func(i, j int) bool { return jwks.Keys[i].Kid < jwks.Keys[j].Kid })
+	return jwks, nil
+}
+
+// readPEMSetting reads PEM data from NAME or from the file named by NAME_FILE
+func readPEMSetting(name string) ([]byte, error) {
+	if value := os.Getenv(name); value != "" {
+		return []byte(value), nil
+	}
+	if path := os.Getenv(name + "_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s_FILE: %w", name, err)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("%s or %s_FILE is required", name, name)
+}
+
+func parsePrivateKey(kid, alg string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %s: invalid PEM data", kid)
+	}
+
+	var parsed interface{}
+	var err error
+	if block.Type == "RSA PRIVATE KEY" {
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %w", kid, err)
+	}
+
+	switch priv := parsed.(type) {
+	case ed25519.PrivateKey:
+		if alg != "EdDSA" {
+			return nil, fmt.Errorf("key %s is an Ed25519 key but JWT_ALG is %s", kid, alg)
+		}
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodEdDSA, SignKey: priv, VerifyKey: priv.Public()}, nil
+	case *rsa.PrivateKey:
+		if alg != "RS256" {
+			return nil, fmt.Errorf("key %s is an RSA key but JWT_ALG is %s", kid, alg)
+		}
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodRS256, SignKey: priv, VerifyKey: &priv.PublicKey}, nil
+	default:
+		return nil, fmt.Errorf("key %s: unsupported private key type %T", kid, parsed)
+	}
+}
+
+func parsePublicKey(kid string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %s: invalid PEM data", kid)
+	}
+
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %w", kid, err)
+	}
+
+	switch key := parsed.(type) {
+	case ed25519.PublicKey:
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodEdDSA, VerifyKey: key}, nil
+	case *rsa.PublicKey:
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodRS256, VerifyKey: key}, nil
+	default:
+		return nil, fmt.Errorf("key %s: unsupported public key type %T", kid, parsed)
+	}
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,208 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// ReceiptParty is the shelter or the donor named on a receipt
+type ReceiptParty struct {
+	Name    string
+	Code    string // EDRPOU or individual tax number
+	Address string
+	Email   string
+}
+
+// Receipt holds the details printed on a donation receipt. Times are printed
+// as dates in their own location.
+type Receipt struct {
+	Serial     string
+	IssuedAt   time.Time
+	Shelter    ReceiptParty
+	Donor      ReceiptParty
+	Amount     string // formatted decimal, such as "500.00"
+	Currency   string
+	ReceivedAt time.Time
+	Method     string
+	Reference  string // payment reference, if any
+	PurposeEn  string
+	PurposeUa  string
+	VoidedAt   *time.Time
+	VoidReason string
+}
+
+// PDFFonts are the regular and bold TrueType fonts of generated documents.
+// Both need Cyrillic glyphs for the Ukrainian text.
+type PDFFonts struct {
+	Regular []byte
+	Bold    []byte
+}
+
+// Layout of an A4 receipt in millimetres
+const (
+	receiptMargin     = 20.0
+	receiptWidth      = 210.0 - 2*receiptMargin
+	receiptLabelWidth = 55.0
+	receiptDateFormat = "02.01.2006"
+)
+
+// RenderReceipt typesets a bilingual (English and Ukrainian) donation receipt
+// as a one-page A4 PDF. Voided receipts carry a VOID stamp and the reason.
+func RenderReceipt(r Receipt, fonts PDFFonts) ([]byte, error) {
+	pdf, err := newPDFDocument("Donation receipt "+r.Serial, r.Shelter.Name, r.IssuedAt, fonts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Shelter letterhead
+	pdf.SetFont("receipt", "B", 15)
+	pdf.MultiCell(receiptWidth, 7, r.Shelter.Name, "", "L", false)
+	pdf.SetFont("receipt", "", 9)
+	pdf.SetTextColor(90, 90, 90)
+	for _, line := range []string{
+		r.Shelter.Address,
+		labelled("EDRPOU / ЄДРПОУ", r.Shelter.Code),
+		r.Shelter.Email,
+	} {
+		if line != "" {
+			pdf.MultiCell(receiptWidth, 4.5, line, "", "L", false)
+		}
+	}
+	pdf.Ln(3)
+	pdf.SetDrawColor(180, 180, 180)
+	pdf.Line(receiptMargin, pdf.GetY(), receiptMargin+receiptWidth, pdf.GetY())
+	pdf.Ln(8)
+
+	// Title
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetFont("receipt", "B", 18)
+	pdf.CellFormat(receiptWidth, 9, "Donation receipt", "", 1, "L", false, 0, "")
+	pdf.SetFont("receipt", "", 14)
+	pdf.CellFormat(receiptWidth, 7, "Квитанція про благодійний внесок", "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	row := func(label, value string) {
+		if value == "" {
+			return
+		}
+		y := pdf.GetY()
+		pdf.SetFont("receipt", "", 9)
+		pdf.SetTextColor(90, 90, 90)
+		pdf.MultiCell(receiptLabelWidth, 5.5, label, "", "L", false)
+		labelBottom := pdf.GetY()
+		pdf.SetXY(receiptMargin+receiptLabelWidth, y)
+		pdf.SetFont("receipt", "", 11)
+		pdf.SetTextColor(0, 0, 0)
+		pdf.MultiCell(receiptWidth-receiptLabelWidth, 5.5, value, "", "L", false)
+		if pdf.GetY() < labelBottom {
+			pdf.SetY(labelBottom)
+		}
+		pdf.Ln(1)
+	}
+	section := func(title string) {
+		pdf.Ln(4)
+		pdf.SetFont("receipt", "B", 11)
+		pdf.SetTextColor(0, 0, 0)
+		pdf.CellFormat(receiptWidth, 6, title, "B", 1, "L", false, 0, "")
+		pdf.Ln(2)
+	}
+
+	row("Receipt No. / Номер", r.Serial)
+	row("Date issued / Дата видачі", r.IssuedAt.Format(receiptDateFormat))
+
+	section("Donor / Благодійник")
+	donor := r.Donor.Name
+	if donor == "" {
+		donor = "Anonymous donor / Анонімний благодійник"
+	}
+	row("Name / Ім'я або назва", donor)
+	row("Tax number / Податковий номер", r.Donor.Code)
+	row("Address / Адреса", r.Donor.Address)
+	row("Email / Ел. пошта", r.Donor.Email)
+
+	section("Donation / Благодійний внесок")
+	row("Amount / Сума", r.Amount+" "+r.Currency)
+	row("Date received / Дата надходження", r.ReceivedAt.Format(receiptDateFormat))
+	row("Payment method / Спосіб оплати", r.Method)
+	row("Payment reference / Референс платежу", r.Reference)
+	row("Purpose / Призначення", joinLines(r.PurposeEn, r.PurposeUa))
+
+	pdf.Ln(8)
+	pdf.SetFont("receipt", "", 10)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.MultiCell(receiptWidth, 5, fmt.Sprintf(
+		"%s confirms receipt of the donation above. Thank you for your support!\n"+
+			"%s підтверджує отримання зазначеного благодійного внеску. Дякуємо за вашу підтримку!",
+		r.Shelter.Name, r.Shelter.Name), "", "L", false)
+	pdf.Ln(4)
+	pdf.SetFont("receipt", "", 8)
+	pdf.SetTextColor(120, 120, 120)
+	pdf.MultiCell(receiptWidth, 4, "This receipt was generated electronically. / Квитанцію сформовано електронно.", "", "L", false)
+
+	if r.VoidedAt != nil {
+		pdf.Ln(6)
+		pdf.SetFont("receipt", "B", 11)
+		pdf.SetTextColor(200, 0, 0)
+		pdf.MultiCell(receiptWidth, 5.5, joinLines(
+			"Voided / Анульовано: "+r.VoidedAt.Format(receiptDateFormat), r.VoidReason), "", "L", false)
+
+		pdf.SetAlpha(0.25, "Normal")
+		pdf.SetFont("receipt", "B", 54)
+		pdf.TransformBegin()
+		pdf.TransformRotate(35, 105, 150)
+		stamp := "VOID / АНУЛЬОВАНО"
+		pdf.Text(105-pdf.GetStringWidth(stamp)/2, 160, stamp)
+		pdf.TransformEnd()
+		pdf.SetAlpha(1, "Normal")
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to render receipt: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// newPDFDocument starts an A4 document set in the "receipt" font family with
+// its first page added
+func newPDFDocument(title, creator string, created time.Time, fonts PDFFonts) (*gofpdf.Fpdf, error) {
+	if len(fonts.Regular) == 0 || len(fonts.Bold) == 0 {
+		return nil, fmt.Errorf("PDF fonts are not loaded")
+	}
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8FontFromBytes("receipt", "", fonts.Regular)
+	pdf.AddUTF8FontFromBytes("receipt", "B", fonts.Bold)
+	pdf.SetMargins(receiptMargin, receiptMargin, receiptMargin)
+	pdf.SetAutoPageBreak(true, receiptMargin)
+	pdf.SetTitle(title, true)
+	pdf.SetCreator(creator, true)
+	pdf.SetCreationDate(created)
+	pdf.AddPage()
+	return pdf, nil
+}
+
+// labelled prefixes a value with its label, or returns "" without a value
+func labelled(label, value string) string {
+	if value == "" {
+		return ""
+	}
+	return label + ": " + value
+}
+
+// joinLines joins the non-empty strings with newlines
+func joinLines(lines ...string) string {
+	var buf bytes.Buffer
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(line)
+	}
+	return buf.String()
+}
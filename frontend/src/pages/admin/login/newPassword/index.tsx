@@ -10,6 +10,7 @@ import PopUpSuccessNewPassword from './PopUpSuccessNewPassword';
 import LoaderSmoll from '@/components/admin/LoaderSmoll';
 
 type FormValuesPasswordd = {
+  currentpassword: string;
   password: string;
   confirmpassword: string;
 };
@@ -61,6 +62,7 @@ const NewPassword = () => {
       const password = getValues('password');
       const body = {
         email,
+        current_password: getValues('currentpassword'),
         password
       };
 
@@ -70,7 +72,7 @@ const NewPassword = () => {
     } catch (error: any) {
       setError('confirmpassword', {
         type: 'manual',
-        message: error.response.data.message
+        message: error.response.data.error ?? error.response.data.message
       });
     } finally {
       setIsLoader(false);
@@ -83,6 +85,28 @@ const NewPassword = () => {
     <div className="px-12 py-10">
       <h2 className=" mb-[59px] text-[2rem] font-semibold">Зміна пароля</h2>
       <form onSubmit={handleSubmit(openConfirmPassword)} action="" className="">
+        <label htmlFor="" className="relative mb-6 block w-[386px]">
+          Поточний пароль:
+          <input
+            {...register('currentpassword')}
+            className={`mt-1 block w-[100%] border ${
+              errors.currentpassword && 'border-red'
+            } border-darkgray px-[14px] py-[10px] placeholder:text-disabled`}
+            type="password"
+            autoComplete="current-password"
+            placeholder="Введіть поточний пароль"
+          />
+          {errors.currentpassword && (
+            <div className="relative">
+              <p className="text-[0.75rem]  text-red">
+                {errors.currentpassword.message}
+              </p>
+              <div className="absolute -top-[30px] right-[14px]">
+                <ErrorIcon />
+              </div>
+            </div>
+          )}
+        </label>
         <label htmlFor="" className="relative mb-6 block w-[386px]">
           Новий пароль:
           <input
@@ -1,6 +1,11 @@
 import { z } from 'zod';
 
 export const passwordSchema = z.object({
+  currentpassword: z
+    .string({ required_error: 'Поле повинно бути заповнене' })
+    .refine((value) => value.length > 0, {
+      message: 'Поле повинно бути заповнене'
+    }),
   password: z
     .string({ required_error: 'Поле повинно бути заповнене' })
     .refine((value) => /^(?=.*[A-Za-z])(?=.*\d)[A-Za-z\d]{6,12}$/.test(value), {
@@ -92,6 +92,7 @@ export type FormValuesSignIn = {
 
 export type FormValuesPassword = {
   email: any;
+  current_password: string;
   password: string;
 };
 
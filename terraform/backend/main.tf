@@ -104,6 +104,12 @@ resource "random_password" "db_master_password" {
   special = false
 }
 
+# Key for signing access tokens; the app refuses to start without one in release mode
+resource "random_password" "jwt_secret" {
+  length  = 48
+  special = false
+}
+
 # Security group for RDS
 resource "aws_security_group" "rds" {
   name        = "${var.project_name}-rds-sg"
@@ -259,6 +265,10 @@ resource "aws_ecs_task_definition" "app" {
           name  = "GIN_MODE"
           value = "release"
         },
+        {
+          name  = "JWT_SECRET"
+          value = random_password.jwt_secret.result
+        },
         {
           name  = "PUBLIC_STORAGE_URL"
           value = var.public_storage_url != "" ? var.public_storage_url : "https://${var.s3_bucket_name}.s3.${var.aws_region}.amazonaws.com"
@@ -41,6 +41,28 @@ resource "aws_s3_bucket_versioning" "cows_shelter_uploads_versioning" {
   }
 }
 
+# Cached donation QR codes are regenerated on demand, so old ones can go
+resource "aws_s3_bucket_lifecycle_configuration" "cows_shelter_uploads_lifecycle" {
+  bucket = aws_s3_bucket.cows_shelter_uploads.id
+
+  rule {
+    id     = "expire-qr-cache"
+    status = "Enabled"
+
+    filter {
+      prefix = "qr/"
+    }
+
+    expiration {
+      days = 30
+    }
+
+    noncurrent_version_expiration {
+      noncurrent_days = 1
+    }
+  }
+}
+
 # Enable server-side encryption
 resource "aws_s3_bucket_server_side_encryption_configuration" "cows_shelter_uploads_encryption" {
   bucket = aws_s3_bucket.cows_shelter_uploads.id
@@ -68,6 +90,16 @@ resource "aws_s3_bucket_policy" "cows_shelter_uploads_policy" {
         Action    = "s3:GetObject"
         Resource  = "${aws_s3_bucket.cows_shelter_uploads.arn}/*"
       },
+      {
+        # Medical files and receipts are only served through the API
+        Sid       = "DenyPublicReadOfPrivatePrefix"
+        Effect    = "Deny"
+        Principal = {
+          AWS = "arn:aws:iam::cloudfront:user/CloudFront Origin Access Identity ${module.frontend.cloudfront_oai_id}"
+        }
+        Action    = "s3:GetObject"
+        Resource  = "${aws_s3_bucket.cows_shelter_uploads.arn}/private/*"
+      },
       {
         Sid       = "AllowECSTaskRole"
         Effect    = "Allow"